@@ -0,0 +1,135 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package column
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// BatchOptions represents options for a batcher.
+type BatchOptions struct {
+	Size     int           // The number of objects after which the batch is flushed
+	Interval time.Duration // The interval at which the batch is flushed
+	OnError  func(error)   // The function called when a periodic flush fails (optional)
+}
+
+// Batcher accumulates objects to be inserted into a collection and inserts them in
+// a single transaction, amortizing the cost of locking across many inserts. The batch
+// is flushed when it reaches its size, on a regular interval or when explicitly asked.
+type Batcher struct {
+	lock    sync.Mutex         // The lock to protect the pending objects
+	owner   *Collection        // The target collection
+	opts    BatchOptions       // The options of the batcher
+	pending []Object           // The objects pending for insertion
+	cancel  context.CancelFunc // The cancellation function for the flush goroutine
+}
+
+// NewBatcher creates a new batcher which inserts objects into the collection.
+func (c *Collection) NewBatcher(opts ...BatchOptions) *Batcher {
+	options := BatchOptions{
+		Size:     1000,
+		Interval: 100 * time.Millisecond,
+	}
+
+	// Merge options together
+	for _, o := range opts {
+		if o.Size > 0 {
+			options.Size = o.Size
+		}
+		if o.Interval > 0 {
+			options.Interval = o.Interval
+		}
+		if o.OnError != nil {
+			options.OnError = o.OnError
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	batch := &Batcher{
+		owner:   c,
+		opts:    options,
+		pending: make([]Object, 0, options.Size),
+		cancel:  cancel,
+	}
+
+	go batch.flushEvery(ctx, options.Interval)
+	return batch
+}
+
+// Add adds an object to the batch. If the batch is full, it is flushed into the
+// collection before returning. An object with a value which can not be written into its
+// column is rejected with an error, while a nil value is inserted as a null.
+func (b *Batcher) Add(obj Object) error {
+	for name, value := range obj {
+		if column, ok := b.owner.cols.Load(name); ok && !column.accepts(value) {
+			return fmt.Errorf("column: unable to add '%s', unexpected value %v", name, value)
+		}
+	}
+
+	b.lock.Lock()
+	b.pending = append(b.pending, obj)
+	full := len(b.pending) >= b.opts.Size
+	b.lock.Unlock()
+
+	if full {
+		return b.Flush()
+	}
+	return nil
+}
+
+// Flush inserts all of the pending objects into the collection, in a single transaction.
+// The pending objects are cleared even if the insertion fails, since retrying it would
+// fail again, and the failure is returned as an error.
+func (b *Batcher) Flush() (err error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if len(b.pending) == 0 {
+		return nil
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("column: unable to flush %d objects, %v", len(b.pending), r)
+		}
+
+		for i := range b.pending {
+			b.pending[i] = nil
+		}
+		b.pending = b.pending[:0]
+	}()
+
+	return b.owner.Query(func(txn *Txn) error {
+		for _, obj := range b.pending {
+			txn.InsertObject(obj)
+		}
+		return nil
+	})
+}
+
+// Close stops the periodic flush and flushes the pending objects. If this final flush
+// fails, its error is returned and the objects are not inserted.
+func (b *Batcher) Close() error {
+	b.cancel()
+	return b.Flush()
+}
+
+// flushEvery flushes the pending objects on a specified interval, reporting the errors to
+// the error function of the options, if any.
+func (b *Batcher) flushEvery(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	for {
+		select {
+		case <-ctx.Done():
+			ticker.Stop()
+			return
+		case <-ticker.C:
+			if err := b.Flush(); err != nil && b.opts.OnError != nil {
+				b.opts.OnError(err)
+			}
+		}
+	}
+}
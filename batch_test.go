@@ -0,0 +1,79 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package column
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBatcher(t *testing.T) {
+	col := NewCollection()
+	col.CreateColumn("name", ForString())
+
+	// Flushed when the batch is full
+	batch := col.NewBatcher(BatchOptions{Size: 3, Interval: time.Hour})
+	assert.NoError(t, batch.Add(Object{"name": "A"}))
+	assert.NoError(t, batch.Add(Object{"name": "B"}))
+	assert.Equal(t, 0, col.Count())
+	assert.NoError(t, batch.Add(Object{"name": "C"}))
+	assert.Equal(t, 3, col.Count())
+
+	// Flushed explicitly and on close
+	assert.NoError(t, batch.Add(Object{"name": "D"}))
+	assert.NoError(t, batch.Flush())
+	assert.Equal(t, 4, col.Count())
+	assert.NoError(t, batch.Add(Object{"name": "E"}))
+	assert.NoError(t, batch.Close())
+	assert.Equal(t, 5, col.Count())
+
+	// Flushed on a timer
+	batch = col.NewBatcher(BatchOptions{Interval: time.Millisecond})
+	defer batch.Close()
+	assert.NoError(t, batch.Add(Object{"name": "F"}))
+	assert.Eventually(t, func() bool {
+		return col.Count() == 6
+	}, time.Second, time.Millisecond)
+}
+
+func TestBatcherFailures(t *testing.T) {
+	col := NewCollection()
+	col.CreateColumn("name", ForString())
+	col.CreateColumn("age", ForInt())
+
+	// Values which can not be written are rejected, nulls are inserted
+	batch := col.NewBatcher(BatchOptions{Size: 10, Interval: time.Hour})
+	assert.Error(t, batch.Add(Object{"name": 42}))
+	assert.Error(t, batch.Add(Object{"age": struct{}{}}))
+	assert.NoError(t, batch.Add(Object{"name": "A", "age": nil}))
+	assert.NoError(t, batch.Close())
+	assert.Equal(t, 1, col.Count())
+
+	// A failed flush on a timer is reported and the batch is discarded
+	assert.NoError(t, col.CreateIndex("broken", "name", func(r Reader) bool {
+		if r.String() == "B" {
+			panic("broken index")
+		}
+		return false
+	}))
+
+	failed := make(chan error, 1)
+	batch = col.NewBatcher(BatchOptions{Interval: time.Millisecond, OnError: func(err error) {
+		failed <- err
+	}})
+	defer batch.Close()
+
+	assert.NoError(t, batch.Add(Object{"name": "B"}))
+	select {
+	case err := <-failed:
+		assert.Error(t, err)
+	case <-time.After(2 * time.Second):
+		assert.Fail(t, "flush failure not reported")
+	}
+
+	assert.NoError(t, col.DropIndex("broken"))
+	assert.NoError(t, batch.Flush())
+}
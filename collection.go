@@ -4,10 +4,15 @@
 package column
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
+	"math"
 	"math/bits"
 	"reflect"
+	"sort"
+	"strconv"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -28,6 +33,7 @@ const (
 // Collection represents a collection of objects in a columnar format
 type Collection struct {
 	count   uint64             // The current count of elements
+	version uint64             // The version, incremented on every committed change
 	txns    *txnPool           // The transaction pool
 	lock    sync.RWMutex       // The mutex to guard the fill-list
 	slock   *smutex.SMutex128  // The sharded mutex for the collection
@@ -39,6 +45,15 @@ type Collection struct {
 	pk      *columnKey         // The primary key column
 	cancel  context.CancelFunc // The cancellation function for the context
 	commits []uint64           // The array of commit IDs for corresponding chunk
+	history *history           // The history of inverse operations (optional)
+	journal *history           // The journal of operations (optional)
+	watch   observers          // The observers of the committed changes
+	keys    compositeKeys      // The composite keys, built on demand
+	tracer  atomic.Value       // The tracer of the applied records (optional)
+	alloc   AllocStrategy      // The strategy to choose a free index
+	freed   []uint32           // The queue of freed indices, for the FIFO strategy
+	tail    uint32             // The index past the highest one ever allocated
+	serial  sync.Mutex         // The mutex to serialize the versioned commits
 }
 
 // Options represents the options for a collection.
@@ -46,6 +61,9 @@ type Options struct {
 	Capacity int           // The initial capacity when creating columns
 	Writer   commit.Logger // The writer for the commit log (optional)
 	Vacuum   time.Duration // The interval at which the vacuum of expired entries will be done
+	History  int           // The number of versions retained for rollback (optional)
+	Journal  int           // The number of versions journaled for incremental snapshots (optional)
+	Stats    bool          // Whether to count the reads and writes of each column (optional)
 }
 
 // NewCollection creates a new columnar collection.
@@ -67,6 +85,15 @@ func NewCollection(opts ...Options) *Collection {
 		if o.Writer != nil {
 			options.Writer = o.Writer
 		}
+		if o.History > 0 {
+			options.History = o.History
+		}
+		if o.Journal > 0 {
+			options.Journal = o.Journal
+		}
+		if o.Stats {
+			options.Stats = true
+		}
 	}
 
 	// Create a new collection
@@ -81,6 +108,16 @@ func NewCollection(opts ...Options) *Collection {
 		cancel: cancel,
 	}
 
+	// If requested, retain the history of changes for rollback
+	if options.History > 0 {
+		store.history = newHistory(options.History)
+	}
+
+	// If requested, journal the changes for incremental snapshots
+	if options.Journal > 0 {
+		store.journal = newHistory(options.Journal)
+	}
+
 	// Create an expiration column and start the cleanup goroutine
 	store.CreateColumn(expireColumn, ForInt64())
 	go store.vacuum(ctx, options.Vacuum)
@@ -90,13 +127,77 @@ func NewCollection(opts ...Options) *Collection {
 // next finds the next free index in the collection, atomically.
 func (c *Collection) next() uint32 {
 	c.lock.Lock()
-	idx := c.findFreeIndex(atomic.AddUint64(&c.count, 1))
+	idx := c.allocate()
+	c.lock.Unlock()
+	return idx
+}
 
-	c.fill.Set(idx)
+// nextMany finds the next n free indices in the collection at once, atomically.
+func (c *Collection) nextMany(n int) []uint32 {
+	out := make([]uint32, n)
+	c.lock.Lock()
+	for i := range out {
+		out[i] = c.allocate()
+	}
 	c.lock.Unlock()
+	return out
+}
+
+// allocate finds a free index according to the allocation strategy and marks it as used.
+// The caller must hold the lock.
+func (c *Collection) allocate() uint32 {
+	count := atomic.AddUint64(&c.count, 1)
+
+	var idx uint32
+	switch c.alloc {
+	case FIFO:
+		idx = c.findFreedIndex()
+	case HighestFirst:
+		idx = c.findHighestIndex()
+	default:
+		idx = c.findFreeIndex(count)
+	}
+
+	c.use(idx)
 	return idx
 }
 
+// nextTail allocates the index immediately after the last one used in the collection,
+// atomically. Unlike next(), it never attempts to reuse previously freed indices, which
+// keeps the insertion order of append-only data aligned with its indices.
+func (c *Collection) nextTail() uint32 {
+	c.lock.Lock()
+	idx := c.findTailIndex()
+
+	atomic.AddUint64(&c.count, 1)
+	c.use(idx)
+	c.lock.Unlock()
+	return idx
+}
+
+// claim allocates the specified index, atomically. It returns false if the index is
+// already in use.
+func (c *Collection) claim(idx uint32) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.fill.Contains(idx) {
+		return false
+	}
+
+	atomic.AddUint64(&c.count, 1)
+	c.use(idx)
+	return true
+}
+
+// use marks the index as used and advances the high-water mark of the allocated indices past
+// it. The caller must hold the lock.
+func (c *Collection) use(idx uint32) {
+	c.fill.Set(idx)
+	if idx >= c.tail {
+		c.tail = idx + 1
+	}
+}
+
 // findFreeIndex finds a free index for insertion
 func (c *Collection) findFreeIndex(count uint64) uint32 {
 	fillSize := len(c.fill)
@@ -119,6 +220,80 @@ func (c *Collection) findFreeIndex(count uint64) uint32 {
 	return idx
 }
 
+// findTailIndex finds the index immediately after the last one ever allocated, or after the
+// last one used if it is higher, since objects may also be inserted by a restore or a replay.
+func (c *Collection) findTailIndex() uint32 {
+	if last, ok := c.fill.Max(); ok && last >= c.tail {
+		return last + 1
+	}
+	return c.tail
+}
+
+// findFreedIndex finds the index which was freed first, skipping the ones which were
+// allocated since. If there is none, the index after the last one used is returned.
+func (c *Collection) findFreedIndex() uint32 {
+	for len(c.freed) > 0 {
+		idx := c.freed[0]
+		c.freed = c.freed[1:]
+		if !c.fill.Contains(idx) {
+			return idx
+		}
+	}
+	return c.findTailIndex()
+}
+
+// findHighestIndex finds the highest free index below the last one used. If there is
+// none, the index after the last one used is returned.
+func (c *Collection) findHighestIndex() uint32 {
+	last, ok := c.fill.Max()
+	if !ok {
+		return 0
+	}
+
+	// Only consider the free indices below the last one in its block
+	free := ^c.fill[last>>6] & (1<<(last&63) - 1)
+	for blk := int(last >> 6); blk >= 0; blk-- {
+		if blk < int(last>>6) {
+			free = ^c.fill[blk]
+		}
+
+		if free != 0 {
+			return uint32(blk<<6 + 63 - bits.LeadingZeros64(free))
+		}
+	}
+	return last + 1
+}
+
+// SetAllocStrategy sets how a free index is chosen when an object is inserted. Indices
+// freed by deletions are reused lowest first by default, which keeps the collection dense.
+// With FIFO, an index is only reused once every index freed before it was reused, and
+// new indices are allocated past the last one in the meantime, which delays the reuse.
+func (c *Collection) SetAllocStrategy(strategy AllocStrategy) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.alloc = strategy
+	c.freed = nil
+
+	// Queue the indices which are already free, in ascending order
+	if last, ok := c.fill.Max(); ok && strategy == FIFO {
+		for idx := uint32(0); idx < last; idx++ {
+			if !c.fill.Contains(idx) {
+				c.freed = append(c.freed, idx)
+			}
+		}
+	}
+}
+
+// AllocStrategy represents a strategy to choose a free index for an inserted object
+type AllocStrategy uint8
+
+// Various allocation strategies
+const (
+	LowestFirst  AllocStrategy = iota // Reuse the lowest free index (default)
+	FIFO                              // Reuse the index which was freed first
+	HighestFirst                      // Reuse the highest free index
+)
+
 // InsertObject adds an object to a collection and returns the allocated index.
 func (c *Collection) InsertObject(obj Object) (index uint32) {
 	c.Query(func(txn *Txn) error {
@@ -128,6 +303,17 @@ func (c *Collection) InsertObject(obj Object) (index uint32) {
 	return
 }
 
+// InsertMany adds a batch of objects to the collection in a single transaction and returns
+// their allocated indices, in the same order as the objects. The indices are allocated all
+// at once, reusing the freed ones first, which is faster than inserting the objects one by one.
+func (c *Collection) InsertMany(objects []Object) (indices []uint32) {
+	c.Query(func(txn *Txn) error {
+		indices = txn.InsertMany(objects)
+		return nil
+	})
+	return
+}
+
 // InsertObjectWithTTL adds an object to a collection, sets the expiration time
 // based on the specified time-to-live and returns the allocated index.
 func (c *Collection) InsertObjectWithTTL(obj Object, ttl time.Duration) (index uint32) {
@@ -157,6 +343,37 @@ func (c *Collection) InsertWithTTL(ttl time.Duration, fn func(Row) error) (index
 	return
 }
 
+// AddAt inserts an object at the specified index rather than at a free one chosen by the
+// collection, for example to keep the indices of replicas aligned. An error is returned if
+// an object already exists at the index.
+func (c *Collection) AddAt(idx uint32, obj Object) error {
+	return c.Query(func(txn *Txn) error {
+		return txn.AddAt(idx, obj)
+	})
+}
+
+// Append adds a value to a single column at a new index past the last one in the
+// collection and returns the allocated index. Freed indices are never reused, hence
+// for append-only data the index always reflects the insertion order.
+func (c *Collection) Append(columnName string, value interface{}) (index uint32, err error) {
+	err = c.Query(func(txn *Txn) (innerErr error) {
+		index, innerErr = txn.Append(columnName, value)
+		return
+	})
+	return
+}
+
+// Reserve allocates the specified number of indices, reusing the freed ones first, and
+// returns them. The reserved objects are live but have no values, which can be set later
+// on by querying them at their index.
+func (c *Collection) Reserve(n int) (indices []uint32) {
+	c.Query(func(txn *Txn) error {
+		indices = txn.Reserve(n)
+		return nil
+	})
+	return
+}
+
 // DeleteAt attempts to delete an item at the specified index for this collection. If the item
 // exists, it marks at as deleted and returns true, otherwise it returns false.
 func (c *Collection) DeleteAt(idx uint32) (deleted bool) {
@@ -167,11 +384,598 @@ func (c *Collection) DeleteAt(idx uint32) (deleted bool) {
 	return
 }
 
+// Clear removes the value of a single column at the specified index, while keeping the
+// object and the rest of its values. It returns whether a value was present and removed.
+func (c *Collection) Clear(idx uint32, columnName string) (cleared bool) {
+	c.QueryAt(idx, func(r Row) error {
+		cleared = r.txn.clearAt(idx, columnName)
+		return nil
+	})
+	return
+}
+
+// DeleteMany attempts to delete all of the items at the indices present in the specified
+// bitmap, in a single transaction. It returns the number of items actually deleted, which
+// excludes the indices that were not present in the collection.
+func (c *Collection) DeleteMany(indices bitmap.Bitmap) (deleted int) {
+	c.Query(func(txn *Txn) error {
+		deleted = txn.DeleteMany(indices)
+		return nil
+	})
+	return
+}
+
+// SetExpiry sets or extends the expiration time of an existing item at the specified
+// index and returns false if the item does not exist. If the expiration time is not in
+// the future, the item is considered expired and is deleted immediately.
+func (c *Collection) SetExpiry(idx uint32, at time.Time) (ok bool) {
+	c.Query(func(txn *Txn) error {
+		ok = txn.SetExpiry(idx, at)
+		return nil
+	})
+	return
+}
+
+// Update sets the values of the object at the specified index in place, keeping its index,
+// and returns false if the object does not exist. Columns which do not exist yet are created
+// with the kind of their value, and values of unsupported kinds are ignored.
+func (c *Collection) Update(idx uint32, delta Object) (ok bool) {
+	c.lock.RLock()
+	exists := c.fill.Contains(idx)
+	c.lock.RUnlock()
+	if !exists {
+		return false
+	}
+
+	// Create the missing columns
+	for name, value := range delta {
+		if _, ok := c.cols.Load(name); ok || value == nil {
+			continue
+		}
+
+		if column, err := ForKind(reflect.TypeOf(value).Kind()); err == nil {
+			c.CreateColumn(name, column)
+		}
+	}
+
+	c.Query(func(txn *Txn) error {
+		ok = txn.Update(idx, delta)
+		return nil
+	})
+	return
+}
+
+// FetchFields reads the values of the specified columns at the index into a new object. Only
+// the values present at the index are returned. If the index does not contain an object, it
+// returns false instead.
+func (c *Collection) FetchFields(idx uint32, fields ...string) (Object, bool) {
+	c.lock.RLock()
+	exists := c.fill.Contains(idx)
+	c.lock.RUnlock()
+	if !exists {
+		return nil, false
+	}
+
+	object := make(Object, len(fields))
+	chunk := commit.ChunkAt(idx)
+	c.slock.RLock(uint(chunk))
+	for _, name := range fields {
+		if column, ok := c.cols.Load(name); ok && !column.IsIndex() {
+			if v, ok := column.Value(idx); ok {
+				object[name] = v
+			}
+		}
+	}
+	c.slock.RUnlock(uint(chunk))
+	return object, true
+}
+
+// FetchConcurrent reads the objects at the specified indices across multiple goroutines and
+// returns them in the same order as the indices. Each object is read while holding the read
+// latch of its chunk, and indices which are not present in the collection yield nil.
+func (c *Collection) FetchConcurrent(indices []uint32, workers int) []Object {
+	if workers < 1 {
+		workers = 1
+	}
+
+	// Split the indices into a contiguous partition per worker
+	out := make([]Object, len(indices))
+	size := (len(indices) + workers - 1) / workers
+	var wg sync.WaitGroup
+	for lo := 0; lo < len(indices); lo += size {
+		hi := lo + size
+		if hi > len(indices) {
+			hi = len(indices)
+		}
+
+		wg.Add(1)
+		go func(lo, hi int) {
+			defer wg.Done()
+			for i := lo; i < hi; i++ {
+				out[i] = c.fetch(indices[i])
+			}
+		}(lo, hi)
+	}
+
+	wg.Wait()
+	return out
+}
+
+// FetchPooled reads all of the values of the object at the index into an object taken from
+// a pool, and returns it along with a function which releases it back into the pool. The
+// object is cleared on release, hence it must not be used after calling the release function,
+// which must be called at most once. If the index does not contain an object, it returns nil.
+func (c *Collection) FetchPooled(idx uint32) (Object, func()) {
+	object := c.txns.acquireObject()
+	if !c.fetchInto(idx, object) {
+		c.txns.releaseObject(object)
+		return nil, func() {}
+	}
+
+	return object, func() {
+		c.txns.releaseObject(object)
+	}
+}
+
+// fetch reads all of the values of an object, or returns nil if it does not exist
+func (c *Collection) fetch(idx uint32) Object {
+	object := make(Object, c.cols.Count())
+	if !c.fetchInto(idx, object) {
+		return nil
+	}
+	return object
+}
+
+// fetchInto reads all of the values of an object into the destination and returns whether
+// the object exists
+func (c *Collection) fetchInto(idx uint32, dst Object) (exists bool) {
+	chunk := commit.ChunkAt(idx)
+	c.slock.RLock(uint(chunk))
+	c.lock.RLock()
+	exists = c.fill.Contains(idx)
+	c.lock.RUnlock()
+
+	if exists {
+		c.readObject(idx, dst)
+	}
+	c.slock.RUnlock(uint(chunk))
+	return
+}
+
+// FetchIntoStructs reads the objects at the specified indices into the destination, which
+// must be a pointer to a slice of structs. Each column is mapped onto the struct field with
+// the corresponding `column:"name"` tag. Indices which are not present in the collection are
+// skipped, and the destination is resized to match the number of objects read.
+func (c *Collection) FetchIntoStructs(indices []uint32, dest interface{}) error {
+	out := reflect.ValueOf(dest)
+	if out.Kind() != reflect.Ptr || out.Elem().Kind() != reflect.Slice || out.Elem().Type().Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("column: unable to fetch into %T, expected a pointer to a slice of structs", dest)
+	}
+
+	slice := out.Elem()
+	elem := slice.Type().Elem()
+	fields := fieldsOf(elem)
+	result := slice.Slice(0, 0)
+	return c.Query(func(txn *Txn) error {
+		txn.initialize()
+		object := make(Object, len(fields))
+		for _, idx := range indices {
+			if !txn.index.Contains(idx) {
+				continue
+			}
+
+			chunk := commit.ChunkAt(idx)
+			c.slock.RLock(uint(chunk))
+			c.readObject(idx, object)
+			c.slock.RUnlock(uint(chunk))
+
+			item := reflect.New(elem).Elem()
+			for name, value := range object {
+				if i, ok := fields[name]; ok {
+					if err := assignField(item.Field(i), value); err != nil {
+						return fmt.Errorf("column: unable to fetch column '%v', %w", name, err)
+					}
+				}
+				delete(object, name)
+			}
+
+			result = reflect.Append(result, item)
+		}
+
+		slice.Set(result)
+		return nil
+	})
+}
+
+// ReadObject decodes the pending updates of a commit for the specified index into an object,
+// keyed by the column name. If a column is updated multiple times, the last value is kept
+// and additions are decoded as their delta rather than the resulting value. Values deleted
+// by the commit are not present in the object.
+func (c *Collection) ReadObject(change commit.Commit, idx uint32) Object {
+	object := make(Object, len(change.Updates))
+	reader := commit.NewReader()
+	for _, u := range change.Updates {
+		column, ok := c.cols.Load(u.Column)
+		if !ok || column.IsIndex() {
+			continue
+		}
+
+		// Booleans are stored as operations, so a delete is equivalent to false
+		_, isBool := column.Column.(*columnBool)
+		for reader.Seek(u); reader.Next(); {
+			if reader.Index() != idx {
+				continue
+			}
+
+			switch value, ok := decodeValue(column.Column, reader); {
+			case !ok:
+				continue
+			case reader.Type == commit.Delete && !isBool:
+				delete(object, u.Column)
+			default:
+				object[u.Column] = value
+			}
+		}
+	}
+	return object
+}
+
+// Get reads the value of a column at the specified index and converts it to the type
+// parameter. If the value is not present or is of a different type, it returns the zero
+// value and false instead.
+func Get[T any](c *Collection, idx uint32, columnName string) (value T, ok bool) {
+	column, exists := c.cols.Load(columnName)
+	if !exists || column.IsIndex() {
+		return
+	}
+
+	chunk := commit.ChunkAt(idx)
+	c.slock.RLock(uint(chunk))
+	v, present := column.Value(idx)
+	null := !present && column.IsNull(idx)
+	c.slock.RUnlock(uint(chunk))
+	switch {
+	case null:
+		ok = reflect.TypeOf(&value).Elem().Kind() == reflect.Interface
+	case present:
+		value, ok = v.(T)
+	}
+	return
+}
+
+// readObject reads all of the values present at the specified index into the destination
+// object, skipping the indexes and the internal expiration column. The caller must hold the
+// read latch of the chunk.
+func (c *Collection) readObject(idx uint32, dst Object) {
+	c.cols.Range(func(column *column) {
+		if column.IsIndex() || column.nullOf != "" || column.name == expireColumn {
+			return
+		}
+
+		switch v, ok := column.Value(idx); {
+		case ok:
+			dst[column.name] = v
+		case column.IsNull(idx):
+			dst[column.name] = nil
+		}
+	})
+}
+
+// Contains returns whether an object currently exists at the index.
+func (c *Collection) Contains(idx uint32) bool {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.fill.Contains(idx)
+}
+
+// Keys returns the indices of all of the objects present in the collection, in ascending
+// order. The indices are read from a consistent snapshot of the collection.
+func (c *Collection) Keys() []uint32 {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	out := make([]uint32, 0, c.fill.Count())
+	c.fill.Range(func(idx uint32) {
+		out = append(out, idx)
+	})
+	return out
+}
+
+// MarshalJSON encodes the objects present in the collection as a JSON array, in the order of
+// their indices. Indexes are computed from the other columns, so they are not encoded.
+func (c *Collection) MarshalJSON() ([]byte, error) {
+	objects := make([]Object, 0, c.Count())
+	c.Query(func(txn *Txn) error {
+		objects = txn.SelectTo(objects)
+		return nil
+	})
+
+	return json.Marshal(objects)
+}
+
+// UnmarshalJSON inserts the objects of a JSON array, as encoded by MarshalJSON, into the
+// collection. The columns which do not exist yet are created from the decoded values, and
+// numbers are converted to the type of an existing numeric column. A null is stored as an
+// explicit null, and values of another kind than their existing column are rejected before
+// anything is written. The objects are inserted
+// at new indices, hence these may differ from the ones of the collection encoded.
+func (c *Collection) UnmarshalJSON(data []byte) error {
+	var objects []Object
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&objects); err != nil {
+		return fmt.Errorf("column: unable to decode objects, %w", err)
+	}
+
+	// Validate every object first, so that no column is created if one of them is invalid
+	missing := make(map[string]Column)
+	for _, object := range objects {
+		for name, value := range object {
+			column, ok := c.cols.Load(name)
+			switch {
+			case ok && column.IsIndex():
+				delete(object, name)
+				continue
+			case value == nil:
+				continue // Restored as an explicit null
+			}
+
+			// Numbers are decoded as text, so that large integers do not lose precision
+			value, err := decodeNumber(column, value)
+			if err != nil {
+				return fmt.Errorf("column: unable to decode '%s', %w", name, err)
+			}
+
+			// The value must be of the kind stored by an existing column
+			if ok && !column.accepts(value) {
+				return fmt.Errorf("column: unable to decode '%s', unexpected value %v", name, value)
+			}
+
+			object[name] = value
+			if _, pending := missing[name]; ok || pending {
+				continue
+			}
+
+			created, err := ForKind(reflect.TypeOf(value).Kind())
+			if err != nil {
+				return fmt.Errorf("column: unable to create column '%s', %w", name, err)
+			}
+
+			missing[name] = created
+		}
+	}
+
+	for name, created := range missing {
+		if err := c.CreateColumn(name, created); err != nil {
+			return err
+		}
+	}
+
+	return c.Query(func(txn *Txn) error {
+		for _, object := range objects {
+			if _, err := txn.InsertObject(object); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// accepts returns whether a value can be written into the column, a nil value being a null.
+func (c *column) accepts(value interface{}) bool {
+	kind, ok := commit.KindOf(value)
+	if !ok || value == nil {
+		return ok
+	}
+
+	expect, known := kindOfColumn(c)
+	return !known || kind == expect
+}
+
+// kindOfColumn returns the kind of the values stored by a column, or false if it is unknown
+func kindOfColumn(column *column) (reflect.Kind, bool) {
+	switch column.Column.(type) {
+	case *float32Column:
+		return reflect.Float32, true
+	case *float64Column:
+		return reflect.Float64, true
+	case *intColumn:
+		return reflect.Int, true
+	case *int16Column:
+		return reflect.Int16, true
+	case *int32Column:
+		return reflect.Int32, true
+	case *int64Column:
+		return reflect.Int64, true
+	case *uintColumn:
+		return reflect.Uint, true
+	case *uint16Column:
+		return reflect.Uint16, true
+	case *uint32Column:
+		return reflect.Uint32, true
+	case *uint64Column:
+		return reflect.Uint64, true
+	case *columnBool:
+		return reflect.Bool, true
+	case *columnString, *columnEnum, *columnKey:
+		return reflect.String, true
+	default:
+		return reflect.Invalid, false
+	}
+}
+
+// decodeNumber converts a number decoded from JSON into the type of the column, or into a
+// float64 if there is no such column.
+func decodeNumber(column *column, value interface{}) (interface{}, error) {
+	number, ok := value.(json.Number)
+	if !ok {
+		return value, nil
+	}
+
+	if column == nil {
+		return number.Float64()
+	}
+
+	switch column.Column.(type) {
+	case *float32Column:
+		v, err := strconv.ParseFloat(number.String(), 32)
+		return float32(v), err
+	case *float64Column:
+		return number.Float64()
+	case *intColumn:
+		v, err := strconv.ParseInt(number.String(), 10, 0)
+		return int(v), err
+	case *int16Column:
+		v, err := strconv.ParseInt(number.String(), 10, 16)
+		return int16(v), err
+	case *int32Column:
+		v, err := strconv.ParseInt(number.String(), 10, 32)
+		return int32(v), err
+	case *int64Column:
+		return number.Int64()
+	case *uintColumn:
+		v, err := strconv.ParseUint(number.String(), 10, 0)
+		return uint(v), err
+	case *uint16Column:
+		v, err := strconv.ParseUint(number.String(), 10, 16)
+		return uint16(v), err
+	case *uint32Column:
+		v, err := strconv.ParseUint(number.String(), 10, 32)
+		return uint32(v), err
+	case *uint64Column:
+		return strconv.ParseUint(number.String(), 10, 64)
+	default:
+		return nil, fmt.Errorf("unexpected number %s", number)
+	}
+}
+
 // Count returns the total number of elements in the collection.
 func (c *Collection) Count() (count int) {
 	return int(atomic.LoadUint64(&c.count))
 }
 
+// Version returns the current version of the collection. The version is incremented
+// exactly once every time a transaction commits changes into the collection, and can
+// be compared across reads in order to detect whether the collection has changed.
+func (c *Collection) Version() uint64 {
+	return atomic.LoadUint64(&c.version)
+}
+
+// isVersioned returns whether the commits are retained in the history or the journal.
+func (c *Collection) isVersioned() bool {
+	return c.history != nil || c.journal != nil
+}
+
+// MemStats returns an approximate breakdown of the memory used by the columns of the
+// collection, grouped by the way the data is represented. The accounting is approximate
+// but consistent, so it can be used to follow the trends over time.
+func (c *Collection) MemStats() (stats MemBreakdown) {
+	c.lock.RLock()
+	stats.Bitmaps += 8 * cap(c.fill)
+	c.cols.Range(func(column *column) {
+		measure(column.Column, &stats)
+	})
+	chunks := len(c.commits)
+	c.lock.RUnlock()
+
+	// Strings are variable in size and need to be measured chunk by chunk
+	for chunk := commit.Chunk(0); chunk < commit.Chunk(chunks); chunk++ {
+		c.slock.RLock(uint(chunk))
+		c.cols.Range(func(column *column) {
+			measureStrings(column.Column, chunk, &stats)
+		})
+		c.slock.RUnlock(uint(chunk))
+	}
+	return
+}
+
+// ColumnMin returns the minimum value of a numeric column. The extremes of each chunk are
+// maintained as values are committed, so this only scans the chunks whose previous minimum
+// or maximum was removed. If the column does not exist, is not numeric or empty, ok will be false.
+func (c *Collection) ColumnMin(columnName string) (min interface{}, ok bool) {
+	min, _, ok = c.boundsOf(columnName)
+	return
+}
+
+// ColumnMax returns the maximum value of a numeric column. The extremes of each chunk are
+// maintained as values are committed, so this only scans the chunks whose previous minimum
+// or maximum was removed. If the column does not exist, is not numeric or empty, ok will be false.
+func (c *Collection) ColumnMax(columnName string) (max interface{}, ok bool) {
+	_, max, ok = c.boundsOf(columnName)
+	return
+}
+
+// boundsOf returns the extremes of a column, if it maintains them
+func (c *Collection) boundsOf(columnName string) (min, max interface{}, ok bool) {
+	column, ok := c.cols.Load(columnName)
+	if !ok {
+		return nil, nil, false
+	}
+
+	extremes, ok := column.Column.(bounded)
+	if !ok {
+		return nil, nil, false
+	}
+
+	return extremes.bounds(c.chunks(), func(chunk commit.Chunk, exclusive bool, fn func()) {
+		if exclusive {
+			c.slock.Lock(uint(chunk))
+			defer c.slock.Unlock(uint(chunk))
+		} else {
+			c.slock.RLock(uint(chunk))
+			defer c.slock.RUnlock(uint(chunk))
+		}
+
+		// Prevent the column from growing while the extremes are read
+		c.lock.RLock()
+		defer c.lock.RUnlock()
+		fn()
+	})
+}
+
+// Histogram counts the values of a numeric column into the buckets delimited by the specified
+// boundaries, which must be sorted in ascending order. The first count is of the values below
+// the first boundary and the last one of the values at or above the last boundary, while each
+// count in between is of the values in [buckets[i-1], buckets[i]). Missing and non-numeric
+// values are skipped, so all of the counts are zero if the column is not numeric.
+func (c *Collection) Histogram(columnName string, buckets []float64) []int {
+	counts := make([]int, len(buckets)+1)
+	c.Query(func(txn *Txn) error {
+		column, ok := txn.columnAt(columnName)
+		if !ok || !column.IsNumeric() {
+			return nil
+		}
+
+		txn.initialize()
+		numeric := column.Column.(Numeric)
+		txn.rangeRead(func(offset uint32, index bitmap.Bitmap) {
+			index.Range(func(x uint32) {
+				if v, ok := numeric.LoadFloat64(offset + x); ok && !math.IsNaN(v) {
+					counts[sort.Search(len(buckets), func(i int) bool {
+						return buckets[i] > v
+					})]++
+				}
+			})
+		})
+		return nil
+	})
+	return counts
+}
+
+// ColumnStats returns the number of values read and written through the accessors of
+// the specified column. The counters are only maintained if the collection was created
+// with the Stats option, otherwise both are zero. If the column does not exist, ok will
+// be false.
+func (c *Collection) ColumnStats(columnName string) (reads, writes uint64, ok bool) {
+	column, ok := c.cols.Load(columnName)
+	if !ok {
+		return 0, 0, false
+	}
+
+	reads, writes = column.stats.Load()
+	return
+}
+
 // createColumnKey attempts to create a primary key column
 func (c *Collection) createColumnKey(columnName string, column *columnKey) error {
 	if c.pk != nil {
@@ -198,14 +1002,53 @@ func (c *Collection) CreateColumnsOf(object Object) error {
 	return nil
 }
 
+// EnsureColumns creates the columns of the specified kinds which do not exist yet, leaving the
+// existing ones untouched. An error is returned if an existing column is of a different kind
+// or if a kind is not supported, in which case none of the columns are created.
+func (c *Collection) EnsureColumns(defs map[string]reflect.Kind) error {
+	missing := make(map[string]Column, len(defs))
+	for name, kind := range defs {
+		column, err := ForKind(kind)
+		if err != nil {
+			return err
+		}
+
+		existing, ok := c.cols.Load(name)
+		switch {
+		case !ok:
+			missing[name] = column
+		case reflect.TypeOf(existing.Column) != reflect.TypeOf(column):
+			return fmt.Errorf("column: unable to ensure column '%s', it exists with a different kind", name)
+		}
+	}
+
+	for name, column := range missing {
+		if err := c.CreateColumn(name, column); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // CreateColumn creates a column of a specified type and adds it to the collection.
 func (c *Collection) CreateColumn(columnName string, column Column) error {
 	if _, ok := c.cols.Load(columnName); ok {
 		return fmt.Errorf("column: unable to create column '%s', already exists", columnName)
 	}
 
+	// Grow the column to cover the chunks already committed, since these are not grown again
 	column.Grow(uint32(c.opts.Capacity))
-	c.cols.Store(columnName, columnFor(columnName, column))
+	c.lock.Lock()
+	if chunks := len(c.commits); chunks > 0 {
+		column.Grow(commit.Chunk(chunks - 1).Max())
+	}
+	created := columnFor(columnName, column)
+	if c.opts.Stats {
+		created.stats = new(columnStats)
+	}
+
+	c.cols.Store(columnName, created)
+	c.lock.Unlock()
 
 	// If necessary, create a primary key column
 	if pk, ok := column.(*columnKey); ok {
@@ -214,10 +1057,218 @@ func (c *Collection) CreateColumn(columnName string, column Column) error {
 	return nil
 }
 
+// AddAlias adds an alias for a column, which resolves to the actual column in queries and
+// when fetching objects, for example to keep the previous name of a renamed column working.
+// Aliases are resolved at query time and can refer to other aliases, but not form a cycle.
+func (c *Collection) AddAlias(alias, actual string) error {
+	if _, ok := c.cols.Load(alias); ok && c.cols.Resolve(alias) == alias {
+		return fmt.Errorf("column: unable to alias '%s', column already exists", alias)
+	}
+
+	return c.cols.StoreAlias(alias, actual)
+}
+
+// CopyColumn creates a new column with the specified name and copies all of the values
+// of the source column into it. The copy is independent from the source, so subsequent
+// updates to either of the columns will not affect the other one.
+func (c *Collection) CopyColumn(srcName, dstName string) error {
+	src, ok := c.cols.Load(srcName)
+	if !ok {
+		return fmt.Errorf("column: unable to copy, column '%v' does not exist", srcName)
+	}
+
+	if _, exists := c.cols.Load(dstName); exists {
+		return fmt.Errorf("column: unable to copy, column '%v' already exists", dstName)
+	}
+
+	dst, err := columnLike(src.Column)
+	if err != nil {
+		return err
+	}
+
+	// Hold the read latches of every chunk until the column is created, so that no write
+	// is committed to the source column between the copy and the creation.
+	for shard := uint(0); shard < 128; shard++ {
+		c.slock.RLock(shard)
+		defer c.slock.RUnlock(shard)
+	}
+
+	// Iterate over all of the values of the source column, chunk by chunk and apply
+	// them onto the destination column.
+	chunks := c.chunks()
+	buffer := commit.NewBuffer(chunkSize)
+	reader := commit.NewReader()
+	for chunk := commit.Chunk(0); int(chunk) < chunks; chunk++ {
+		if src.Snapshot(chunk, buffer) {
+			dst.Grow(chunk.Max())
+			reader.Seek(buffer)
+			dst.Apply(reader)
+		}
+	}
+
+	return c.CreateColumn(dstName, dst)
+}
+
+// MapColumn sets the destination column of every object to the result of the function applied
+// to its value of the source column, in a single transaction. Objects without a value in the
+// source column are skipped and keep their destination value, while a nil result clears the
+// destination value.
+func (c *Collection) MapColumn(srcName, dstName string, fn func(v interface{}) interface{}) error {
+	return c.mapColumn(srcName, dstName, fn, false)
+}
+
+// MapColumnWithUnset is similar to MapColumn, but also applies the function to the objects
+// without a value in the source column, in which case the function is called with nil.
+func (c *Collection) MapColumnWithUnset(srcName, dstName string, fn func(v interface{}) interface{}) error {
+	return c.mapColumn(srcName, dstName, fn, true)
+}
+
+// mapColumn applies the function to the source column and writes the results to the destination
+func (c *Collection) mapColumn(srcName, dstName string, fn func(v interface{}) interface{}, unset bool) error {
+	return c.Query(func(txn *Txn) error {
+		src, ok := txn.columnAt(srcName)
+		if !ok {
+			return fmt.Errorf("column: unable to map, column '%v' does not exist", srcName)
+		}
+
+		dst, ok := txn.columnAt(dstName)
+		if !ok || dst.IsIndex() {
+			return fmt.Errorf("column: unable to map, column '%v' does not exist", dstName)
+		}
+
+		buffer := txn.bufferFor(dstName)
+		return txn.Range(func(idx uint32) {
+			value, ok := src.Value(idx)
+			switch {
+			case !ok && !unset:
+				return
+			case !ok:
+				value = nil
+			}
+
+			dst.stats.write()
+			if out := fn(value); out != nil {
+				buffer.PutAny(commit.Put, idx, out)
+			} else {
+				buffer.PutOperation(commit.Delete, idx)
+			}
+		})
+	})
+}
+
+// RangeColumn iterates over the values of a single column, in the order of their indices,
+// without reading any of the other columns. Each chunk is read while holding its read latch,
+// and the iteration stops as soon as the function returns false. It returns false if the
+// column does not exist.
+func (c *Collection) RangeColumn(columnName string, fn func(idx uint32, v interface{}) bool) bool {
+	column, ok := c.cols.Load(columnName)
+	if !ok {
+		return false
+	}
+
+	chunks := c.chunks()
+	for chunk := commit.Chunk(0); int(chunk) < chunks; chunk++ {
+		c.slock.RLock(uint(chunk))
+		c.lock.RLock()
+		fill := chunk.OfBitmap(*column.Index())
+		c.lock.RUnlock()
+
+		next := rangeValues(column, chunk.Min(), fill, fn)
+		c.slock.RUnlock(uint(chunk))
+		if !next {
+			break
+		}
+	}
+	return true
+}
+
+// rangeValues calls the function with each value of the column present in the fill list,
+// until it returns false. The caller must hold the read latch of the chunk.
+func rangeValues(column *column, offset uint32, fill bitmap.Bitmap, fn func(uint32, interface{}) bool) bool {
+	for blk, word := range fill {
+		for ; word != 0; word &= word - 1 {
+			idx := offset + uint32(blk<<6+bits.TrailingZeros64(word))
+			if v, ok := column.Value(idx); ok && !fn(idx, v) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Range iterates over all of the objects present in the collection, in ascending order of
+// their index, until the function returns false. Each chunk is read while holding its read
+// latch. The same object is reused across calls, so it is only valid during the call and must
+// be copied in order to be retained.
+func (c *Collection) Range(fn func(idx uint32, obj Object) bool) {
+	object := c.txns.acquireObject()
+	defer c.txns.releaseObject(object)
+
+	chunks := c.chunks()
+	for chunk := commit.Chunk(0); int(chunk) < chunks; chunk++ {
+		c.slock.RLock(uint(chunk))
+		c.lock.RLock()
+		fill := append(bitmap.Bitmap(nil), chunk.OfBitmap(c.fill)...)
+		c.lock.RUnlock()
+
+		next := c.rangeObjects(chunk.Min(), fill, object, fn)
+		c.slock.RUnlock(uint(chunk))
+		if !next {
+			return
+		}
+	}
+}
+
+// rangeObjects calls the function with each object present in the fill list, until it
+// returns false. The caller must hold the read latch of the chunk.
+func (c *Collection) rangeObjects(offset uint32, fill bitmap.Bitmap, object Object, fn func(uint32, Object) bool) bool {
+	for blk, word := range fill {
+		for ; word != 0; word &= word - 1 {
+			for k := range object {
+				delete(object, k)
+			}
+
+			idx := offset + uint32(blk<<6+bits.TrailingZeros64(word))
+			c.readObject(idx, object)
+			if !fn(idx, object) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// CompactColumn reclaims the internal overhead of a single column, such as spare capacity,
+// deleted strings and unused dictionary entries, without changing its values or indices. The
+// column is locked exclusively while being compacted.
+func (c *Collection) CompactColumn(columnName string) error {
+	column, ok := c.cols.Load(columnName)
+	if !ok {
+		return fmt.Errorf("column: unable to compact column '%s', does not exist", columnName)
+	}
+
+	target, ok := column.Column.(compactable)
+	if !ok {
+		return nil // Nothing to reclaim
+	}
+
+	// Acquire every shard, followed by the fill-list to prevent the column from growing
+	for shard := uint(0); shard < 128; shard++ {
+		c.slock.Lock(shard)
+		defer c.slock.Unlock(shard)
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	target.compact()
+	return nil
+}
+
 // DropColumn removes the column (or an index) with the specified name. If the column with this
 // name does not exist, this operation is a no-op.
 func (c *Collection) DropColumn(columnName string) {
 	c.cols.DeleteColumn(columnName)
+	c.cols.DeleteColumn(nullColumnOf(columnName))
 }
 
 // CreateIndex creates an index column with a specified name which depends on a given
@@ -234,12 +1285,39 @@ func (c *Collection) CreateIndex(indexName, columnName string, fn func(r Reader)
 		return fmt.Errorf("column: unable to create index, column '%v' does not exist", columnName)
 	}
 
+	c.storeIndex(newIndex(indexName, columnName, fn), column)
+	return nil
+}
+
+// CreateValueIndex creates an equality index with a specified name on a given string column,
+// which keeps the matching objects for each distinct value of the column. While the index
+// exists, WhereEq on the column intersects the query with the objects of the value instead
+// of scanning the entire column.
+func (c *Collection) CreateValueIndex(indexName, columnName string) error {
+	if columnName == "" || indexName == "" {
+		return fmt.Errorf("column: create index must specify name and column")
+	}
+
+	column, ok := c.cols.Load(columnName)
+	switch {
+	case !ok:
+		return fmt.Errorf("column: unable to create index, column '%v' does not exist", columnName)
+	case !column.IsTextual():
+		return fmt.Errorf("column: unable to create index, column '%v' is not a string", columnName)
+	}
+
+	c.storeIndex(newValueIndex(indexName, columnName), column)
+	return nil
+}
+
+// storeIndex adds the index column to the target column and fills it with its values
+func (c *Collection) storeIndex(index, column *column) {
+
 	// Create and add the index column,
-	index := newIndex(indexName, columnName, fn)
 	c.lock.Lock()
 	index.Grow(uint32(c.opts.Capacity))
-	c.cols.Store(indexName, index)
-	c.cols.Store(columnName, column, index)
+	c.cols.Store(index.name, index)
+	c.cols.Store(column.name, column, index)
 	c.lock.Unlock()
 
 	// Iterate over all of the values of the target column, chunk by chunk and fill
@@ -253,8 +1331,17 @@ func (c *Collection) CreateIndex(indexName, columnName string, fn func(r Reader)
 			index.Apply(reader)
 		}
 	}
+}
 
-	return nil
+// valuesOf returns the equality index of a column, if there is one
+func (c *Collection) valuesOf(columnName string) (*columnValues, bool) {
+	columns, _ := c.cols.LoadWithIndex(columnName)
+	for _, v := range columns {
+		if values, ok := v.Column.(*columnValues); ok {
+			return values, true
+		}
+	}
+	return nil, false
 }
 
 // DropIndex removes the index column with the specified name. If the index with this
@@ -295,12 +1382,15 @@ func (c *Collection) QueryKey(key string, fn func(Row) error) error {
 // Query creates a transaction which allows for filtering and iteration over the
 // columns in this collection. It also allows for individual rows to be modified or
 // deleted during iteration (range), but the actual operations will be queued and
-// executed after the iteration.
+// executed after the iteration. If the function returns an error or panics, none
+// of the operations are applied and the collection is left as it was. Once the function
+// returns, the transaction and its index are released back to the pool, hence neither
+// must be retained nor used afterwards.
 func (c *Collection) Query(fn func(txn *Txn) error) error {
 	txn := c.txns.acquire(c)
 
 	// Execute the query and keep the error for later
-	if err := fn(txn); err != nil {
+	if err := c.execute(txn, fn); err != nil {
 		txn.rollback()
 		c.txns.release(txn)
 		return err
@@ -313,6 +1403,19 @@ func (c *Collection) Query(fn func(txn *Txn) error) error {
 	return nil
 }
 
+// execute calls the function of a query. If the function panics, the transaction is rolled
+// back and released before the panic is propagated, since none of its operations were applied.
+func (c *Collection) execute(txn *Txn, fn func(txn *Txn) error) error {
+	defer func() {
+		if r := recover(); r != nil {
+			txn.rollback()
+			c.txns.release(txn)
+			panic(r)
+		}
+	}()
+	return fn(txn)
+}
+
 // Close closes the collection and clears up all of the resources.
 func (c *Collection) Close() error {
 	c.cancel()
@@ -341,19 +1444,64 @@ func (c *Collection) vacuum(ctx context.Context, interval time.Duration) {
 	}
 }
 
+// --------------------------- struct mapping ---------------------------
+
+// fieldsOf returns the indices of the struct fields, keyed by their column tag
+func fieldsOf(typ reflect.Type) map[string]int {
+	fields := make(map[string]int, typ.NumField())
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if name := field.Tag.Get("column"); name != "" && name != "-" && field.IsExported() {
+			fields[name] = i
+		}
+	}
+	return fields
+}
+
+// assignField assigns a column value to a struct field, converting numbers as needed
+func assignField(field reflect.Value, value interface{}) error {
+	v := reflect.ValueOf(value)
+	switch {
+	case value == nil:
+		field.Set(reflect.Zero(field.Type()))
+	case v.Type().AssignableTo(field.Type()):
+		field.Set(v)
+	case isNumberKind(v.Kind()) && isNumberKind(field.Kind()):
+		field.Set(v.Convert(field.Type()))
+	default:
+		return fmt.Errorf("type %T is not assignable to %v", value, field.Type())
+	}
+	return nil
+}
+
+// isNumberKind returns whether the kind is a numeric one
+func isNumberKind(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	default:
+		return false
+	}
+}
+
 // --------------------------- column registry ---------------------------
 
 // columns represents a concurrent column registry.
 type columns struct {
-	cols *atomic.Value
+	cols  *atomic.Value
+	alias *atomic.Value
 }
 
 func makeColumns(capacity int) columns {
 	data := columns{
-		cols: &atomic.Value{},
+		cols:  &atomic.Value{},
+		alias: &atomic.Value{},
 	}
 
 	data.cols.Store(make([]columnEntry, 0, capacity))
+	data.alias.Store(&map[string]string{})
 	return data
 }
 
@@ -394,8 +1542,9 @@ func (c *columns) RangeUntil(fn func(column *column) error) error {
 	return nil
 }
 
-// Load loads a column by its name.
+// Load loads a column by its name, or by one of its aliases.
 func (c *columns) Load(columnName string) (*column, bool) {
+	columnName = c.Resolve(columnName)
 	cols := c.cols.Load().([]columnEntry)
 	for _, v := range cols {
 		if v.name == columnName {
@@ -408,6 +1557,7 @@ func (c *columns) Load(columnName string) (*column, bool) {
 
 // LoadWithIndex loads a column by its name along with their computed indices.
 func (c *columns) LoadWithIndex(columnName string) ([]*column, bool) {
+	columnName = c.Resolve(columnName)
 	cols := c.cols.Load().([]columnEntry)
 	for _, v := range cols {
 		if v.name == columnName {
@@ -449,6 +1599,43 @@ func (c *columns) Store(columnName string, main *column, index ...*column) {
 	c.cols.Store(columns)
 }
 
+// Resolve follows the aliases of a name and returns the name of the actual column. Since
+// an alias can never be added over an existing column, names without an alias are returned
+// as they are.
+func (c *columns) Resolve(columnName string) string {
+	aliases := *c.alias.Load().(*map[string]string)
+	for len(aliases) > 0 {
+		actual, ok := aliases[columnName]
+		if !ok {
+			break
+		}
+		columnName = actual
+	}
+	return columnName
+}
+
+// StoreAlias stores an alias of a column into the registry, unless it creates a cycle.
+func (c *columns) StoreAlias(alias, actual string) error {
+	for {
+		prev := c.alias.Load().(*map[string]string)
+		for name, ok := actual, true; ok; name, ok = (*prev)[name] {
+			if name == alias {
+				return fmt.Errorf("column: unable to alias '%s' to '%s', aliases form a cycle", alias, actual)
+			}
+		}
+
+		next := make(map[string]string, len(*prev)+1)
+		for k, v := range *prev {
+			next[k] = v
+		}
+
+		next[alias] = actual
+		if c.alias.CompareAndSwap(prev, &next) {
+			return nil
+		}
+	}
+}
+
 // DeleteColumn deletes a column from the registry.
 func (c *columns) DeleteColumn(columnName string) {
 	columns := c.cols.Load().([]columnEntry)
@@ -4,16 +4,22 @@
 package column
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
+	"math"
 	"os"
+	"reflect"
 	"runtime"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/kelindar/bitmap"
+	"github.com/kelindar/column/commit"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -55,6 +61,22 @@ func BenchmarkCollection(b *testing.B) {
 		}
 	})
 
+	b.Run("insert-many", func(b *testing.B) {
+		temp := loadPlayers(500)
+		data := loadFixture("players.json")
+		b.ReportAllocs()
+		b.ResetTimer()
+		for n := 0; n < b.N; n++ {
+			b.StopTimer()
+			temp.Query(func(txn *Txn) error {
+				txn.DeleteAll()
+				return nil
+			})
+			b.StartTimer()
+			temp.InsertMany(data)
+		}
+	})
+
 	b.Run("select-at", func(b *testing.B) {
 		name := ""
 		b.ReportAllocs()
@@ -311,6 +333,27 @@ func TestExpire(t *testing.T) {
 	assert.Equal(t, 0, col.Count())
 }
 
+func TestSetExpiry(t *testing.T) {
+	col := NewCollection()
+	col.CreateColumn("name", ForString())
+	defer col.Close()
+
+	idx := col.InsertObject(Object{"name": "Roman"})
+	at := time.Now().Add(time.Hour)
+	assert.True(t, col.SetExpiry(idx, at))
+	assert.NoError(t, col.QueryAt(idx, func(r Row) error {
+		expireAt, ok := r.Int64(expireColumn)
+		assert.True(t, ok)
+		assert.Equal(t, at.UnixNano(), expireAt)
+		return nil
+	}))
+
+	// Expiry in the past deletes the object immediately
+	assert.True(t, col.SetExpiry(idx, time.Now().Add(-time.Second)))
+	assert.Equal(t, 0, col.Count())
+	assert.False(t, col.SetExpiry(idx, at))
+}
+
 func TestCreateIndex(t *testing.T) {
 	row := Object{
 		"age": 35,
@@ -344,6 +387,54 @@ func TestCreateIndexInvalidColumn(t *testing.T) {
 	}))
 }
 
+func TestCreateValueIndex(t *testing.T) {
+	players := loadPlayers(500)
+	defer players.Close()
+
+	count := func(class string) (n int) {
+		players.Query(func(txn *Txn) error {
+			n = txn.WhereEq("class", class).Count()
+			return nil
+		})
+		return
+	}
+
+	// The index must match the scan, for the values before and after its creation
+	expect := count("mage")
+	assert.NoError(t, players.CreateValueIndex("by_class", "class"))
+	assert.Equal(t, expect, count("mage"))
+	assert.Equal(t, 0, count("unknown"))
+
+	players.QueryAt(1, func(r Row) error {
+		r.SetEnum("class", "mage")
+		return nil
+	})
+	players.DeleteAt(2)
+	players.Query(func(txn *Txn) error {
+		scan := txn.WithString("class", func(v string) bool {
+			return v == "mage"
+		}).Count()
+
+		assert.Equal(t, scan, count("mage"))
+		assert.True(t, txn.index.Contains(1))
+		assert.False(t, txn.index.Contains(2))
+		return nil
+	})
+
+	// The index is recreated by a clone
+	clone, err := players.Clone()
+	assert.NoError(t, err)
+	_, ok := clone.valuesOf("class")
+	assert.True(t, ok)
+
+	// Indexes are not included in the objects and can be dropped
+	obj, _ := players.FetchFields(1)
+	assert.NotContains(t, obj, "by_class")
+	assert.NoError(t, players.DropIndex("by_class"))
+	assert.Error(t, players.CreateValueIndex("by_age", "age"))
+	assert.Error(t, players.CreateValueIndex("by_class", "invalid"))
+}
+
 func TestDropIndex(t *testing.T) {
 	row := Object{
 		"age": 35,
@@ -519,6 +610,58 @@ func TestCreateColumnsOfDuplicate(t *testing.T) {
 	assert.Error(t, col.CreateColumnsOf(obj))
 }
 
+func TestCreateColumnAfterCommit(t *testing.T) {
+	players := loadPlayers(500)
+	defer players.Close()
+
+	// The column must cover the chunk which was already committed
+	assert.NoError(t, players.CreateColumn("level", ForUint16()))
+	players.QueryAt(499, func(r Row) error {
+		r.SetUint16("level", 10)
+		return nil
+	})
+
+	players.Query(func(txn *Txn) error {
+		assert.Equal(t, 1, txn.WithUint("level", func(v uint64) bool {
+			return v == 10
+		}).Count())
+		return nil
+	})
+}
+
+func TestEnsureColumns(t *testing.T) {
+	col := NewCollection()
+	assert.NoError(t, col.CreateColumn("name", ForString()))
+	col.InsertObject(Object{"name": "Roman"})
+
+	// Missing columns are created and existing ones are left untouched
+	assert.NoError(t, col.EnsureColumns(map[string]reflect.Kind{
+		"name": reflect.String,
+		"age":  reflect.Int,
+	}))
+	assert.NoError(t, col.EnsureColumns(map[string]reflect.Kind{
+		"age": reflect.Int,
+	}))
+
+	age, _ := col.cols.Load("age")
+	assert.IsType(t, new(intColumn), age.Column)
+	name, ok := Get[string](col, 0, "name")
+	assert.True(t, ok)
+	assert.Equal(t, "Roman", name)
+
+	// Conflicting or unsupported kinds do not create anything
+	assert.Error(t, col.EnsureColumns(map[string]reflect.Kind{
+		"name":    reflect.Int,
+		"balance": reflect.Float64,
+	}))
+	assert.Error(t, col.EnsureColumns(map[string]reflect.Kind{
+		"balance": reflect.Float64,
+		"data":    reflect.Complex64,
+	}))
+	_, ok = col.cols.Load("balance")
+	assert.False(t, ok)
+}
+
 func TestFindFreeIndex(t *testing.T) {
 	col := NewCollection()
 	assert.NoError(t, col.CreateColumn("name", ForString()))
@@ -532,6 +675,1349 @@ func TestFindFreeIndex(t *testing.T) {
 	}
 }
 
+func TestAppend(t *testing.T) {
+	col := NewCollection()
+	assert.NoError(t, col.CreateColumn("name", ForString()))
+	for i := 0; i < 3; i++ {
+		idx, err := col.Append("name", fmt.Sprintf("log-%d", i))
+		assert.NoError(t, err)
+		assert.Equal(t, i, int(idx))
+	}
+
+	// Freed indices must not be reused by an append
+	assert.True(t, col.DeleteAt(1))
+	idx, err := col.Append("name", "log-3")
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(3), idx)
+	assert.Equal(t, 3, col.Count())
+	assert.NoError(t, col.QueryAt(idx, func(r Row) error {
+		name, ok := r.String("name")
+		assert.True(t, ok)
+		assert.Equal(t, "log-3", name)
+		return nil
+	}))
+
+	// Nor the freed index at the tail of the collection
+	assert.True(t, col.DeleteAt(3))
+	idx, err = col.Append("name", "log-4")
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(4), idx)
+
+	// Unknown column
+	_, err = col.Append("invalid", "hello")
+	assert.Error(t, err)
+}
+
+func TestCopyColumn(t *testing.T) {
+	col := NewCollection()
+	assert.NoError(t, col.CreateColumn("wallet", ForFloat64()))
+	assert.NoError(t, col.CreateColumn("key", ForKey()))
+	for i := 0; i < 100; i++ {
+		col.InsertObject(Object{"wallet": float64(i)})
+	}
+
+	assert.NoError(t, col.CopyColumn("wallet", "copy"))
+	assert.Error(t, col.CopyColumn("wallet", "copy"))
+	assert.Error(t, col.CopyColumn("invalid", "other"))
+	assert.Error(t, col.CopyColumn("key", "other"))
+
+	// Mutate the copy, the source must remain untouched
+	col.Query(func(txn *Txn) error {
+		copy := txn.Float64("copy")
+		return txn.Range(func(idx uint32) {
+			copy.Add(1000)
+		})
+	})
+
+	assert.NoError(t, col.QueryAt(10, func(r Row) error {
+		wallet, _ := r.Float64("wallet")
+		copy, _ := r.Float64("copy")
+		assert.Equal(t, 10.0, wallet)
+		assert.Equal(t, 1010.0, copy)
+		return nil
+	}))
+}
+
+func TestVersion(t *testing.T) {
+	col := NewCollection()
+	assert.NoError(t, col.CreateColumn("name", ForString()))
+	assert.Equal(t, uint64(0), col.Version())
+
+	// Each insert is a single change
+	col.InsertObject(Object{"name": "A"})
+	col.InsertObject(Object{"name": "B"})
+	assert.Equal(t, uint64(2), col.Version())
+
+	// A transaction without changes must not bump the version
+	col.Query(func(txn *Txn) error {
+		txn.Count()
+		return nil
+	})
+	assert.Equal(t, uint64(2), col.Version())
+
+	// An update and a delete
+	col.QueryAt(0, func(r Row) error {
+		r.SetString("name", "C")
+		return nil
+	})
+	col.DeleteAt(1)
+	assert.Equal(t, uint64(4), col.Version())
+}
+
+func TestDeleteMany(t *testing.T) {
+	players := loadPlayers(500)
+	assert.True(t, players.DeleteAt(10))
+
+	var indices bitmap.Bitmap
+	for i := uint32(0); i < 100; i++ {
+		indices.Set(i)
+	}
+	indices.Set(10000)
+
+	// Index 10 was already deleted and 10000 was never present
+	assert.Equal(t, 99, players.DeleteMany(indices))
+	assert.Equal(t, 400, players.Count())
+	assert.Equal(t, 0, players.DeleteMany(indices))
+}
+
+func TestColumnStats(t *testing.T) {
+	col := NewCollection(Options{Stats: true})
+	assert.NoError(t, col.CreateColumn("name", ForString()))
+	assert.NoError(t, col.CreateColumn("age", ForInt()))
+	col.InsertObject(Object{"name": "Roman", "age": 35})
+	col.InsertObject(Object{"name": "Merlin", "age": 200})
+
+	col.Query(func(txn *Txn) error {
+		age := txn.Int("age")
+		return txn.Range(func(idx uint32) {
+			v, _ := age.Get()
+			age.Set(v + 1)
+		})
+	})
+
+	reads, writes, ok := col.ColumnStats("age")
+	assert.True(t, ok)
+	assert.Equal(t, uint64(2), reads)
+	assert.Equal(t, uint64(4), writes)
+
+	reads, writes, ok = col.ColumnStats("name")
+	assert.True(t, ok)
+	assert.Equal(t, uint64(0), reads)
+	assert.Equal(t, uint64(2), writes)
+
+	_, _, ok = col.ColumnStats("invalid")
+	assert.False(t, ok)
+
+	// The counters are disabled by default
+	other := NewCollection()
+	assert.NoError(t, other.CreateColumn("age", ForInt()))
+	other.InsertObject(Object{"age": 35})
+	reads, writes, ok = other.ColumnStats("age")
+	assert.True(t, ok)
+	assert.Zero(t, reads)
+	assert.Zero(t, writes)
+}
+
+func TestRollback(t *testing.T) {
+	col := NewCollection(Options{History: 3})
+	assert.NoError(t, col.CreateColumn("name", ForString()))
+	assert.NoError(t, col.CreateColumn("age", ForInt()))
+	assert.NoError(t, col.CreateColumn("active", ForBool()))
+	col.InsertObject(Object{"name": "Roman", "age": 35, "active": true})
+	col.InsertObject(Object{"name": "Merlin", "age": 200})
+	version := col.Version()
+
+	// Update, delete and insert
+	col.QueryAt(0, func(r Row) error {
+		r.SetInt("age", 36)
+		r.SetBool("active", false)
+		return nil
+	})
+	col.DeleteAt(1)
+	col.InsertObject(Object{"name": "Arthur"})
+	assert.Equal(t, 2, col.Count())
+
+	// Rollback to the original state
+	assert.NoError(t, col.Rollback(version))
+	assert.Equal(t, 2, col.Count())
+	assert.Equal(t, version+3+3, col.Version())
+	assert.NoError(t, col.QueryAt(0, func(r Row) error {
+		age, _ := r.Int("age")
+		assert.Equal(t, 35, age)
+		assert.True(t, r.Bool("active"))
+		return nil
+	}))
+	assert.NoError(t, col.QueryAt(1, func(r Row) error {
+		name, _ := r.String("name")
+		age, _ := r.Int("age")
+		assert.Equal(t, "Merlin", name)
+		assert.Equal(t, 200, age)
+		assert.False(t, r.Bool("active"))
+		return nil
+	}))
+	assert.NoError(t, col.QueryAt(2, func(r Row) error {
+		_, ok := r.String("name")
+		assert.False(t, ok)
+		return nil
+	}))
+
+	// Older versions are no longer retained
+	assert.Error(t, col.Rollback(version))
+	assert.Error(t, col.Rollback(col.Version()+1))
+	assert.NoError(t, col.Rollback(col.Version()))
+	assert.Equal(t, errNoHistory, NewCollection().Rollback(0))
+}
+
+func TestFetchIntoStructs(t *testing.T) {
+	type player struct {
+		Name    string  `column:"name"`
+		Class   string  `column:"class"`
+		Age     int     `column:"age"`
+		Active  bool    `column:"active"`
+		Balance float32 `column:"balance"`
+		Other   string
+	}
+
+	players := loadPlayers(500)
+	players.DeleteAt(1)
+
+	out := make([]player, 10)
+	assert.NoError(t, players.FetchIntoStructs([]uint32{0, 1, 2, 100000}, &out))
+	assert.Len(t, out, 2)
+	assert.NoError(t, players.QueryAt(2, func(r Row) error {
+		name, _ := r.Enum("name")
+		age, _ := r.Float64("age")
+		balance, _ := r.Float64("balance")
+		assert.Equal(t, name, out[1].Name)
+		assert.Equal(t, int(age), out[1].Age)
+		assert.Equal(t, float32(balance), out[1].Balance)
+		assert.Equal(t, r.Bool("active"), out[1].Active)
+		return nil
+	}))
+
+	// Invalid destinations and types
+	assert.Error(t, players.FetchIntoStructs([]uint32{0}, out))
+	assert.Error(t, players.FetchIntoStructs([]uint32{0}, &[]int{}))
+	assert.Error(t, players.FetchIntoStructs([]uint32{0}, &[]struct {
+		Name int `column:"name"`
+	}{}))
+}
+
+func TestReadObject(t *testing.T) {
+	col := NewCollection()
+	col.CreateColumn("name", ForString())
+	col.CreateColumn("class", ForEnum())
+	col.CreateColumn("age", ForInt())
+	col.CreateColumn("active", ForBool())
+	col.CreateColumn("balance", ForFloat64())
+	col.CreateIndex("rich", "balance", func(r Reader) bool {
+		return r.Float() > 100
+	})
+
+	bufferOf := func(name string, fn func(*commit.Buffer)) *commit.Buffer {
+		buffer := commit.NewBuffer(0)
+		buffer.Reset(name)
+		fn(buffer)
+		return buffer
+	}
+
+	object := col.ReadObject(commit.Commit{
+		Updates: []*commit.Buffer{
+			bufferOf(rowColumn, func(b *commit.Buffer) { b.PutOperation(commit.Insert, 5) }),
+			bufferOf("name", func(b *commit.Buffer) { b.PutString(commit.Put, 5, "Roman") }),
+			bufferOf("class", func(b *commit.Buffer) {
+				b.PutString(commit.Put, 5, "mage")
+				b.PutOperation(commit.Delete, 5)
+			}),
+			bufferOf("age", func(b *commit.Buffer) {
+				b.PutInt(4, 10)
+				b.AddInt(5, 1)
+			}),
+			bufferOf("active", func(b *commit.Buffer) { b.PutBool(5, false) }),
+			bufferOf("balance", func(b *commit.Buffer) { b.PutFloat64(5, 150) }),
+			bufferOf("invalid", func(b *commit.Buffer) { b.PutInt(5, 1) }),
+		},
+	}, 5)
+
+	assert.Equal(t, Object{
+		"name":    "Roman",
+		"age":     1,
+		"active":  false,
+		"balance": 150.0,
+	}, object)
+
+	// Numbers which are deleted do not carry any value
+	object = col.ReadObject(commit.Commit{
+		Updates: []*commit.Buffer{
+			bufferOf("balance", func(b *commit.Buffer) {
+				b.PutFloat64(5, 150)
+				b.PutOperation(commit.Delete, 5)
+			}),
+		},
+	}, 5)
+	assert.Equal(t, Object{}, object)
+}
+
+func TestMemStats(t *testing.T) {
+	players := loadPlayers(500)
+	before := players.MemStats()
+	assert.NotZero(t, before.Dense)
+	assert.NotZero(t, before.Sparse)
+	assert.NotZero(t, before.Bitmaps)
+	assert.NotZero(t, before.Dictionaries)
+
+	// Accounting must be consistent
+	assert.Equal(t, before, players.MemStats())
+
+	// A new string column only grows the dense slices
+	players.CreateColumn("note", ForString())
+	players.Query(func(txn *Txn) error {
+		note := txn.String("note")
+		return txn.Range(func(idx uint32) {
+			note.Set("hello")
+		})
+	})
+
+	after := players.MemStats()
+	assert.Greater(t, after.Dense, before.Dense)
+	assert.Equal(t, before.Sparse, after.Sparse)
+	assert.Equal(t, before.Dictionaries, after.Dictionaries)
+}
+
+func TestKeys(t *testing.T) {
+	players := loadPlayers(500)
+	players.DeleteAt(0)
+	players.DeleteAt(10)
+
+	keys := players.Keys()
+	assert.Len(t, keys, 498)
+	assert.Equal(t, uint32(1), keys[0])
+	assert.Equal(t, uint32(11), keys[9])
+	assert.Equal(t, uint32(499), keys[497])
+	assert.Empty(t, NewCollection().Keys())
+}
+
+func TestGet(t *testing.T) {
+	players := loadPlayers(500)
+	name, ok := Get[string](players, 0, "name")
+	assert.True(t, ok)
+	assert.Equal(t, "Maura Daugherty", name)
+
+	age, ok := Get[float64](players, 0, "age")
+	assert.True(t, ok)
+	assert.NotZero(t, age)
+
+	// Type mismatch, missing column and missing value
+	_, ok = Get[int](players, 0, "age")
+	assert.False(t, ok)
+	_, ok = Get[string](players, 0, "invalid")
+	assert.False(t, ok)
+	_, ok = Get[string](players, 0, "human")
+	assert.False(t, ok)
+	_, ok = Get[string](players, 100000, "name")
+	assert.False(t, ok)
+}
+
+func TestClear(t *testing.T) {
+	players := loadPlayers(500)
+	name, _ := Get[string](players, 0, "name")
+	assert.True(t, players.Clear(0, "age"))
+	assert.True(t, players.Clear(0, "serial"))
+	assert.False(t, players.Clear(0, "age"))
+	assert.False(t, players.Clear(0, "invalid"))
+	assert.False(t, players.Clear(0, "human"))
+
+	// Other values and the object itself must remain
+	_, ok := Get[float64](players, 0, "age")
+	assert.False(t, ok)
+	_, ok = Get[string](players, 0, "serial")
+	assert.False(t, ok)
+	v, ok := Get[string](players, 0, "name")
+	assert.True(t, ok)
+	assert.Equal(t, name, v)
+	assert.Equal(t, 500, players.Count())
+}
+
+func TestFetchFields(t *testing.T) {
+	players := loadPlayers(500)
+	players.Clear(0, "age")
+	name, _ := Get[string](players, 0, "name")
+
+	object, ok := players.FetchFields(0, "name", "age", "invalid", "human")
+	assert.True(t, ok)
+	assert.Equal(t, Object{"name": name}, object)
+
+	players.DeleteAt(1)
+	_, ok = players.FetchFields(1, "name")
+	assert.False(t, ok)
+	_, ok = players.FetchFields(100000, "name")
+	assert.False(t, ok)
+}
+
+func TestObserve(t *testing.T) {
+	players := loadPlayers(500)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Keep changing the collection while the snapshot is read
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 200; i++ {
+			players.Query(func(txn *Txn) error {
+				txn.QueryAt(uint32(i), func(r Row) error {
+					r.AddFloat64("balance", 1)
+					return nil
+				})
+				return nil
+			})
+			players.DeleteAt(uint32(200 + i))
+			players.InsertObject(Object{"name": fmt.Sprintf("Player %d", i), "age": float64(i)})
+		}
+	}()
+
+	view, changes := players.Observe(ctx)
+	wg.Wait()
+
+	// Apply the changes on top of the snapshot until it converges with the collection
+	expect := make(map[uint32]Object)
+	players.Query(func(txn *Txn) error {
+		return txn.Range(func(idx uint32) {
+			object := make(Object)
+			players.readObject(idx, object)
+			expect[idx] = object
+		})
+	})
+
+	timeout := time.After(5 * time.Second)
+	for !assert.ObjectsAreEqual(expect, view) {
+		select {
+		case change := <-changes:
+			switch {
+			case change.Type == commit.Insert:
+				view[change.Index] = Object{}
+			case change.Type == commit.Delete && change.Column == "":
+				delete(view, change.Index)
+			case change.Type == commit.Delete:
+				delete(view[change.Index], change.Column)
+			default:
+				view[change.Index][change.Column] = change.Value
+			}
+		case <-timeout:
+			assert.Fail(t, "view did not converge")
+			return
+		}
+	}
+
+	// Once cancelled, the channel is closed
+	cancel()
+	for range changes {
+	}
+
+	// An empty collection can be observed as well
+	empty, _ := NewCollection().Observe(ctx)
+	assert.Empty(t, empty)
+}
+
+func TestObserveRemoved(t *testing.T) {
+	players := loadPlayers(500)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	view, changes := players.Observe(ctx)
+	expect := view[5]
+	assert.NotEmpty(t, expect)
+
+	// Deleting an object provides its values as they were before the deletion
+	players.Query(func(txn *Txn) error {
+		txn.QueryAt(5, func(r Row) error {
+			r.SetFloat64("balance", 0)
+			return nil
+		})
+		txn.DeleteAt(5)
+		return nil
+	})
+
+	for change := range changes {
+		if change.Type == commit.Delete && change.Column == "" {
+			assert.Equal(t, uint32(5), change.Index)
+			assert.Equal(t, expect, change.Object)
+			return
+		}
+		assert.Nil(t, change.Object)
+	}
+}
+
+func TestColumnMinMax(t *testing.T) {
+	players := loadPlayers(500)
+	extremes := func() (min, max float64) {
+		min, max = math.MaxFloat64, -math.MaxFloat64
+		players.Query(func(txn *Txn) error {
+			return txn.Range(func(idx uint32) {
+				if v, ok := Get[float64](players, idx, "balance"); ok {
+					min, max = math.Min(min, v), math.Max(max, v)
+				}
+			})
+		})
+		return
+	}
+
+	assertExtremes := func() {
+		min, max := extremes()
+		actualMin, ok := players.ColumnMin("balance")
+		assert.True(t, ok)
+		assert.Equal(t, min, actualMin)
+		actualMax, ok := players.ColumnMax("balance")
+		assert.True(t, ok)
+		assert.Equal(t, max, actualMax)
+	}
+
+	indexOf := func(value float64) (found uint32) {
+		players.Query(func(txn *Txn) error {
+			return txn.WhereEq("balance", value).Range(func(idx uint32) {
+				found = idx
+			})
+		})
+		return
+	}
+
+	// Removing, lowering and raising the extremes
+	assertExtremes()
+	min, max := extremes()
+	players.DeleteAt(indexOf(min))
+	assertExtremes()
+	players.QueryAt(indexOf(max), func(r Row) error {
+		r.SetFloat64("balance", 0)
+		return nil
+	})
+	assertExtremes()
+	players.QueryAt(0, func(r Row) error {
+		r.AddFloat64("balance", 1e9)
+		return nil
+	})
+	assertExtremes()
+	players.Clear(0, "balance")
+	assertExtremes()
+
+	// Columns which are not numeric, missing or empty
+	_, ok := players.ColumnMin("name")
+	assert.False(t, ok)
+	_, ok = players.ColumnMax("invalid")
+	assert.False(t, ok)
+	players.CreateColumn("score", ForInt32())
+	_, ok = players.ColumnMin("score")
+	assert.False(t, ok)
+
+	// The extremes of each chunk are combined
+	col := NewCollection()
+	col.CreateColumn("v", ForInt())
+	for i := 0; i < 20000; i++ {
+		col.InsertObject(Object{"v": i % 1000})
+	}
+	col.InsertObject(Object{"v": -1})
+	col.DeleteAt(20000)
+	col.InsertObject(Object{"v": 5000})
+	lowest, ok := col.ColumnMin("v")
+	assert.True(t, ok)
+	assert.Equal(t, 0, lowest)
+	highest, ok := col.ColumnMax("v")
+	assert.True(t, ok)
+	assert.Equal(t, 5000, highest)
+}
+
+func TestFetchConcurrent(t *testing.T) {
+	players := loadPlayers(500)
+	players.DeleteAt(10)
+
+	indices := make([]uint32, 0, 1000)
+	for i := 999; i >= 0; i-- {
+		indices = append(indices, uint32(i))
+	}
+
+	for _, workers := range []int{0, 1, 3, 8, 2000} {
+		objects := players.FetchConcurrent(indices, workers)
+		assert.Len(t, objects, len(indices))
+		for i, idx := range indices {
+			if idx >= 500 || idx == 10 {
+				assert.Nil(t, objects[i])
+				continue
+			}
+
+			expect, _ := Get[string](players, idx, "serial")
+			assert.Equal(t, expect, objects[i]["serial"])
+		}
+	}
+
+	assert.Empty(t, players.FetchConcurrent(nil, 4))
+}
+
+func TestFetchByKey(t *testing.T) {
+	players := newEmpty(500)
+	players.CreateColumn("tenant", ForString())
+	players.CreateColumn("id", ForInt64())
+	for i := 0; i < 100; i++ {
+		players.InsertObject(Object{
+			"tenant": fmt.Sprintf("tenant-%d", i%10),
+			"id":     int64(i / 10),
+			"name":   fmt.Sprintf("Player %d", i),
+		})
+	}
+
+	keys := []string{"tenant", "id"}
+	object, ok := players.FetchByKey(keys, []interface{}{"tenant-3", 5})
+	assert.True(t, ok)
+	assert.Equal(t, "Player 53", object["name"])
+
+	// The key is maintained on every commit
+	idx := players.InsertObject(Object{"tenant": "tenant-3", "id": int64(10), "name": "New"})
+	object, ok = players.FetchByKey(keys, []interface{}{"tenant-3", 10})
+	assert.True(t, ok)
+	assert.Equal(t, "New", object["name"])
+
+	players.QueryAt(idx, func(r Row) error {
+		r.SetInt64("id", 11)
+		return nil
+	})
+	_, ok = players.FetchByKey(keys, []interface{}{"tenant-3", 10})
+	assert.False(t, ok)
+	_, ok = players.FetchByKey(keys, []interface{}{"tenant-3", 11})
+	assert.True(t, ok)
+
+	players.DeleteAt(idx)
+	_, ok = players.FetchByKey(keys, []interface{}{"tenant-3", 11})
+	assert.False(t, ok)
+
+	// Length-prefixed values do not collide when joined
+	a := players.InsertObject(Object{"tenant": "a1", "id": int64(23), "name": "A"})
+	b := players.InsertObject(Object{"tenant": "a", "id": int64(123), "name": "B"})
+	assert.NotEqual(t, a, b)
+	object, ok = players.FetchByKey(keys, []interface{}{"a1", 23})
+	assert.True(t, ok)
+	assert.Equal(t, "A", object["name"])
+	object, ok = players.FetchByKey(keys, []interface{}{"a", 123})
+	assert.True(t, ok)
+	assert.Equal(t, "B", object["name"])
+
+	// Invalid lookups
+	_, ok = players.FetchByKey(keys, []interface{}{"tenant-3"})
+	assert.False(t, ok)
+	_, ok = players.FetchByKey([]string{"invalid"}, []interface{}{1})
+	assert.False(t, ok)
+	_, ok = players.FetchByKey(nil, nil)
+	assert.False(t, ok)
+}
+
+func TestAddAlias(t *testing.T) {
+	players := loadPlayers(500)
+	assert.NoError(t, players.AddAlias("wealth", "balance"))
+	assert.NoError(t, players.AddAlias("money", "wealth"))
+
+	countOf := func(columnName string) (count int) {
+		players.Query(func(txn *Txn) error {
+			count = txn.WithFloat(columnName, func(v float64) bool {
+				return v > 3000
+			}).Count()
+			return nil
+		})
+		return
+	}
+
+	// Aliases are resolved in queries, when fetching and when writing
+	assert.NotZero(t, countOf("balance"))
+	assert.Equal(t, countOf("balance"), countOf("wealth"))
+	assert.Equal(t, countOf("balance"), countOf("money"))
+
+	expect, _ := Get[float64](players, 0, "balance")
+	object, ok := players.FetchFields(0, "money")
+	assert.True(t, ok)
+	assert.Equal(t, expect, object["money"])
+
+	assert.NoError(t, players.QueryAt(0, func(r Row) error {
+		r.SetFloat64("wealth", 42)
+		return nil
+	}))
+	balance, _ := Get[float64](players, 0, "balance")
+	assert.Equal(t, float64(42), balance)
+
+	// Aliases are resolved at query time, so the column can be created later
+	assert.NoError(t, players.AddAlias("years", "level"))
+	_, ok = players.FetchFields(0, "years")
+	assert.True(t, ok)
+	assert.NoError(t, players.CreateColumn("level", ForInt()))
+	assert.Error(t, players.CreateColumn("years", ForInt()))
+
+	// Cycles and existing columns are rejected
+	assert.Error(t, players.AddAlias("balance", "wealth"))
+	assert.Error(t, players.AddAlias("wealth", "money"))
+	assert.Error(t, players.AddAlias("self", "self"))
+	assert.Error(t, players.AddAlias("name", "balance"))
+}
+
+func TestReserve(t *testing.T) {
+	players := loadPlayers(500)
+	players.DeleteAt(20)
+	assert.Equal(t, 499, players.Count())
+
+	// Reserved indices are allocated like inserted ones and are live
+	indices := players.Reserve(3)
+	assert.Equal(t, []uint32{500, 501, 502}, indices)
+	assert.Equal(t, 502, players.Count())
+	assert.Contains(t, players.Keys(), uint32(501))
+
+	object, ok := players.FetchFields(501, "name")
+	assert.True(t, ok)
+	assert.Empty(t, object)
+
+	// Reserved objects can be filled later on
+	assert.NoError(t, players.QueryAt(501, func(r Row) error {
+		r.SetEnum("name", "Reserved")
+		return nil
+	}))
+	object, _ = players.FetchFields(501, "name")
+	assert.Equal(t, "Reserved", object["name"])
+	assert.Empty(t, players.Reserve(0))
+}
+
+func TestInsertMany(t *testing.T) {
+	players := loadPlayers(500)
+	players.DeleteAt(20)
+
+	// Indices are returned in the order of the objects
+	indices := players.InsertMany([]Object{
+		{"name": "Alice", "age": 20.0},
+		{"name": "Bob", "age": 30.0},
+	})
+	assert.Len(t, indices, 2)
+	assert.NotEqual(t, indices[0], indices[1])
+	assert.Equal(t, 501, players.Count())
+
+	name, _ := Get[string](players, indices[0], "name")
+	assert.Equal(t, "Alice", name)
+	age, _ := Get[float64](players, indices[1], "age")
+	assert.Equal(t, 30.0, age)
+	assert.Empty(t, players.InsertMany(nil))
+}
+
+func TestUpdate(t *testing.T) {
+	players := loadPlayers(500)
+	assert.True(t, players.Update(400, Object{
+		"name":    "Roman",
+		"age":     30.0,
+		"nick":    "R",
+		"invalid": complex64(1),
+	}))
+
+	// The object keeps its index and other values, and missing columns are created
+	object, ok := players.FetchFields(400, "name", "age", "nick", "race", "invalid")
+	assert.True(t, ok)
+	assert.Equal(t, "Roman", object["name"])
+	assert.Equal(t, 30.0, object["age"])
+	assert.Equal(t, "R", object["nick"])
+	assert.NotEmpty(t, object["race"])
+	assert.NotContains(t, object, "invalid")
+	assert.Equal(t, 500, players.Count())
+
+	// Deleted objects can not be updated
+	players.DeleteAt(20)
+	assert.False(t, players.Update(20, Object{"name": "Roman"}))
+	assert.False(t, players.Update(100000, Object{"name": "Roman"}))
+	object, _ = players.FetchFields(20, "name")
+	assert.Empty(t, object)
+}
+
+func TestMapColumn(t *testing.T) {
+	col := NewCollection()
+	col.CreateColumn("name", ForString())
+	col.CreateColumn("price", ForFloat64())
+	col.CreateColumn("total", ForFloat64())
+	col.CreateColumn("label", ForString())
+	a := col.InsertObject(Object{"name": "a", "price": 10.0})
+	b := col.InsertObject(Object{"name": "b", "price": 2.5})
+	n := col.InsertObject(Object{"name": "none", "total": 1.0})
+
+	var calls int
+	assert.NoError(t, col.MapColumn("price", "total", func(v interface{}) interface{} {
+		calls++
+		return v.(float64) * 2
+	}))
+	assert.Equal(t, 2, calls)
+
+	for idx, expect := range map[uint32]float64{a: 20, b: 5, n: 1} {
+		total, ok := Get[float64](col, idx, "total")
+		assert.True(t, ok)
+		assert.Equal(t, expect, total)
+	}
+
+	// Unset values can be opted into, and nil results clear the destination
+	assert.NoError(t, col.MapColumnWithUnset("price", "label", func(v interface{}) interface{} {
+		if v == nil {
+			return "unpriced"
+		}
+		return fmt.Sprintf("$%.2f", v)
+	}))
+	assert.NoError(t, col.MapColumn("name", "total", func(v interface{}) interface{} {
+		return nil
+	}))
+
+	for idx, expect := range map[uint32]string{a: "$10.00", b: "$2.50", n: "unpriced"} {
+		label, _ := Get[string](col, idx, "label")
+		assert.Equal(t, expect, label)
+		_, ok := Get[float64](col, idx, "total")
+		assert.False(t, ok)
+	}
+
+	// Missing columns
+	assert.Error(t, col.MapColumn("missing", "total", nil))
+	assert.Error(t, col.MapColumn("price", "missing", nil))
+}
+
+func TestCountDistinctApprox(t *testing.T) {
+	col := NewCollection()
+	col.CreateColumn("name", ForString())
+	col.CreateColumn("age", ForInt())
+	col.CreateColumn("old", ForBool())
+	col.CreateIndex("adult", "age", func(r Reader) bool {
+		return r.Int() >= 18
+	})
+
+	for i := 0; i < 20000; i++ {
+		col.InsertObject(Object{"name": fmt.Sprintf("user-%d", i%5000), "age": i % 100})
+	}
+
+	assertNear := func(expect int, actual uint64) {
+		assert.InDelta(t, float64(expect), float64(actual), 0.025*float64(expect))
+	}
+
+	// The sketch is built on the first call and maintained afterwards
+	assertNear(5000, col.CountDistinctApprox("name"))
+	assert.Equal(t, uint64(100), col.CountDistinctApprox("age"))
+	for i := 0; i < 1000; i++ {
+		col.InsertObject(Object{"name": fmt.Sprintf("new-%d", i)})
+	}
+	assertNear(6000, col.CountDistinctApprox("name"))
+
+	// Removing values rebuilds the sketch
+	col.Query(func(txn *Txn) error {
+		txn.WithString("name", func(v string) bool {
+			return strings.HasPrefix(v, "user-")
+		}).DeleteAll()
+		return nil
+	})
+	assertNear(1000, col.CountDistinctApprox("name"))
+	assert.Equal(t, uint64(0), col.CountDistinctApprox("age"))
+
+	// Empty, missing and index columns
+	assert.Equal(t, uint64(0), col.CountDistinctApprox("old"))
+	assert.Equal(t, uint64(0), col.CountDistinctApprox("missing"))
+	assert.Equal(t, uint64(0), col.CountDistinctApprox("adult"))
+}
+
+func TestFetchPooled(t *testing.T) {
+	players := loadPlayers(500)
+	expect, ok := players.FetchFields(10, "name", "race", "age")
+	assert.True(t, ok)
+
+	object, release := players.FetchPooled(10)
+	assert.Equal(t, expect["name"], object["name"])
+	assert.Equal(t, expect["race"], object["race"])
+	assert.Equal(t, expect["age"], object["age"])
+
+	// The object is cleared on release
+	release()
+	assert.Empty(t, object)
+
+	// Missing objects are not returned
+	players.DeleteAt(20)
+	object, release = players.FetchPooled(20)
+	assert.Nil(t, object)
+	release()
+}
+
+func TestAddAt(t *testing.T) {
+	col := NewCollection()
+	col.CreateColumn("name", ForString())
+	col.CreateColumn("age", ForInt())
+	assert.NoError(t, col.AddAt(5000, Object{"name": "Roman", "age": 30}))
+	assert.Equal(t, 1, col.Count())
+
+	object, ok := col.FetchFields(5000, "name", "age")
+	assert.True(t, ok)
+	assert.Equal(t, Object{"name": "Roman", "age": 30}, object)
+
+	// Live indices can not be added again
+	assert.Error(t, col.AddAt(5000, Object{"name": "Other"}))
+	object, _ = col.FetchFields(5000, "name")
+	assert.Equal(t, "Roman", object["name"])
+
+	// A freed index is no longer free once added at
+	idx := col.InsertObject(Object{"name": "Freed"})
+	col.DeleteAt(idx)
+	assert.NoError(t, col.AddAt(idx, Object{"name": "Joe"}))
+	assert.NotEqual(t, idx, col.InsertObject(Object{"name": "Next"}))
+	assert.Equal(t, 3, col.Count())
+}
+
+func TestSetTraceWriter(t *testing.T) {
+	col := NewCollection()
+	col.CreateColumn("name", ForString())
+	col.CreateColumn("active", ForBool())
+	col.CreateColumn("balance", ForFloat64())
+
+	var output bytes.Buffer
+	col.SetTraceWriter(&output)
+	idx := col.InsertObject(Object{"name": "Roman", "active": false})
+	col.QueryAt(idx, func(r Row) error {
+		r.AddFloat64("balance", 10)
+		return nil
+	})
+	col.Clear(idx, "balance")
+	col.DeleteAt(idx)
+
+	lines := strings.Split(strings.TrimSpace(output.String()), "\n")
+	assert.Len(t, lines, 6)
+	assert.Contains(t, lines[0], " insert row 0")
+	assert.ElementsMatch(t, []string{"put active 0 false", "put name 0 Roman"}, []string{
+		lines[1][strings.Index(lines[1], " ")+1:],
+		lines[2][strings.Index(lines[2], " ")+1:],
+	})
+	assert.Contains(t, lines[3], " put balance 0 10")
+	assert.Contains(t, lines[4], " delete balance 0 <nil>")
+	assert.Contains(t, lines[5], " delete row 0")
+
+	// Once disabled, nothing is traced
+	output.Reset()
+	col.SetTraceWriter(nil)
+	col.InsertObject(Object{"name": "Roman"})
+	assert.Zero(t, output.Len())
+}
+
+func TestCompactColumn(t *testing.T) {
+	players := loadPlayers(500)
+	players.CreateColumn("nick", ForString())
+	players.Query(func(txn *Txn) error {
+		return txn.Range(func(idx uint32) {
+			txn.QueryAt(idx, func(r Row) error {
+				r.SetString("nick", fmt.Sprintf("nick-%d", idx))
+				return nil
+			})
+		})
+	})
+
+	// Delete a lot of objects, which leaves their values and strings behind
+	players.Query(func(txn *Txn) error {
+		txn.WithValue("race", func(v interface{}) bool {
+			return v != "human"
+		}).DeleteAll()
+		return nil
+	})
+
+	fetchAll := func() []Object {
+		return players.FetchConcurrent(players.Keys(), 1)
+	}
+
+	before := players.MemStats()
+	expect := fetchAll()
+	for _, name := range []string{"serial", "name", "nick", "age", "active", "human"} {
+		assert.NoError(t, players.CompactColumn(name))
+	}
+
+	assert.Equal(t, expect, fetchAll())
+	assert.Less(t, players.MemStats().Dictionaries, before.Dictionaries)
+	assert.Error(t, players.CompactColumn("invalid"))
+
+	// The columns are still usable after being compacted
+	idx := players.InsertObject(Object{"serial": "new", "name": "Roman", "nick": "R"})
+	object, ok := players.FetchByKey([]string{"name", "nick"}, []interface{}{"Roman", "R"})
+	assert.True(t, ok)
+	assert.Equal(t, "new", object["serial"])
+	assert.NoError(t, players.QueryKey("new", func(r Row) error {
+		assert.Equal(t, idx, r.txn.cursor)
+		return nil
+	}))
+}
+
+func TestHistogram(t *testing.T) {
+	col := NewCollection()
+	col.CreateColumn("name", ForString())
+	col.CreateColumn("score", ForFloat64())
+	for _, v := range []float64{-5, 0, 1, 4.99, 5, 9.99, 10, 20, math.NaN()} {
+		col.InsertObject(Object{"name": "x", "score": v})
+	}
+	col.InsertObject(Object{"name": "missing"})
+
+	assert.Equal(t, []int{1, 3, 2, 2}, col.Histogram("score", []float64{0, 5, 10}))
+	assert.Equal(t, []int{8}, col.Histogram("score", nil))
+
+	// Deleted values are not counted
+	col.DeleteAt(0)
+	assert.Equal(t, []int{0, 3, 2, 2}, col.Histogram("score", []float64{0, 5, 10}))
+
+	// Non-numeric and missing columns have no values to count
+	assert.Equal(t, []int{0, 0}, col.Histogram("name", []float64{0}))
+	assert.Equal(t, []int{0, 0}, col.Histogram("missing", []float64{0}))
+}
+
+func TestMarshalJSON(t *testing.T) {
+	players := loadPlayers(500)
+	players.DeleteAt(0)
+	encoded, err := json.Marshal(players)
+	assert.NoError(t, err)
+
+	// Unmarshal into an empty collection, creating the columns
+	output := NewCollection()
+	assert.NoError(t, json.Unmarshal(encoded, output))
+	assert.Equal(t, players.Count(), output.Count())
+
+	// Objects are inserted in order, so encoding again should produce the same array
+	reencoded, err := json.Marshal(output)
+	assert.NoError(t, err)
+	assert.JSONEq(t, string(encoded), string(reencoded))
+
+	// The expiration of the objects is internal, so it is not encoded
+	col := NewCollection()
+	col.CreateColumn("name", ForString())
+	col.InsertObjectWithTTL(Object{"name": "Roman"}, time.Hour)
+	encoded, err = json.Marshal(col)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `[{"name":"Roman"}]`, string(encoded))
+}
+
+func TestUnmarshalJSON(t *testing.T) {
+	output := NewCollection()
+	output.CreateColumn("id", ForUint64())
+	output.CreateColumn("ttl", ForInt64())
+	output.CreateColumn("name", ForString())
+	assert.NoError(t, output.UnmarshalJSON([]byte(`[
+		{"id": 18446744073709551615, "ttl": 1666000000123456789, "name": "a", "hp": 1.5},
+		{"id": 2, "name": "b", "active": true, "note": null}
+	]`)))
+
+	assert.Equal(t, 2, output.Count())
+	assert.NoError(t, output.QueryAt(0, func(r Row) error {
+		id, _ := r.Uint64("id")
+		ttl, _ := r.Int64("ttl")
+		hp, _ := r.Float64("hp")
+		assert.Equal(t, uint64(math.MaxUint64), id)
+		assert.Equal(t, int64(1666000000123456789), ttl)
+		assert.Equal(t, 1.5, hp)
+		return nil
+	}))
+
+	assert.NoError(t, output.QueryAt(1, func(r Row) error {
+		active := r.Bool("active")
+		name, _ := r.String("name")
+		assert.True(t, active)
+		assert.Equal(t, "b", name)
+		return nil
+	}))
+
+	// Numbers can not be decoded into a string column, nor can invalid JSON
+	assert.Error(t, output.UnmarshalJSON([]byte(`[{"name": 1}]`)))
+	assert.Error(t, output.UnmarshalJSON([]byte(`{`)))
+
+	// No column is created if one of the objects is invalid
+	assert.Error(t, output.UnmarshalJSON([]byte(`[{"other": 1}, {"id": -1}]`)))
+	_, ok := output.cols.Load("other")
+	assert.False(t, ok)
+
+	// Values of another kind than their column are rejected before any write
+	for _, invalid := range []string{
+		`[{"ttl": "x"}]`, `[{"ttl": true}]`, `[{"name": ["a"]}]`, `[{"name": {"a": 1}}]`,
+	} {
+		assert.Error(t, output.UnmarshalJSON([]byte(invalid)), invalid)
+	}
+	assert.Equal(t, 2, output.Count())
+
+	// Explicit nulls are kept on a round trip
+	assert.True(t, output.SetNull(1, "ttl"))
+	encoded, err := json.Marshal(output)
+	assert.NoError(t, err)
+	restored := NewCollection()
+	restored.CreateColumn("id", ForUint64())
+	restored.CreateColumn("ttl", ForInt64())
+	restored.CreateColumn("name", ForString())
+	assert.NoError(t, restored.UnmarshalJSON(encoded))
+	v, ok := Get[any](restored, 1, "ttl")
+	assert.True(t, ok)
+	assert.Nil(t, v)
+}
+
+func TestAllocStrategy(t *testing.T) {
+	insert := func(c *Collection) uint32 {
+		return c.InsertObject(Object{"name": "x"})
+	}
+
+	// Each strategy is exercised on the same sequence of deletions
+	for strategy, expect := range map[AllocStrategy][]uint32{
+		LowestFirst:  {2, 5, 7, 10},
+		FIFO:         {2, 7, 5, 10},
+		HighestFirst: {7, 5, 2, 10},
+	} {
+		c := NewCollection()
+		c.CreateColumn("name", ForString())
+		for i := 0; i < 10; i++ {
+			insert(c)
+		}
+
+		c.DeleteAt(2)
+		c.SetAllocStrategy(strategy)
+		c.DeleteAt(7)
+		c.DeleteAt(5)
+
+		var actual []uint32
+		for i := 0; i < 4; i++ {
+			actual = append(actual, insert(c))
+		}
+		assert.Equal(t, expect, actual, "strategy %d", strategy)
+		assert.Equal(t, 11, c.Count())
+	}
+}
+
+func TestBulkUpsert(t *testing.T) {
+	players := newEmpty(500)
+	players.CreateColumn("id", ForInt64())
+	for i := 0; i < 10; i++ {
+		players.InsertObject(Object{"id": int64(i), "name": fmt.Sprintf("Player %d", i)})
+	}
+
+	inserted, updated := players.BulkUpsert("id", []Object{
+		{"id": int64(1), "name": "Updated 1"},
+		{"id": int64(20), "name": "First 20"},
+		{"id": int64(3), "name": "Updated 3"},
+		{"id": int64(20), "name": "Last 20"},
+		{"id": int64(3), "name": "Last 3"},
+		{"name": "Unkeyed"},
+	})
+	assert.Equal(t, 2, inserted)
+	assert.Equal(t, 2, updated)
+	assert.Equal(t, 12, players.Count())
+
+	// Duplicate keys of the batch are resolved in favour of the last object
+	for id, name := range map[int]string{1: "Updated 1", 3: "Last 3", 20: "Last 20", 5: "Player 5"} {
+		object, ok := players.FetchByKey([]string{"id"}, []interface{}{id})
+		assert.True(t, ok)
+		assert.Equal(t, name, object["name"])
+	}
+
+	// Nothing is written for a key column which does not exist
+	inserted, updated = players.BulkUpsert("xxx", []Object{{"id": int64(30)}})
+	assert.Zero(t, inserted)
+	assert.Zero(t, updated)
+	assert.Equal(t, 12, players.Count())
+}
+
+func TestUpsert(t *testing.T) {
+	col := NewCollection()
+	col.CreateColumn("id", ForInt64())
+	col.CreateColumn("name", ForString())
+	first := col.InsertObject(Object{"id": int64(1), "name": "First"})
+	second := col.InsertObject(Object{"id": int64(1), "name": "Second"})
+	assert.Equal(t, first+1, second)
+
+	// Ties update the lowest index
+	idx := col.Upsert("id", int64(1), Object{"id": int64(1), "name": "Updated"})
+	assert.Equal(t, first, idx)
+	name, _ := Get[string](col, first, "name")
+	assert.Equal(t, "Updated", name)
+	name, _ = Get[string](col, second, "name")
+	assert.Equal(t, "Second", name)
+
+	// No match inserts the object
+	idx = col.Upsert("id", int64(2), Object{"id": int64(2), "name": "Inserted"})
+	assert.Equal(t, second+1, idx)
+	assert.Equal(t, 3, col.Count())
+
+	// The primary key is looked up instead
+	keyed := NewCollection()
+	keyed.CreateColumn("key", ForKey())
+	keyed.CreateColumn("value", ForInt())
+	a := keyed.Upsert("key", "a", Object{"key": "a", "value": 1})
+	b := keyed.Upsert("key", "b", Object{"key": "b", "value": 2})
+	assert.Equal(t, a, keyed.Upsert("key", "a", Object{"key": "a", "value": 3}))
+	assert.NotEqual(t, a, b)
+	assert.Equal(t, 2, keyed.Count())
+	value, _ := Get[int](keyed, a, "value")
+	assert.Equal(t, 3, value)
+}
+
+func TestContains(t *testing.T) {
+	players := loadPlayers(500)
+	assert.True(t, players.Contains(0))
+	assert.True(t, players.Contains(499))
+	assert.False(t, players.Contains(500))
+	assert.False(t, players.Contains(math.MaxUint32))
+
+	players.DeleteAt(10)
+	assert.False(t, players.Contains(10))
+	assert.True(t, players.Contains(11))
+}
+
+func TestRangeColumn(t *testing.T) {
+	players := loadPlayers(500)
+	players.DeleteAt(1)
+	players.Clear(2, "balance")
+
+	// Only the objects with a value are visited, in order
+	var sum float64
+	var visited []uint32
+	assert.True(t, players.RangeColumn("balance", func(idx uint32, v interface{}) bool {
+		sum += v.(float64)
+		visited = append(visited, idx)
+		return true
+	}))
+	assert.Len(t, visited, 498)
+	assert.Equal(t, []uint32{0, 3, 4}, visited[:3])
+	expect := 0.0
+	players.Query(func(txn *Txn) error {
+		txn.WithFloat("balance", func(v float64) bool {
+			expect += v
+			return true
+		})
+		return nil
+	})
+	assert.InDelta(t, expect, sum, 0.001)
+
+	// The iteration stops early, and unknown columns are reported
+	count := 0
+	assert.True(t, players.RangeColumn("name", func(idx uint32, v interface{}) bool {
+		count++
+		return count < 10
+	}))
+	assert.Equal(t, 10, count)
+	assert.False(t, players.RangeColumn("xxx", func(uint32, interface{}) bool {
+		return true
+	}))
+}
+
+func TestSetNull(t *testing.T) {
+	col := NewCollection()
+	col.CreateColumn("name", ForString())
+	col.CreateColumn("age", ForInt())
+	idx := col.InsertObject(Object{"name": "Roman", "age": 30})
+	other := col.InsertObject(Object{"name": "Alice"})
+
+	assert.True(t, col.SetNull(idx, "age"))
+	assert.False(t, col.SetNull(idx, "missing"))
+	assert.False(t, col.SetNull(999, "age"))
+
+	// Explicit nulls are present with a nil value, unlike the values never set
+	v, ok := Get[any](col, idx, "age")
+	assert.True(t, ok)
+	assert.Nil(t, v)
+	_, ok = Get[any](col, other, "age")
+	assert.False(t, ok)
+	_, ok = Get[int](col, idx, "age")
+	assert.False(t, ok)
+
+	obj := col.fetch(idx)
+	assert.Equal(t, Object{"name": "Roman", "age": nil}, obj)
+	obj = col.fetch(other)
+	assert.Equal(t, Object{"name": "Alice"}, obj)
+
+	// Setting a value overrides the null
+	col.QueryAt(idx, func(r Row) error {
+		r.SetInt("age", 31)
+		return nil
+	})
+	obj = col.fetch(idx)
+	assert.Equal(t, Object{"name": "Roman", "age": 31}, obj)
+
+	// Clearing the column removes the null
+	assert.True(t, col.SetNull(idx, "age"))
+	assert.True(t, col.Clear(idx, "age"))
+	obj = col.fetch(idx)
+	assert.Equal(t, Object{"name": "Roman"}, obj)
+
+	// Nulls are kept in a clone, but not by a new object at the same index
+	assert.True(t, col.SetNull(other, "name"))
+	clone, err := col.Clone()
+	assert.NoError(t, err)
+	obj = clone.fetch(other)
+	assert.Equal(t, Object{"name": nil}, obj)
+
+	assert.True(t, col.DeleteAt(other))
+	reused := col.InsertObject(Object{"age": 1})
+	assert.Equal(t, other, reused)
+	obj = col.fetch(reused)
+	assert.Equal(t, Object{"age": 1}, obj)
+}
+
+func TestSetNullWriteBack(t *testing.T) {
+	col := NewCollection()
+	col.CreateColumn("name", ForString())
+	col.CreateColumn("age", ForInt())
+	idx := col.InsertObject(Object{"name": "Roman", "age": 30})
+	assert.True(t, col.SetNull(idx, "age"))
+
+	// Objects read with a null can be written back, keeping the null
+	var objects []Object
+	col.Query(func(txn *Txn) error {
+		objects = txn.Select()
+		return nil
+	})
+	assert.Equal(t, []Object{{"name": "Roman", "age": nil}}, objects)
+	other := col.InsertObject(objects[0])
+	assert.Equal(t, Object{"name": "Roman", "age": nil}, col.fetch(other))
+
+	// Nulls are read into structs as the zero value
+	type player struct {
+		Name string `column:"name"`
+		Age  int    `column:"age"`
+	}
+
+	var out []player
+	assert.NoError(t, col.FetchIntoStructs([]uint32{idx, other}, &out))
+	assert.Equal(t, []player{{Name: "Roman"}, {Name: "Roman"}}, out)
+}
+
+func TestSetNullReplicated(t *testing.T) {
+	writer := make(commit.Channel, 10)
+	primary := NewCollection(Options{Writer: &writer})
+	primary.CreateColumn("name", ForString())
+	idx := primary.InsertObject(Object{"name": "Roman"})
+	assert.True(t, primary.SetNull(idx, "name"))
+
+	// Nulls are restored from a snapshot into a collection with the same schema
+	buffer := bytes.NewBuffer(nil)
+	assert.NoError(t, primary.Snapshot(buffer))
+	restored := NewCollection()
+	restored.CreateColumn("name", ForString())
+	assert.NoError(t, restored.Restore(buffer))
+	assert.Equal(t, Object{"name": nil}, restored.fetch(idx))
+
+	// Nulls are replicated by replaying the commits
+	close(writer)
+	replica := NewCollection()
+	replica.CreateColumn("name", ForString())
+	for change := range writer {
+		assert.NoError(t, replica.Replay(change))
+	}
+	assert.Equal(t, Object{"name": nil}, replica.fetch(idx))
+}
+
+func TestRange(t *testing.T) {
+	players := loadPlayers(500)
+	players.DeleteAt(0)
+	players.DeleteAt(100)
+
+	var indices []uint32
+	players.Range(func(idx uint32, obj Object) bool {
+		assert.Equal(t, players.fetch(idx), obj)
+		indices = append(indices, idx)
+		return true
+	})
+	assert.Equal(t, players.Keys(), indices)
+
+	// Stops as soon as the function returns false
+	count := 0
+	players.Range(func(idx uint32, obj Object) bool {
+		count++
+		return count < 10
+	})
+	assert.Equal(t, 10, count)
+}
+
 // --------------------------- Mocks & Fixtures ----------------------------
 
 // loadPlayers loads a list of players from the fixture
@@ -9,6 +9,7 @@ import (
 	"fmt"
 	"reflect"
 	"sync"
+	"sync/atomic"
 
 	"github.com/kelindar/bitmap"
 	"github.com/kelindar/column/commit"
@@ -64,6 +65,17 @@ type Textual interface {
 	FilterString(uint32, bitmap.Bitmap, func(v string) bool)
 }
 
+// compactable represents a column which can reclaim its internal overhead without changing
+// its values. The caller must hold exclusive access to the column.
+type compactable interface {
+	compact()
+}
+
+// bounded represents a column which maintains the extremes of its values.
+type bounded interface {
+	bounds(chunks int, latch func(chunk commit.Chunk, exclusive bool, fn func())) (min, max interface{}, ok bool)
+}
+
 // --------------------------- Constructors ----------------------------
 
 // Various column constructor functions for a specific types.
@@ -116,14 +128,193 @@ func ForKind(kind reflect.Kind) (Column, error) {
 	}
 }
 
+// columnLike creates a new, empty column of the same type as the specified one. Computed
+// columns and primary keys can not be duplicated, and an error is returned for them.
+func columnLike(column Column) (Column, error) {
+	switch column.(type) {
+	case *float32Column:
+		return makeFloat32s(), nil
+	case *float64Column:
+		return makeFloat64s(), nil
+	case *intColumn:
+		return makeInts(), nil
+	case *int16Column:
+		return makeInt16s(), nil
+	case *int32Column:
+		return makeInt32s(), nil
+	case *int64Column:
+		return makeInt64s(), nil
+	case *uintColumn:
+		return makeUints(), nil
+	case *uint16Column:
+		return makeUint16s(), nil
+	case *uint32Column:
+		return makeUint32s(), nil
+	case *uint64Column:
+		return makeUint64s(), nil
+	case *columnBool:
+		return makeBools(), nil
+	case *columnString:
+		return makeStrings(), nil
+	case *columnEnum:
+		return makeEnum(), nil
+	default:
+		return nil, fmt.Errorf("column: unable to create a column of type %T", column)
+	}
+}
+
+// decodeValue decodes the value of the current record of the reader, according to the
+// type of the column. It returns false if the column type does not store values.
+func decodeValue(column Column, r *commit.Reader) (interface{}, bool) {
+	if _, isBool := column.(*columnBool); !isBool && r.Type == commit.Delete {
+		return nil, true // Deletions do not carry a value, except for booleans
+	}
+
+	switch column.(type) {
+	case *float32Column:
+		return r.Float32(), true
+	case *float64Column:
+		return r.Float64(), true
+	case *intColumn:
+		return r.Int(), true
+	case *int16Column:
+		return r.Int16(), true
+	case *int32Column:
+		return r.Int32(), true
+	case *int64Column:
+		return r.Int64(), true
+	case *uintColumn:
+		return r.Uint(), true
+	case *uint16Column:
+		return r.Uint16(), true
+	case *uint32Column:
+		return r.Uint32(), true
+	case *uint64Column:
+		return r.Uint64(), true
+	case *columnBool:
+		return r.Bool(), true
+	case *columnString, *columnEnum, *columnKey:
+		return string(r.BytesRef()), true
+	default:
+		return nil, false
+	}
+}
+
+// --------------------------- Memory ----------------------------
+
+const (
+	stringSize    = 16     // The size of a string header
+	dictEntrySize = 16 + 8 // The size of a string header and a hash table entry
+	keyEntrySize  = 16 + 8 // The size of a string header and an offset in a map
+)
+
+// MemBreakdown represents an approximate breakdown of the memory used by the columns,
+// in bytes, grouped by the way the data is represented.
+type MemBreakdown struct {
+	Dense        int // Values stored in dense slices
+	Sparse       int // Values stored in maps
+	Bitmaps      int // Fill lists, boolean values and bitmap indexes
+	Dictionaries int // Dictionaries of distinct values
+}
+
+// measure adds the approximate memory used by the fixed-size part of the column into the
+// breakdown. The variable-size strings are measured separately, see measureStrings.
+func measure(column Column, dst *MemBreakdown) {
+	switch c := column.(type) {
+	case *float32Column:
+		dst.Dense += 4 * cap(c.data)
+		dst.Bitmaps += 8 * cap(c.fill)
+	case *float64Column:
+		dst.Dense += 8 * cap(c.data)
+		dst.Bitmaps += 8 * cap(c.fill)
+	case *intColumn:
+		dst.Dense += 8 * cap(c.data)
+		dst.Bitmaps += 8 * cap(c.fill)
+	case *int16Column:
+		dst.Dense += 2 * cap(c.data)
+		dst.Bitmaps += 8 * cap(c.fill)
+	case *int32Column:
+		dst.Dense += 4 * cap(c.data)
+		dst.Bitmaps += 8 * cap(c.fill)
+	case *int64Column:
+		dst.Dense += 8 * cap(c.data)
+		dst.Bitmaps += 8 * cap(c.fill)
+	case *uintColumn:
+		dst.Dense += 8 * cap(c.data)
+		dst.Bitmaps += 8 * cap(c.fill)
+	case *uint16Column:
+		dst.Dense += 2 * cap(c.data)
+		dst.Bitmaps += 8 * cap(c.fill)
+	case *uint32Column:
+		dst.Dense += 4 * cap(c.data)
+		dst.Bitmaps += 8 * cap(c.fill)
+	case *uint64Column:
+		dst.Dense += 8 * cap(c.data)
+		dst.Bitmaps += 8 * cap(c.fill)
+	case *columnBool:
+		dst.Bitmaps += 8 * cap(c.data)
+	case *columnIndex:
+		dst.Bitmaps += 8 * cap(c.fill)
+	case *columnValues:
+		c.lock.RLock()
+		dst.Dense += 4 * cap(c.locs)
+		dst.Bitmaps += 8 * cap(c.fill)
+		for _, v := range c.data {
+			dst.Bitmaps += 8 * cap(v)
+		}
+		for _, v := range c.keys {
+			dst.Dictionaries += len(v) + dictEntrySize
+		}
+		c.lock.RUnlock()
+	case *columnEnum:
+		dst.Dense += 4 * cap(c.locs)
+		dst.Bitmaps += 8 * cap(c.fill)
+		dst.Dictionaries += int(atomic.LoadInt64(&c.size))
+	case *columnString:
+		dst.Dense += stringSize * cap(c.data)
+		dst.Bitmaps += 8 * cap(c.fill)
+	case *columnKey:
+		dst.Dense += stringSize * cap(c.data)
+		dst.Bitmaps += 8 * cap(c.fill)
+		c.lock.RLock()
+		dst.Sparse += keyEntrySize * len(c.seek)
+		c.lock.RUnlock()
+	}
+}
+
+// measureStrings adds the size of the strings present in the chunk into the breakdown. The
+// caller must hold the read latch of the chunk.
+func measureStrings(column Column, chunk commit.Chunk, dst *MemBreakdown) {
+	var strings *columnString
+	switch c := column.(type) {
+	case *columnString:
+		strings = c
+	case *columnKey:
+		strings = &c.columnString
+	default:
+		return
+	}
+
+	chunk.Range(strings.fill, func(idx uint32) {
+		if idx < uint32(len(strings.data)) {
+			dst.Dense += len(strings.data[idx])
+		}
+	})
+}
+
 // --------------------------- Column ----------------------------
 
 // column represents a column wrapper that synchronizes operations
 type column struct {
 	Column
-	lock sync.RWMutex // The lock to protect the entire column
-	kind columnType   // The type of the colum
-	name string       // The name of the column
+	lock  sync.RWMutex // The lock to protect the entire column
+	kind  columnType   // The type of the colum
+	name  string       // The name of the column
+	stats *columnStats // The access statistics of the column, if enabled
+	hll   atomic.Value // The cardinality sketch, built on demand
+	null  atomic.Value // The column of the explicit nulls, created on demand
+
+	nullOf string // The column whose explicit nulls are marked, if any
 }
 
 // columnFor creates a synchronized column for a column implementation
@@ -137,8 +328,12 @@ func columnFor(name string, v Column) *column {
 
 // IsIndex returns whether the column is an index
 func (c *column) IsIndex() bool {
-	_, ok := c.Column.(*columnIndex)
-	return ok
+	switch c.Column.(type) {
+	case *columnIndex, *columnValues:
+		return true
+	default:
+		return false
+	}
 }
 
 // IsNumeric checks whether a column type supports certain numerical operations.
@@ -166,6 +361,15 @@ func (c *column) Apply(r *commit.Reader) {
 
 	r.Rewind()
 	c.Column.Apply(r)
+
+	r.Rewind()
+	c.applyNulls(r)
+
+	// Additions were swapped with their resulting values, so the sketch sees the final values
+	if s, ok := c.hll.Load().(*sketch); ok {
+		r.Rewind()
+		s.apply(r)
+	}
 }
 
 // Snapshot takes a snapshot of a column, skipping indexes
@@ -185,6 +389,37 @@ func (c *column) Value(idx uint32) (v interface{}, ok bool) {
 	return
 }
 
+// --------------------------- Statistics ----------------------------
+
+// columnStats represents the access counters of a column. The counters are updated
+// atomically, and a nil value disables them so that accessors only pay for a nil check.
+type columnStats struct {
+	reads  uint64 // The number of values read through accessors
+	writes uint64 // The number of values written through accessors
+}
+
+// read increments the read counter
+func (s *columnStats) read() {
+	if s != nil {
+		atomic.AddUint64(&s.reads, 1)
+	}
+}
+
+// write increments the write counter
+func (s *columnStats) write() {
+	if s != nil {
+		atomic.AddUint64(&s.writes, 1)
+	}
+}
+
+// Load atomically loads the read and write counters
+func (s *columnStats) Load() (reads, writes uint64) {
+	if s == nil {
+		return 0, 0
+	}
+	return atomic.LoadUint64(&s.reads), atomic.LoadUint64(&s.writes)
+}
+
 // --------------------------- booleans ----------------------------
 
 // columnBool represents a boolean column
@@ -242,10 +477,12 @@ func (c *columnBool) Snapshot(chunk commit.Chunk, dst *commit.Buffer) {
 type boolReader struct {
 	cursor *uint32
 	reader Column
+	stats  *columnStats
 }
 
 // Get loads the value at the current transaction cursor
 func (s boolReader) Get() bool {
+	s.stats.read()
 	return s.reader.Contains(*s.cursor)
 }
 
@@ -259,6 +496,7 @@ func boolReaderFor(txn *Txn, columnName string) boolReader {
 	return boolReader{
 		cursor: &txn.cursor,
 		reader: column.Column,
+		stats:  column.stats,
 	}
 }
 
@@ -270,6 +508,7 @@ type boolWriter struct {
 
 // Set sets the value at the current transaction cursor
 func (s boolWriter) Set(value bool) {
+	s.stats.write()
 	s.writer.PutBool(*s.cursor, value)
 }
 
@@ -287,10 +526,12 @@ func (txn *Txn) Bool(columnName string) boolWriter {
 type anyReader struct {
 	cursor *uint32
 	reader Column
+	stats  *columnStats
 }
 
 // Get loads the value at the current transaction cursor
 func (s anyReader) Get() (interface{}, bool) {
+	s.stats.read()
 	return s.reader.Value(*s.cursor)
 }
 
@@ -304,6 +545,7 @@ func anyReaderFor(txn *Txn, columnName string) anyReader {
 	return anyReader{
 		cursor: &txn.cursor,
 		reader: column.Column,
+		stats:  column.stats,
 	}
 }
 
@@ -315,6 +557,7 @@ type anyWriter struct {
 
 // Set sets the value at the current transaction cursor
 func (s anyWriter) Set(value interface{}) {
+	s.stats.write()
 	s.writer.PutAny(commit.Put, *s.cursor, value)
 }
 
@@ -350,3 +593,15 @@ func resize(capacity int, v uint32) int {
 	}
 	return capacity
 }
+
+// clip returns a copy of the slice without any spare capacity, or the slice itself if it
+// does not have any
+func clip[T any](v []T) []T {
+	if len(v) == cap(v) {
+		return v
+	}
+
+	out := make([]T, len(v))
+	copy(out, v)
+	return out
+}
@@ -17,8 +17,17 @@ type number = generic.Number
 
 // numberColumn represents a generic column
 type numberColumn struct {
-	fill bitmap.Bitmap // The fill-list
-	data []number      // The actual values
+	fill     bitmap.Bitmap  // The fill-list
+	data     []number       // The actual values
+	extremes []numberBounds // The extremes of the values of each chunk
+}
+
+// numberBounds represents the extremes of the values of a chunk
+type numberBounds struct {
+	min   number // The minimum value, if known
+	max   number // The maximum value, if known
+	known bool   // Whether the extremes are known
+	stale bool   // Whether the extremes need to be recomputed
 }
 
 // makeNumbers creates a new vector for Numbers
@@ -31,6 +40,10 @@ func makeNumbers() Column {
 
 // Grow grows the size of the column until we have enough to store
 func (c *numberColumn) Grow(idx uint32) {
+	for len(c.extremes) <= int(commit.ChunkAt(idx)) {
+		c.extremes = append(c.extremes, numberBounds{})
+	}
+
 	if idx < uint32(len(c.data)) {
 		return
 	}
@@ -47,28 +60,124 @@ func (c *numberColumn) Grow(idx uint32) {
 	c.data = clone
 }
 
+// compact reclaims the spare capacity of the column
+func (c *numberColumn) compact() {
+	c.fill = clip(c.fill)
+	c.data = clip(c.data)
+}
+
 // Apply applies a set of operations to the column.
 func (c *numberColumn) Apply(r *commit.Reader) {
 	for r.Next() {
+		bounds := &c.extremes[commit.ChunkAt(r.Index())]
 		switch r.Type {
 		case commit.Put:
+			value := r.Number()
+			c.bound(bounds, r.Index(), value)
 			c.fill[r.Offset>>6] |= 1 << (r.Offset & 0x3f)
-			c.data[r.Offset] = r.Number()
+			c.data[r.Offset] = value
 
 		// If this is an atomic increment/decrement, we need to change the operation to
 		// the final value, since after this update an index needs to be recalculated.
 		case commit.Add:
-			c.fill[r.Offset>>6] |= 1 << (r.Offset & 0x3f)
 			value := c.data[r.Offset] + r.Number()
+			c.bound(bounds, r.Index(), value)
+			c.fill[r.Offset>>6] |= 1 << (r.Offset & 0x3f)
 			c.data[r.Offset] = value
 			r.SwapNumber(value)
 
 		case commit.Delete:
+			c.unbound(bounds, r.Index())
 			c.fill.Remove(r.Index())
 		}
 	}
 }
 
+// bound updates the extremes of a chunk with a value about to be stored at the index. If the
+// value replaces one of the extremes with a less extreme one, the extremes become stale.
+func (c *numberColumn) bound(b *numberBounds, idx uint32, value number) {
+	if b.stale {
+		return
+	}
+
+	if c.fill.Contains(idx) {
+		if prev := c.data[idx]; (prev == b.min && value > prev) || (prev == b.max && value < prev) {
+			b.stale = true
+			return
+		}
+	}
+
+	switch {
+	case !b.known:
+		b.min, b.max, b.known = value, value, true
+	case value < b.min:
+		b.min = value
+	case value > b.max:
+		b.max = value
+	}
+}
+
+// unbound marks the extremes of a chunk as stale if the value about to be removed is one of them
+func (c *numberColumn) unbound(b *numberBounds, idx uint32) {
+	if !b.stale && c.fill.Contains(idx) {
+		prev := c.data[idx]
+		b.stale = prev == b.min || prev == b.max
+	}
+}
+
+// bounds returns the minimum and maximum values of the column, combining the extremes of
+// each chunk. The latch function must hold the latch of the chunk while calling back, for
+// writing if it is exclusive, since only the stale chunks are recomputed.
+func (c *numberColumn) bounds(chunks int, latch func(chunk commit.Chunk, exclusive bool, fn func())) (min, max interface{}, ok bool) {
+	var lo, hi number
+	for chunk := commit.Chunk(0); int(chunk) < chunks; chunk++ {
+		var b numberBounds
+		latch(chunk, false, func() {
+			if int(chunk) < len(c.extremes) {
+				b = c.extremes[chunk]
+			}
+		})
+
+		if b.stale {
+			latch(chunk, true, func() {
+				b = c.rebound(chunk)
+			})
+		}
+
+		switch {
+		case !b.known:
+			continue
+		case !ok:
+			lo, hi, ok = b.min, b.max, true
+		default:
+			if b.min < lo {
+				lo = b.min
+			}
+			if b.max > hi {
+				hi = b.max
+			}
+		}
+	}
+
+	if !ok {
+		return nil, nil, false
+	}
+	return lo, hi, true
+}
+
+// rebound recomputes the extremes of a chunk, if they are still stale. The caller must hold
+// the write latch of the chunk.
+func (c *numberColumn) rebound(chunk commit.Chunk) numberBounds {
+	b := &c.extremes[chunk]
+	if b.stale {
+		b.known, b.stale = false, false
+		chunk.Range(c.fill, func(idx uint32) {
+			c.bound(b, idx, c.data[idx])
+		})
+	}
+	return *b
+}
+
 // Contains checks whether the column has a value at a specified index.
 func (c *numberColumn) Contains(idx uint32) bool {
 	return c.fill.Contains(idx)
@@ -147,6 +256,18 @@ func (c *numberColumn) FilterUint64(offset uint32, index bitmap.Bitmap, predicat
 	})
 }
 
+// sum computes the sum of the values present at the indices of the bitmap, which
+// starts at the specified offset.
+func (c *numberColumn) sum(offset uint32, index bitmap.Bitmap) (sum number) {
+	index.Range(func(idx uint32) {
+		idx = offset + idx
+		if idx < uint32(len(c.data)) && c.fill.Contains(idx) {
+			sum += c.data[idx]
+		}
+	})
+	return
+}
+
 // Snapshot writes the entire column into the specified destination buffer
 func (c *numberColumn) Snapshot(chunk commit.Chunk, dst *commit.Buffer) {
 	chunk.Range(c.fill, func(idx uint32) {
@@ -158,10 +279,12 @@ func (c *numberColumn) Snapshot(chunk commit.Chunk, dst *commit.Buffer) {
 type numberReader struct {
 	cursor *uint32
 	reader *numberColumn
+	stats  *columnStats
 }
 
 // Get loads the value at the current transaction cursor
 func (s numberReader) Get() (number, bool) {
+	s.stats.read()
 	return s.reader.load(*s.cursor)
 }
 
@@ -180,6 +303,7 @@ func numberReaderFor(txn *Txn, columnName string) numberReader {
 	return numberReader{
 		cursor: &txn.cursor,
 		reader: reader,
+		stats:  column.stats,
 	}
 }
 
@@ -191,11 +315,13 @@ type numberWriter struct {
 
 // Set sets the value at the current transaction cursor
 func (s numberWriter) Set(value number) {
+	s.stats.write()
 	s.writer.PutNumber(*s.cursor, value)
 }
 
 // Add atomically adds a delta to the value at the current transaction cursor
 func (s numberWriter) Add(delta number) {
+	s.stats.write()
 	s.writer.AddNumber(*s.cursor, delta)
 }
 
@@ -206,3 +332,14 @@ func (txn *Txn) Number(columnName string) numberWriter {
 		writer:       txn.bufferFor(columnName),
 	}
 }
+
+// SumNumber computes the sum of the number column over the objects matching the query,
+// without boxing the values. It panics if the column is not of type number.
+func (txn *Txn) SumNumber(columnName string) (sum number) {
+	column := numberReaderFor(txn, columnName).reader
+	txn.initialize()
+	txn.rangeRead(func(offset uint32, index bitmap.Bitmap) {
+		sum += column.sum(offset, index)
+	})
+	return
+}
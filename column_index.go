@@ -103,6 +103,127 @@ func (c *columnIndex) Snapshot(chunk commit.Chunk, dst *commit.Buffer) {
 	dst.PutBitmap(commit.PutTrue, chunk, c.fill)
 }
 
+// --------------------------- Value Index ----------------------------
+
+// columnValues represents an equality index, which keeps the bitmap of the matching
+// indices for each distinct value of a string column.
+type columnValues struct {
+	lock sync.RWMutex      // Lock to protect the lookup table and the bitmaps
+	fill bitmap.Bitmap     // The fill list for the column
+	name string            // The name of the target column
+	locs []uint32          // The location of the value, for each index
+	seek map[string]uint32 // The value->location table
+	keys []string          // The value, for each location
+	data []bitmap.Bitmap   // The matching indices, for each location
+}
+
+// newValueIndex creates a new equality index column.
+func newValueIndex(indexName, columnName string) *column {
+	return columnFor(indexName, &columnValues{
+		fill: make(bitmap.Bitmap, 0, 4),
+		name: columnName,
+		locs: make([]uint32, 0, 64),
+		seek: make(map[string]uint32, 16),
+	})
+}
+
+// Grow grows the size of the column until we have enough to store
+func (c *columnValues) Grow(idx uint32) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.grow(idx)
+}
+
+// grow grows the locations, the caller must hold the lock
+func (c *columnValues) grow(idx uint32) {
+	c.fill.Grow(idx)
+	if idx < uint32(len(c.locs)) {
+		return
+	}
+
+	clone := make([]uint32, idx+1, resize(cap(c.locs), idx+1))
+	copy(clone, c.locs)
+	c.locs = clone
+}
+
+// Column returns the target name of the column on which this index should apply.
+func (c *columnValues) Column() string {
+	return c.name
+}
+
+// Apply applies a set of operations to the column.
+func (c *columnValues) Apply(r *commit.Reader) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	for r.Next() {
+		switch r.Type {
+		case commit.Put:
+			idx := r.Index()
+			c.grow(idx)
+			c.remove(idx)
+
+			at, ok := c.seek[string(r.BytesRef())]
+			if !ok {
+				at = uint32(len(c.keys))
+				c.keys = append(c.keys, r.String())
+				c.data = append(c.data, nil)
+				c.seek[c.keys[at]] = at
+			}
+
+			c.data[at].Set(idx)
+			c.locs[idx] = at
+			c.fill.Set(idx)
+		case commit.Delete:
+			c.remove(r.Index())
+		}
+	}
+}
+
+// remove removes the index from the bitmap of its current value, the caller must hold the lock
+func (c *columnValues) remove(idx uint32) {
+	if c.fill.Contains(idx) {
+		c.data[c.locs[idx]].Remove(idx)
+		c.fill.Remove(idx)
+	}
+}
+
+// Value retrieves the indexed value at a specified index.
+func (c *columnValues) Value(idx uint32) (v interface{}, ok bool) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	if c.fill.Contains(idx) {
+		v, ok = c.keys[c.locs[idx]], true
+	}
+	return
+}
+
+// Contains checks whether the column has a value at a specified index.
+func (c *columnValues) Contains(idx uint32) bool {
+	return c.fill.Contains(idx)
+}
+
+// Index returns the fill list for the column
+func (c *columnValues) Index() *bitmap.Bitmap {
+	return &c.fill
+}
+
+// Snapshot writes the entire column into the specified destination buffer
+func (c *columnValues) Snapshot(chunk commit.Chunk, dst *commit.Buffer) {
+	dst.PutBitmap(commit.PutTrue, chunk, c.fill)
+}
+
+// AndValue intersects the bitmap with the indices holding the specified value
+func (c *columnValues) AndValue(value string, index *bitmap.Bitmap) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	if at, ok := c.seek[value]; ok {
+		index.And(c.data[at])
+	} else {
+		index.Clear()
+	}
+}
+
 // --------------------------- Key ----------------------------
 
 // columnKey represents the primary key column implementation
@@ -129,7 +250,7 @@ func (c *columnKey) Apply(r *commit.Reader) {
 	for r.Next() {
 		switch r.Type {
 		case commit.Put:
-			value := string(r.Bytes())
+			value := string(r.BytesRef())
 
 			c.fill[r.Offset>>6] |= 1 << (r.Offset & 0x3f)
 			c.data[r.Offset] = value
@@ -146,6 +267,18 @@ func (c *columnKey) Apply(r *commit.Reader) {
 	}
 }
 
+// compact compacts the strings and rebuilds the lookup table, since maps never shrink
+func (c *columnKey) compact() {
+	c.columnString.compact()
+	c.lock.Lock()
+	seek := make(map[string]uint32, len(c.seek))
+	for k, v := range c.seek {
+		seek[k] = v
+	}
+	c.seek = seek
+	c.lock.Unlock()
+}
+
 // OffsetOf returns the offset for a particular value
 func (c *columnKey) OffsetOf(v string) (uint32, bool) {
 	c.lock.RLock()
@@ -159,15 +292,18 @@ type keySlice struct {
 	cursor *uint32
 	writer *commit.Buffer
 	reader *columnKey
+	stats  *columnStats
 }
 
 // Set sets the value at the current transaction index
 func (s keySlice) Set(value string) {
+	s.stats.write()
 	s.writer.PutString(commit.Put, *s.cursor, value)
 }
 
 // Get loads the value at the current transaction index
 func (s keySlice) Get() (string, bool) {
+	s.stats.read()
 	return s.reader.LoadString(*s.cursor)
 }
 
@@ -177,9 +313,11 @@ func (txn *Txn) Key() keySlice {
 		panic(fmt.Errorf("column: primary key column does not exist"))
 	}
 
+	column, _ := txn.columnAt(txn.owner.pk.name)
 	return keySlice{
 		cursor: &txn.cursor,
 		writer: txn.bufferFor(txn.owner.pk.name),
 		reader: txn.owner.pk,
+		stats:  column.stats,
 	}
 }
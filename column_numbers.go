@@ -14,8 +14,17 @@ import (
 
 // float32Column represents a generic column
 type float32Column struct {
-	fill bitmap.Bitmap // The fill-list
-	data []float32     // The actual values
+	fill     bitmap.Bitmap   // The fill-list
+	data     []float32       // The actual values
+	extremes []float32Bounds // The extremes of the values of each chunk
+}
+
+// float32Bounds represents the extremes of the values of a chunk
+type float32Bounds struct {
+	min   float32 // The minimum value, if known
+	max   float32 // The maximum value, if known
+	known bool    // Whether the extremes are known
+	stale bool    // Whether the extremes need to be recomputed
 }
 
 // makeFloat32s creates a new vector for Float32s
@@ -28,6 +37,10 @@ func makeFloat32s() Column {
 
 // Grow grows the size of the column until we have enough to store
 func (c *float32Column) Grow(idx uint32) {
+	for len(c.extremes) <= int(commit.ChunkAt(idx)) {
+		c.extremes = append(c.extremes, float32Bounds{})
+	}
+
 	if idx < uint32(len(c.data)) {
 		return
 	}
@@ -44,28 +57,124 @@ func (c *float32Column) Grow(idx uint32) {
 	c.data = clone
 }
 
+// compact reclaims the spare capacity of the column
+func (c *float32Column) compact() {
+	c.fill = clip(c.fill)
+	c.data = clip(c.data)
+}
+
 // Apply applies a set of operations to the column.
 func (c *float32Column) Apply(r *commit.Reader) {
 	for r.Next() {
+		bounds := &c.extremes[commit.ChunkAt(r.Index())]
 		switch r.Type {
 		case commit.Put:
+			value := r.Float32()
+			c.bound(bounds, r.Index(), value)
 			c.fill[r.Offset>>6] |= 1 << (r.Offset & 0x3f)
-			c.data[r.Offset] = r.Float32()
+			c.data[r.Offset] = value
 
 		// If this is an atomic increment/decrement, we need to change the operation to
 		// the final value, since after this update an index needs to be recalculated.
 		case commit.Add:
-			c.fill[r.Offset>>6] |= 1 << (r.Offset & 0x3f)
 			value := c.data[r.Offset] + r.Float32()
+			c.bound(bounds, r.Index(), value)
+			c.fill[r.Offset>>6] |= 1 << (r.Offset & 0x3f)
 			c.data[r.Offset] = value
 			r.SwapFloat32(value)
 
 		case commit.Delete:
+			c.unbound(bounds, r.Index())
 			c.fill.Remove(r.Index())
 		}
 	}
 }
 
+// bound updates the extremes of a chunk with a value about to be stored at the index. If the
+// value replaces one of the extremes with a less extreme one, the extremes become stale.
+func (c *float32Column) bound(b *float32Bounds, idx uint32, value float32) {
+	if b.stale {
+		return
+	}
+
+	if c.fill.Contains(idx) {
+		if prev := c.data[idx]; (prev == b.min && value > prev) || (prev == b.max && value < prev) {
+			b.stale = true
+			return
+		}
+	}
+
+	switch {
+	case !b.known:
+		b.min, b.max, b.known = value, value, true
+	case value < b.min:
+		b.min = value
+	case value > b.max:
+		b.max = value
+	}
+}
+
+// unbound marks the extremes of a chunk as stale if the value about to be removed is one of them
+func (c *float32Column) unbound(b *float32Bounds, idx uint32) {
+	if !b.stale && c.fill.Contains(idx) {
+		prev := c.data[idx]
+		b.stale = prev == b.min || prev == b.max
+	}
+}
+
+// bounds returns the minimum and maximum values of the column, combining the extremes of
+// each chunk. The latch function must hold the latch of the chunk while calling back, for
+// writing if it is exclusive, since only the stale chunks are recomputed.
+func (c *float32Column) bounds(chunks int, latch func(chunk commit.Chunk, exclusive bool, fn func())) (min, max interface{}, ok bool) {
+	var lo, hi float32
+	for chunk := commit.Chunk(0); int(chunk) < chunks; chunk++ {
+		var b float32Bounds
+		latch(chunk, false, func() {
+			if int(chunk) < len(c.extremes) {
+				b = c.extremes[chunk]
+			}
+		})
+
+		if b.stale {
+			latch(chunk, true, func() {
+				b = c.rebound(chunk)
+			})
+		}
+
+		switch {
+		case !b.known:
+			continue
+		case !ok:
+			lo, hi, ok = b.min, b.max, true
+		default:
+			if b.min < lo {
+				lo = b.min
+			}
+			if b.max > hi {
+				hi = b.max
+			}
+		}
+	}
+
+	if !ok {
+		return nil, nil, false
+	}
+	return lo, hi, true
+}
+
+// rebound recomputes the extremes of a chunk, if they are still stale. The caller must hold
+// the write latch of the chunk.
+func (c *float32Column) rebound(chunk commit.Chunk) float32Bounds {
+	b := &c.extremes[chunk]
+	if b.stale {
+		b.known, b.stale = false, false
+		chunk.Range(c.fill, func(idx uint32) {
+			c.bound(b, idx, c.data[idx])
+		})
+	}
+	return *b
+}
+
 // Contains checks whether the column has a value at a specified index.
 func (c *float32Column) Contains(idx uint32) bool {
 	return c.fill.Contains(idx)
@@ -144,6 +253,18 @@ func (c *float32Column) FilterUint64(offset uint32, index bitmap.Bitmap, predica
 	})
 }
 
+// sum computes the sum of the values present at the indices of the bitmap, which
+// starts at the specified offset.
+func (c *float32Column) sum(offset uint32, index bitmap.Bitmap) (sum float32) {
+	index.Range(func(idx uint32) {
+		idx = offset + idx
+		if idx < uint32(len(c.data)) && c.fill.Contains(idx) {
+			sum += c.data[idx]
+		}
+	})
+	return
+}
+
 // Snapshot writes the entire column into the specified destination buffer
 func (c *float32Column) Snapshot(chunk commit.Chunk, dst *commit.Buffer) {
 	chunk.Range(c.fill, func(idx uint32) {
@@ -155,10 +276,12 @@ func (c *float32Column) Snapshot(chunk commit.Chunk, dst *commit.Buffer) {
 type float32Reader struct {
 	cursor *uint32
 	reader *float32Column
+	stats  *columnStats
 }
 
 // Get loads the value at the current transaction cursor
 func (s float32Reader) Get() (float32, bool) {
+	s.stats.read()
 	return s.reader.load(*s.cursor)
 }
 
@@ -177,6 +300,7 @@ func float32ReaderFor(txn *Txn, columnName string) float32Reader {
 	return float32Reader{
 		cursor: &txn.cursor,
 		reader: reader,
+		stats:  column.stats,
 	}
 }
 
@@ -188,11 +312,13 @@ type float32Writer struct {
 
 // Set sets the value at the current transaction cursor
 func (s float32Writer) Set(value float32) {
+	s.stats.write()
 	s.writer.PutFloat32(*s.cursor, value)
 }
 
 // Add atomically adds a delta to the value at the current transaction cursor
 func (s float32Writer) Add(delta float32) {
+	s.stats.write()
 	s.writer.AddFloat32(*s.cursor, delta)
 }
 
@@ -204,12 +330,32 @@ func (txn *Txn) Float32(columnName string) float32Writer {
 	}
 }
 
+// SumFloat32 computes the sum of the float32 column over the objects matching the query,
+// without boxing the values. It panics if the column is not of type float32.
+func (txn *Txn) SumFloat32(columnName string) (sum float32) {
+	column := float32ReaderFor(txn, columnName).reader
+	txn.initialize()
+	txn.rangeRead(func(offset uint32, index bitmap.Bitmap) {
+		sum += column.sum(offset, index)
+	})
+	return
+}
+
 // --------------------------- Float64s ----------------------------
 
 // float64Column represents a generic column
 type float64Column struct {
-	fill bitmap.Bitmap // The fill-list
-	data []float64     // The actual values
+	fill     bitmap.Bitmap   // The fill-list
+	data     []float64       // The actual values
+	extremes []float64Bounds // The extremes of the values of each chunk
+}
+
+// float64Bounds represents the extremes of the values of a chunk
+type float64Bounds struct {
+	min   float64 // The minimum value, if known
+	max   float64 // The maximum value, if known
+	known bool    // Whether the extremes are known
+	stale bool    // Whether the extremes need to be recomputed
 }
 
 // makeFloat64s creates a new vector for Float64s
@@ -222,6 +368,10 @@ func makeFloat64s() Column {
 
 // Grow grows the size of the column until we have enough to store
 func (c *float64Column) Grow(idx uint32) {
+	for len(c.extremes) <= int(commit.ChunkAt(idx)) {
+		c.extremes = append(c.extremes, float64Bounds{})
+	}
+
 	if idx < uint32(len(c.data)) {
 		return
 	}
@@ -238,28 +388,124 @@ func (c *float64Column) Grow(idx uint32) {
 	c.data = clone
 }
 
+// compact reclaims the spare capacity of the column
+func (c *float64Column) compact() {
+	c.fill = clip(c.fill)
+	c.data = clip(c.data)
+}
+
 // Apply applies a set of operations to the column.
 func (c *float64Column) Apply(r *commit.Reader) {
 	for r.Next() {
+		bounds := &c.extremes[commit.ChunkAt(r.Index())]
 		switch r.Type {
 		case commit.Put:
+			value := r.Float64()
+			c.bound(bounds, r.Index(), value)
 			c.fill[r.Offset>>6] |= 1 << (r.Offset & 0x3f)
-			c.data[r.Offset] = r.Float64()
+			c.data[r.Offset] = value
 
 		// If this is an atomic increment/decrement, we need to change the operation to
 		// the final value, since after this update an index needs to be recalculated.
 		case commit.Add:
-			c.fill[r.Offset>>6] |= 1 << (r.Offset & 0x3f)
 			value := c.data[r.Offset] + r.Float64()
+			c.bound(bounds, r.Index(), value)
+			c.fill[r.Offset>>6] |= 1 << (r.Offset & 0x3f)
 			c.data[r.Offset] = value
 			r.SwapFloat64(value)
 
 		case commit.Delete:
+			c.unbound(bounds, r.Index())
 			c.fill.Remove(r.Index())
 		}
 	}
 }
 
+// bound updates the extremes of a chunk with a value about to be stored at the index. If the
+// value replaces one of the extremes with a less extreme one, the extremes become stale.
+func (c *float64Column) bound(b *float64Bounds, idx uint32, value float64) {
+	if b.stale {
+		return
+	}
+
+	if c.fill.Contains(idx) {
+		if prev := c.data[idx]; (prev == b.min && value > prev) || (prev == b.max && value < prev) {
+			b.stale = true
+			return
+		}
+	}
+
+	switch {
+	case !b.known:
+		b.min, b.max, b.known = value, value, true
+	case value < b.min:
+		b.min = value
+	case value > b.max:
+		b.max = value
+	}
+}
+
+// unbound marks the extremes of a chunk as stale if the value about to be removed is one of them
+func (c *float64Column) unbound(b *float64Bounds, idx uint32) {
+	if !b.stale && c.fill.Contains(idx) {
+		prev := c.data[idx]
+		b.stale = prev == b.min || prev == b.max
+	}
+}
+
+// bounds returns the minimum and maximum values of the column, combining the extremes of
+// each chunk. The latch function must hold the latch of the chunk while calling back, for
+// writing if it is exclusive, since only the stale chunks are recomputed.
+func (c *float64Column) bounds(chunks int, latch func(chunk commit.Chunk, exclusive bool, fn func())) (min, max interface{}, ok bool) {
+	var lo, hi float64
+	for chunk := commit.Chunk(0); int(chunk) < chunks; chunk++ {
+		var b float64Bounds
+		latch(chunk, false, func() {
+			if int(chunk) < len(c.extremes) {
+				b = c.extremes[chunk]
+			}
+		})
+
+		if b.stale {
+			latch(chunk, true, func() {
+				b = c.rebound(chunk)
+			})
+		}
+
+		switch {
+		case !b.known:
+			continue
+		case !ok:
+			lo, hi, ok = b.min, b.max, true
+		default:
+			if b.min < lo {
+				lo = b.min
+			}
+			if b.max > hi {
+				hi = b.max
+			}
+		}
+	}
+
+	if !ok {
+		return nil, nil, false
+	}
+	return lo, hi, true
+}
+
+// rebound recomputes the extremes of a chunk, if they are still stale. The caller must hold
+// the write latch of the chunk.
+func (c *float64Column) rebound(chunk commit.Chunk) float64Bounds {
+	b := &c.extremes[chunk]
+	if b.stale {
+		b.known, b.stale = false, false
+		chunk.Range(c.fill, func(idx uint32) {
+			c.bound(b, idx, c.data[idx])
+		})
+	}
+	return *b
+}
+
 // Contains checks whether the column has a value at a specified index.
 func (c *float64Column) Contains(idx uint32) bool {
 	return c.fill.Contains(idx)
@@ -338,6 +584,18 @@ func (c *float64Column) FilterUint64(offset uint32, index bitmap.Bitmap, predica
 	})
 }
 
+// sum computes the sum of the values present at the indices of the bitmap, which
+// starts at the specified offset.
+func (c *float64Column) sum(offset uint32, index bitmap.Bitmap) (sum float64) {
+	index.Range(func(idx uint32) {
+		idx = offset + idx
+		if idx < uint32(len(c.data)) && c.fill.Contains(idx) {
+			sum += c.data[idx]
+		}
+	})
+	return
+}
+
 // Snapshot writes the entire column into the specified destination buffer
 func (c *float64Column) Snapshot(chunk commit.Chunk, dst *commit.Buffer) {
 	chunk.Range(c.fill, func(idx uint32) {
@@ -349,10 +607,12 @@ func (c *float64Column) Snapshot(chunk commit.Chunk, dst *commit.Buffer) {
 type float64Reader struct {
 	cursor *uint32
 	reader *float64Column
+	stats  *columnStats
 }
 
 // Get loads the value at the current transaction cursor
 func (s float64Reader) Get() (float64, bool) {
+	s.stats.read()
 	return s.reader.load(*s.cursor)
 }
 
@@ -371,6 +631,7 @@ func float64ReaderFor(txn *Txn, columnName string) float64Reader {
 	return float64Reader{
 		cursor: &txn.cursor,
 		reader: reader,
+		stats:  column.stats,
 	}
 }
 
@@ -382,11 +643,13 @@ type float64Writer struct {
 
 // Set sets the value at the current transaction cursor
 func (s float64Writer) Set(value float64) {
+	s.stats.write()
 	s.writer.PutFloat64(*s.cursor, value)
 }
 
 // Add atomically adds a delta to the value at the current transaction cursor
 func (s float64Writer) Add(delta float64) {
+	s.stats.write()
 	s.writer.AddFloat64(*s.cursor, delta)
 }
 
@@ -398,12 +661,32 @@ func (txn *Txn) Float64(columnName string) float64Writer {
 	}
 }
 
+// SumFloat64 computes the sum of the float64 column over the objects matching the query,
+// without boxing the values. It panics if the column is not of type float64.
+func (txn *Txn) SumFloat64(columnName string) (sum float64) {
+	column := float64ReaderFor(txn, columnName).reader
+	txn.initialize()
+	txn.rangeRead(func(offset uint32, index bitmap.Bitmap) {
+		sum += column.sum(offset, index)
+	})
+	return
+}
+
 // --------------------------- Ints ----------------------------
 
 // intColumn represents a generic column
 type intColumn struct {
-	fill bitmap.Bitmap // The fill-list
-	data []int         // The actual values
+	fill     bitmap.Bitmap // The fill-list
+	data     []int         // The actual values
+	extremes []intBounds   // The extremes of the values of each chunk
+}
+
+// intBounds represents the extremes of the values of a chunk
+type intBounds struct {
+	min   int  // The minimum value, if known
+	max   int  // The maximum value, if known
+	known bool // Whether the extremes are known
+	stale bool // Whether the extremes need to be recomputed
 }
 
 // makeInts creates a new vector for Ints
@@ -416,6 +699,10 @@ func makeInts() Column {
 
 // Grow grows the size of the column until we have enough to store
 func (c *intColumn) Grow(idx uint32) {
+	for len(c.extremes) <= int(commit.ChunkAt(idx)) {
+		c.extremes = append(c.extremes, intBounds{})
+	}
+
 	if idx < uint32(len(c.data)) {
 		return
 	}
@@ -432,28 +719,124 @@ func (c *intColumn) Grow(idx uint32) {
 	c.data = clone
 }
 
+// compact reclaims the spare capacity of the column
+func (c *intColumn) compact() {
+	c.fill = clip(c.fill)
+	c.data = clip(c.data)
+}
+
 // Apply applies a set of operations to the column.
 func (c *intColumn) Apply(r *commit.Reader) {
 	for r.Next() {
+		bounds := &c.extremes[commit.ChunkAt(r.Index())]
 		switch r.Type {
 		case commit.Put:
+			value := r.Int()
+			c.bound(bounds, r.Index(), value)
 			c.fill[r.Offset>>6] |= 1 << (r.Offset & 0x3f)
-			c.data[r.Offset] = r.Int()
+			c.data[r.Offset] = value
 
 		// If this is an atomic increment/decrement, we need to change the operation to
 		// the final value, since after this update an index needs to be recalculated.
 		case commit.Add:
-			c.fill[r.Offset>>6] |= 1 << (r.Offset & 0x3f)
 			value := c.data[r.Offset] + r.Int()
+			c.bound(bounds, r.Index(), value)
+			c.fill[r.Offset>>6] |= 1 << (r.Offset & 0x3f)
 			c.data[r.Offset] = value
 			r.SwapInt(value)
 
 		case commit.Delete:
+			c.unbound(bounds, r.Index())
 			c.fill.Remove(r.Index())
 		}
 	}
 }
 
+// bound updates the extremes of a chunk with a value about to be stored at the index. If the
+// value replaces one of the extremes with a less extreme one, the extremes become stale.
+func (c *intColumn) bound(b *intBounds, idx uint32, value int) {
+	if b.stale {
+		return
+	}
+
+	if c.fill.Contains(idx) {
+		if prev := c.data[idx]; (prev == b.min && value > prev) || (prev == b.max && value < prev) {
+			b.stale = true
+			return
+		}
+	}
+
+	switch {
+	case !b.known:
+		b.min, b.max, b.known = value, value, true
+	case value < b.min:
+		b.min = value
+	case value > b.max:
+		b.max = value
+	}
+}
+
+// unbound marks the extremes of a chunk as stale if the value about to be removed is one of them
+func (c *intColumn) unbound(b *intBounds, idx uint32) {
+	if !b.stale && c.fill.Contains(idx) {
+		prev := c.data[idx]
+		b.stale = prev == b.min || prev == b.max
+	}
+}
+
+// bounds returns the minimum and maximum values of the column, combining the extremes of
+// each chunk. The latch function must hold the latch of the chunk while calling back, for
+// writing if it is exclusive, since only the stale chunks are recomputed.
+func (c *intColumn) bounds(chunks int, latch func(chunk commit.Chunk, exclusive bool, fn func())) (min, max interface{}, ok bool) {
+	var lo, hi int
+	for chunk := commit.Chunk(0); int(chunk) < chunks; chunk++ {
+		var b intBounds
+		latch(chunk, false, func() {
+			if int(chunk) < len(c.extremes) {
+				b = c.extremes[chunk]
+			}
+		})
+
+		if b.stale {
+			latch(chunk, true, func() {
+				b = c.rebound(chunk)
+			})
+		}
+
+		switch {
+		case !b.known:
+			continue
+		case !ok:
+			lo, hi, ok = b.min, b.max, true
+		default:
+			if b.min < lo {
+				lo = b.min
+			}
+			if b.max > hi {
+				hi = b.max
+			}
+		}
+	}
+
+	if !ok {
+		return nil, nil, false
+	}
+	return lo, hi, true
+}
+
+// rebound recomputes the extremes of a chunk, if they are still stale. The caller must hold
+// the write latch of the chunk.
+func (c *intColumn) rebound(chunk commit.Chunk) intBounds {
+	b := &c.extremes[chunk]
+	if b.stale {
+		b.known, b.stale = false, false
+		chunk.Range(c.fill, func(idx uint32) {
+			c.bound(b, idx, c.data[idx])
+		})
+	}
+	return *b
+}
+
 // Contains checks whether the column has a value at a specified index.
 func (c *intColumn) Contains(idx uint32) bool {
 	return c.fill.Contains(idx)
@@ -532,6 +915,18 @@ func (c *intColumn) FilterUint64(offset uint32, index bitmap.Bitmap, predicate f
 	})
 }
 
+// sum computes the sum of the values present at the indices of the bitmap, which
+// starts at the specified offset.
+func (c *intColumn) sum(offset uint32, index bitmap.Bitmap) (sum int) {
+	index.Range(func(idx uint32) {
+		idx = offset + idx
+		if idx < uint32(len(c.data)) && c.fill.Contains(idx) {
+			sum += c.data[idx]
+		}
+	})
+	return
+}
+
 // Snapshot writes the entire column into the specified destination buffer
 func (c *intColumn) Snapshot(chunk commit.Chunk, dst *commit.Buffer) {
 	chunk.Range(c.fill, func(idx uint32) {
@@ -543,10 +938,12 @@ func (c *intColumn) Snapshot(chunk commit.Chunk, dst *commit.Buffer) {
 type intReader struct {
 	cursor *uint32
 	reader *intColumn
+	stats  *columnStats
 }
 
 // Get loads the value at the current transaction cursor
 func (s intReader) Get() (int, bool) {
+	s.stats.read()
 	return s.reader.load(*s.cursor)
 }
 
@@ -565,6 +962,7 @@ func intReaderFor(txn *Txn, columnName string) intReader {
 	return intReader{
 		cursor: &txn.cursor,
 		reader: reader,
+		stats:  column.stats,
 	}
 }
 
@@ -576,11 +974,13 @@ type intWriter struct {
 
 // Set sets the value at the current transaction cursor
 func (s intWriter) Set(value int) {
+	s.stats.write()
 	s.writer.PutInt(*s.cursor, value)
 }
 
 // Add atomically adds a delta to the value at the current transaction cursor
 func (s intWriter) Add(delta int) {
+	s.stats.write()
 	s.writer.AddInt(*s.cursor, delta)
 }
 
@@ -592,12 +992,32 @@ func (txn *Txn) Int(columnName string) intWriter {
 	}
 }
 
+// SumInt computes the sum of the int column over the objects matching the query,
+// without boxing the values. It panics if the column is not of type int.
+func (txn *Txn) SumInt(columnName string) (sum int) {
+	column := intReaderFor(txn, columnName).reader
+	txn.initialize()
+	txn.rangeRead(func(offset uint32, index bitmap.Bitmap) {
+		sum += column.sum(offset, index)
+	})
+	return
+}
+
 // --------------------------- Int16s ----------------------------
 
 // int16Column represents a generic column
 type int16Column struct {
-	fill bitmap.Bitmap // The fill-list
-	data []int16       // The actual values
+	fill     bitmap.Bitmap // The fill-list
+	data     []int16       // The actual values
+	extremes []int16Bounds // The extremes of the values of each chunk
+}
+
+// int16Bounds represents the extremes of the values of a chunk
+type int16Bounds struct {
+	min   int16 // The minimum value, if known
+	max   int16 // The maximum value, if known
+	known bool  // Whether the extremes are known
+	stale bool  // Whether the extremes need to be recomputed
 }
 
 // makeInt16s creates a new vector for Int16s
@@ -610,6 +1030,10 @@ func makeInt16s() Column {
 
 // Grow grows the size of the column until we have enough to store
 func (c *int16Column) Grow(idx uint32) {
+	for len(c.extremes) <= int(commit.ChunkAt(idx)) {
+		c.extremes = append(c.extremes, int16Bounds{})
+	}
+
 	if idx < uint32(len(c.data)) {
 		return
 	}
@@ -626,28 +1050,124 @@ func (c *int16Column) Grow(idx uint32) {
 	c.data = clone
 }
 
+// compact reclaims the spare capacity of the column
+func (c *int16Column) compact() {
+	c.fill = clip(c.fill)
+	c.data = clip(c.data)
+}
+
 // Apply applies a set of operations to the column.
 func (c *int16Column) Apply(r *commit.Reader) {
 	for r.Next() {
+		bounds := &c.extremes[commit.ChunkAt(r.Index())]
 		switch r.Type {
 		case commit.Put:
+			value := r.Int16()
+			c.bound(bounds, r.Index(), value)
 			c.fill[r.Offset>>6] |= 1 << (r.Offset & 0x3f)
-			c.data[r.Offset] = r.Int16()
+			c.data[r.Offset] = value
 
 		// If this is an atomic increment/decrement, we need to change the operation to
 		// the final value, since after this update an index needs to be recalculated.
 		case commit.Add:
-			c.fill[r.Offset>>6] |= 1 << (r.Offset & 0x3f)
 			value := c.data[r.Offset] + r.Int16()
+			c.bound(bounds, r.Index(), value)
+			c.fill[r.Offset>>6] |= 1 << (r.Offset & 0x3f)
 			c.data[r.Offset] = value
 			r.SwapInt16(value)
 
 		case commit.Delete:
+			c.unbound(bounds, r.Index())
 			c.fill.Remove(r.Index())
 		}
 	}
 }
 
+// bound updates the extremes of a chunk with a value about to be stored at the index. If the
+// value replaces one of the extremes with a less extreme one, the extremes become stale.
+func (c *int16Column) bound(b *int16Bounds, idx uint32, value int16) {
+	if b.stale {
+		return
+	}
+
+	if c.fill.Contains(idx) {
+		if prev := c.data[idx]; (prev == b.min && value > prev) || (prev == b.max && value < prev) {
+			b.stale = true
+			return
+		}
+	}
+
+	switch {
+	case !b.known:
+		b.min, b.max, b.known = value, value, true
+	case value < b.min:
+		b.min = value
+	case value > b.max:
+		b.max = value
+	}
+}
+
+// unbound marks the extremes of a chunk as stale if the value about to be removed is one of them
+func (c *int16Column) unbound(b *int16Bounds, idx uint32) {
+	if !b.stale && c.fill.Contains(idx) {
+		prev := c.data[idx]
+		b.stale = prev == b.min || prev == b.max
+	}
+}
+
+// bounds returns the minimum and maximum values of the column, combining the extremes of
+// each chunk. The latch function must hold the latch of the chunk while calling back, for
+// writing if it is exclusive, since only the stale chunks are recomputed.
+func (c *int16Column) bounds(chunks int, latch func(chunk commit.Chunk, exclusive bool, fn func())) (min, max interface{}, ok bool) {
+	var lo, hi int16
+	for chunk := commit.Chunk(0); int(chunk) < chunks; chunk++ {
+		var b int16Bounds
+		latch(chunk, false, func() {
+			if int(chunk) < len(c.extremes) {
+				b = c.extremes[chunk]
+			}
+		})
+
+		if b.stale {
+			latch(chunk, true, func() {
+				b = c.rebound(chunk)
+			})
+		}
+
+		switch {
+		case !b.known:
+			continue
+		case !ok:
+			lo, hi, ok = b.min, b.max, true
+		default:
+			if b.min < lo {
+				lo = b.min
+			}
+			if b.max > hi {
+				hi = b.max
+			}
+		}
+	}
+
+	if !ok {
+		return nil, nil, false
+	}
+	return lo, hi, true
+}
+
+// rebound recomputes the extremes of a chunk, if they are still stale. The caller must hold
+// the write latch of the chunk.
+func (c *int16Column) rebound(chunk commit.Chunk) int16Bounds {
+	b := &c.extremes[chunk]
+	if b.stale {
+		b.known, b.stale = false, false
+		chunk.Range(c.fill, func(idx uint32) {
+			c.bound(b, idx, c.data[idx])
+		})
+	}
+	return *b
+}
+
 // Contains checks whether the column has a value at a specified index.
 func (c *int16Column) Contains(idx uint32) bool {
 	return c.fill.Contains(idx)
@@ -726,6 +1246,18 @@ func (c *int16Column) FilterUint64(offset uint32, index bitmap.Bitmap, predicate
 	})
 }
 
+// sum computes the sum of the values present at the indices of the bitmap, which
+// starts at the specified offset.
+func (c *int16Column) sum(offset uint32, index bitmap.Bitmap) (sum int16) {
+	index.Range(func(idx uint32) {
+		idx = offset + idx
+		if idx < uint32(len(c.data)) && c.fill.Contains(idx) {
+			sum += c.data[idx]
+		}
+	})
+	return
+}
+
 // Snapshot writes the entire column into the specified destination buffer
 func (c *int16Column) Snapshot(chunk commit.Chunk, dst *commit.Buffer) {
 	chunk.Range(c.fill, func(idx uint32) {
@@ -737,10 +1269,12 @@ func (c *int16Column) Snapshot(chunk commit.Chunk, dst *commit.Buffer) {
 type int16Reader struct {
 	cursor *uint32
 	reader *int16Column
+	stats  *columnStats
 }
 
 // Get loads the value at the current transaction cursor
 func (s int16Reader) Get() (int16, bool) {
+	s.stats.read()
 	return s.reader.load(*s.cursor)
 }
 
@@ -759,6 +1293,7 @@ func int16ReaderFor(txn *Txn, columnName string) int16Reader {
 	return int16Reader{
 		cursor: &txn.cursor,
 		reader: reader,
+		stats:  column.stats,
 	}
 }
 
@@ -770,11 +1305,13 @@ type int16Writer struct {
 
 // Set sets the value at the current transaction cursor
 func (s int16Writer) Set(value int16) {
+	s.stats.write()
 	s.writer.PutInt16(*s.cursor, value)
 }
 
 // Add atomically adds a delta to the value at the current transaction cursor
 func (s int16Writer) Add(delta int16) {
+	s.stats.write()
 	s.writer.AddInt16(*s.cursor, delta)
 }
 
@@ -786,12 +1323,32 @@ func (txn *Txn) Int16(columnName string) int16Writer {
 	}
 }
 
+// SumInt16 computes the sum of the int16 column over the objects matching the query,
+// without boxing the values. It panics if the column is not of type int16.
+func (txn *Txn) SumInt16(columnName string) (sum int16) {
+	column := int16ReaderFor(txn, columnName).reader
+	txn.initialize()
+	txn.rangeRead(func(offset uint32, index bitmap.Bitmap) {
+		sum += column.sum(offset, index)
+	})
+	return
+}
+
 // --------------------------- Int32s ----------------------------
 
 // int32Column represents a generic column
 type int32Column struct {
-	fill bitmap.Bitmap // The fill-list
-	data []int32       // The actual values
+	fill     bitmap.Bitmap // The fill-list
+	data     []int32       // The actual values
+	extremes []int32Bounds // The extremes of the values of each chunk
+}
+
+// int32Bounds represents the extremes of the values of a chunk
+type int32Bounds struct {
+	min   int32 // The minimum value, if known
+	max   int32 // The maximum value, if known
+	known bool  // Whether the extremes are known
+	stale bool  // Whether the extremes need to be recomputed
 }
 
 // makeInt32s creates a new vector for Int32s
@@ -804,6 +1361,10 @@ func makeInt32s() Column {
 
 // Grow grows the size of the column until we have enough to store
 func (c *int32Column) Grow(idx uint32) {
+	for len(c.extremes) <= int(commit.ChunkAt(idx)) {
+		c.extremes = append(c.extremes, int32Bounds{})
+	}
+
 	if idx < uint32(len(c.data)) {
 		return
 	}
@@ -820,28 +1381,124 @@ func (c *int32Column) Grow(idx uint32) {
 	c.data = clone
 }
 
+// compact reclaims the spare capacity of the column
+func (c *int32Column) compact() {
+	c.fill = clip(c.fill)
+	c.data = clip(c.data)
+}
+
 // Apply applies a set of operations to the column.
 func (c *int32Column) Apply(r *commit.Reader) {
 	for r.Next() {
+		bounds := &c.extremes[commit.ChunkAt(r.Index())]
 		switch r.Type {
 		case commit.Put:
+			value := r.Int32()
+			c.bound(bounds, r.Index(), value)
 			c.fill[r.Offset>>6] |= 1 << (r.Offset & 0x3f)
-			c.data[r.Offset] = r.Int32()
+			c.data[r.Offset] = value
 
 		// If this is an atomic increment/decrement, we need to change the operation to
 		// the final value, since after this update an index needs to be recalculated.
 		case commit.Add:
-			c.fill[r.Offset>>6] |= 1 << (r.Offset & 0x3f)
 			value := c.data[r.Offset] + r.Int32()
+			c.bound(bounds, r.Index(), value)
+			c.fill[r.Offset>>6] |= 1 << (r.Offset & 0x3f)
 			c.data[r.Offset] = value
 			r.SwapInt32(value)
 
 		case commit.Delete:
+			c.unbound(bounds, r.Index())
 			c.fill.Remove(r.Index())
 		}
 	}
 }
 
+// bound updates the extremes of a chunk with a value about to be stored at the index. If the
+// value replaces one of the extremes with a less extreme one, the extremes become stale.
+func (c *int32Column) bound(b *int32Bounds, idx uint32, value int32) {
+	if b.stale {
+		return
+	}
+
+	if c.fill.Contains(idx) {
+		if prev := c.data[idx]; (prev == b.min && value > prev) || (prev == b.max && value < prev) {
+			b.stale = true
+			return
+		}
+	}
+
+	switch {
+	case !b.known:
+		b.min, b.max, b.known = value, value, true
+	case value < b.min:
+		b.min = value
+	case value > b.max:
+		b.max = value
+	}
+}
+
+// unbound marks the extremes of a chunk as stale if the value about to be removed is one of them
+func (c *int32Column) unbound(b *int32Bounds, idx uint32) {
+	if !b.stale && c.fill.Contains(idx) {
+		prev := c.data[idx]
+		b.stale = prev == b.min || prev == b.max
+	}
+}
+
+// bounds returns the minimum and maximum values of the column, combining the extremes of
+// each chunk. The latch function must hold the latch of the chunk while calling back, for
+// writing if it is exclusive, since only the stale chunks are recomputed.
+func (c *int32Column) bounds(chunks int, latch func(chunk commit.Chunk, exclusive bool, fn func())) (min, max interface{}, ok bool) {
+	var lo, hi int32
+	for chunk := commit.Chunk(0); int(chunk) < chunks; chunk++ {
+		var b int32Bounds
+		latch(chunk, false, func() {
+			if int(chunk) < len(c.extremes) {
+				b = c.extremes[chunk]
+			}
+		})
+
+		if b.stale {
+			latch(chunk, true, func() {
+				b = c.rebound(chunk)
+			})
+		}
+
+		switch {
+		case !b.known:
+			continue
+		case !ok:
+			lo, hi, ok = b.min, b.max, true
+		default:
+			if b.min < lo {
+				lo = b.min
+			}
+			if b.max > hi {
+				hi = b.max
+			}
+		}
+	}
+
+	if !ok {
+		return nil, nil, false
+	}
+	return lo, hi, true
+}
+
+// rebound recomputes the extremes of a chunk, if they are still stale. The caller must hold
+// the write latch of the chunk.
+func (c *int32Column) rebound(chunk commit.Chunk) int32Bounds {
+	b := &c.extremes[chunk]
+	if b.stale {
+		b.known, b.stale = false, false
+		chunk.Range(c.fill, func(idx uint32) {
+			c.bound(b, idx, c.data[idx])
+		})
+	}
+	return *b
+}
+
 // Contains checks whether the column has a value at a specified index.
 func (c *int32Column) Contains(idx uint32) bool {
 	return c.fill.Contains(idx)
@@ -920,6 +1577,18 @@ func (c *int32Column) FilterUint64(offset uint32, index bitmap.Bitmap, predicate
 	})
 }
 
+// sum computes the sum of the values present at the indices of the bitmap, which
+// starts at the specified offset.
+func (c *int32Column) sum(offset uint32, index bitmap.Bitmap) (sum int32) {
+	index.Range(func(idx uint32) {
+		idx = offset + idx
+		if idx < uint32(len(c.data)) && c.fill.Contains(idx) {
+			sum += c.data[idx]
+		}
+	})
+	return
+}
+
 // Snapshot writes the entire column into the specified destination buffer
 func (c *int32Column) Snapshot(chunk commit.Chunk, dst *commit.Buffer) {
 	chunk.Range(c.fill, func(idx uint32) {
@@ -931,10 +1600,12 @@ func (c *int32Column) Snapshot(chunk commit.Chunk, dst *commit.Buffer) {
 type int32Reader struct {
 	cursor *uint32
 	reader *int32Column
+	stats  *columnStats
 }
 
 // Get loads the value at the current transaction cursor
 func (s int32Reader) Get() (int32, bool) {
+	s.stats.read()
 	return s.reader.load(*s.cursor)
 }
 
@@ -953,6 +1624,7 @@ func int32ReaderFor(txn *Txn, columnName string) int32Reader {
 	return int32Reader{
 		cursor: &txn.cursor,
 		reader: reader,
+		stats:  column.stats,
 	}
 }
 
@@ -964,11 +1636,13 @@ type int32Writer struct {
 
 // Set sets the value at the current transaction cursor
 func (s int32Writer) Set(value int32) {
+	s.stats.write()
 	s.writer.PutInt32(*s.cursor, value)
 }
 
 // Add atomically adds a delta to the value at the current transaction cursor
 func (s int32Writer) Add(delta int32) {
+	s.stats.write()
 	s.writer.AddInt32(*s.cursor, delta)
 }
 
@@ -980,12 +1654,32 @@ func (txn *Txn) Int32(columnName string) int32Writer {
 	}
 }
 
+// SumInt32 computes the sum of the int32 column over the objects matching the query,
+// without boxing the values. It panics if the column is not of type int32.
+func (txn *Txn) SumInt32(columnName string) (sum int32) {
+	column := int32ReaderFor(txn, columnName).reader
+	txn.initialize()
+	txn.rangeRead(func(offset uint32, index bitmap.Bitmap) {
+		sum += column.sum(offset, index)
+	})
+	return
+}
+
 // --------------------------- Int64s ----------------------------
 
 // int64Column represents a generic column
 type int64Column struct {
-	fill bitmap.Bitmap // The fill-list
-	data []int64       // The actual values
+	fill     bitmap.Bitmap // The fill-list
+	data     []int64       // The actual values
+	extremes []int64Bounds // The extremes of the values of each chunk
+}
+
+// int64Bounds represents the extremes of the values of a chunk
+type int64Bounds struct {
+	min   int64 // The minimum value, if known
+	max   int64 // The maximum value, if known
+	known bool  // Whether the extremes are known
+	stale bool  // Whether the extremes need to be recomputed
 }
 
 // makeInt64s creates a new vector for Int64s
@@ -998,6 +1692,10 @@ func makeInt64s() Column {
 
 // Grow grows the size of the column until we have enough to store
 func (c *int64Column) Grow(idx uint32) {
+	for len(c.extremes) <= int(commit.ChunkAt(idx)) {
+		c.extremes = append(c.extremes, int64Bounds{})
+	}
+
 	if idx < uint32(len(c.data)) {
 		return
 	}
@@ -1014,28 +1712,124 @@ func (c *int64Column) Grow(idx uint32) {
 	c.data = clone
 }
 
+// compact reclaims the spare capacity of the column
+func (c *int64Column) compact() {
+	c.fill = clip(c.fill)
+	c.data = clip(c.data)
+}
+
 // Apply applies a set of operations to the column.
 func (c *int64Column) Apply(r *commit.Reader) {
 	for r.Next() {
+		bounds := &c.extremes[commit.ChunkAt(r.Index())]
 		switch r.Type {
 		case commit.Put:
+			value := r.Int64()
+			c.bound(bounds, r.Index(), value)
 			c.fill[r.Offset>>6] |= 1 << (r.Offset & 0x3f)
-			c.data[r.Offset] = r.Int64()
+			c.data[r.Offset] = value
 
 		// If this is an atomic increment/decrement, we need to change the operation to
 		// the final value, since after this update an index needs to be recalculated.
 		case commit.Add:
-			c.fill[r.Offset>>6] |= 1 << (r.Offset & 0x3f)
 			value := c.data[r.Offset] + r.Int64()
+			c.bound(bounds, r.Index(), value)
+			c.fill[r.Offset>>6] |= 1 << (r.Offset & 0x3f)
 			c.data[r.Offset] = value
 			r.SwapInt64(value)
 
 		case commit.Delete:
+			c.unbound(bounds, r.Index())
 			c.fill.Remove(r.Index())
 		}
 	}
 }
 
+// bound updates the extremes of a chunk with a value about to be stored at the index. If the
+// value replaces one of the extremes with a less extreme one, the extremes become stale.
+func (c *int64Column) bound(b *int64Bounds, idx uint32, value int64) {
+	if b.stale {
+		return
+	}
+
+	if c.fill.Contains(idx) {
+		if prev := c.data[idx]; (prev == b.min && value > prev) || (prev == b.max && value < prev) {
+			b.stale = true
+			return
+		}
+	}
+
+	switch {
+	case !b.known:
+		b.min, b.max, b.known = value, value, true
+	case value < b.min:
+		b.min = value
+	case value > b.max:
+		b.max = value
+	}
+}
+
+// unbound marks the extremes of a chunk as stale if the value about to be removed is one of them
+func (c *int64Column) unbound(b *int64Bounds, idx uint32) {
+	if !b.stale && c.fill.Contains(idx) {
+		prev := c.data[idx]
+		b.stale = prev == b.min || prev == b.max
+	}
+}
+
+// bounds returns the minimum and maximum values of the column, combining the extremes of
+// each chunk. The latch function must hold the latch of the chunk while calling back, for
+// writing if it is exclusive, since only the stale chunks are recomputed.
+func (c *int64Column) bounds(chunks int, latch func(chunk commit.Chunk, exclusive bool, fn func())) (min, max interface{}, ok bool) {
+	var lo, hi int64
+	for chunk := commit.Chunk(0); int(chunk) < chunks; chunk++ {
+		var b int64Bounds
+		latch(chunk, false, func() {
+			if int(chunk) < len(c.extremes) {
+				b = c.extremes[chunk]
+			}
+		})
+
+		if b.stale {
+			latch(chunk, true, func() {
+				b = c.rebound(chunk)
+			})
+		}
+
+		switch {
+		case !b.known:
+			continue
+		case !ok:
+			lo, hi, ok = b.min, b.max, true
+		default:
+			if b.min < lo {
+				lo = b.min
+			}
+			if b.max > hi {
+				hi = b.max
+			}
+		}
+	}
+
+	if !ok {
+		return nil, nil, false
+	}
+	return lo, hi, true
+}
+
+// rebound recomputes the extremes of a chunk, if they are still stale. The caller must hold
+// the write latch of the chunk.
+func (c *int64Column) rebound(chunk commit.Chunk) int64Bounds {
+	b := &c.extremes[chunk]
+	if b.stale {
+		b.known, b.stale = false, false
+		chunk.Range(c.fill, func(idx uint32) {
+			c.bound(b, idx, c.data[idx])
+		})
+	}
+	return *b
+}
+
 // Contains checks whether the column has a value at a specified index.
 func (c *int64Column) Contains(idx uint32) bool {
 	return c.fill.Contains(idx)
@@ -1114,6 +1908,18 @@ func (c *int64Column) FilterUint64(offset uint32, index bitmap.Bitmap, predicate
 	})
 }
 
+// sum computes the sum of the values present at the indices of the bitmap, which
+// starts at the specified offset.
+func (c *int64Column) sum(offset uint32, index bitmap.Bitmap) (sum int64) {
+	index.Range(func(idx uint32) {
+		idx = offset + idx
+		if idx < uint32(len(c.data)) && c.fill.Contains(idx) {
+			sum += c.data[idx]
+		}
+	})
+	return
+}
+
 // Snapshot writes the entire column into the specified destination buffer
 func (c *int64Column) Snapshot(chunk commit.Chunk, dst *commit.Buffer) {
 	chunk.Range(c.fill, func(idx uint32) {
@@ -1125,10 +1931,12 @@ func (c *int64Column) Snapshot(chunk commit.Chunk, dst *commit.Buffer) {
 type int64Reader struct {
 	cursor *uint32
 	reader *int64Column
+	stats  *columnStats
 }
 
 // Get loads the value at the current transaction cursor
 func (s int64Reader) Get() (int64, bool) {
+	s.stats.read()
 	return s.reader.load(*s.cursor)
 }
 
@@ -1147,6 +1955,7 @@ func int64ReaderFor(txn *Txn, columnName string) int64Reader {
 	return int64Reader{
 		cursor: &txn.cursor,
 		reader: reader,
+		stats:  column.stats,
 	}
 }
 
@@ -1158,11 +1967,13 @@ type int64Writer struct {
 
 // Set sets the value at the current transaction cursor
 func (s int64Writer) Set(value int64) {
+	s.stats.write()
 	s.writer.PutInt64(*s.cursor, value)
 }
 
 // Add atomically adds a delta to the value at the current transaction cursor
 func (s int64Writer) Add(delta int64) {
+	s.stats.write()
 	s.writer.AddInt64(*s.cursor, delta)
 }
 
@@ -1174,12 +1985,32 @@ func (txn *Txn) Int64(columnName string) int64Writer {
 	}
 }
 
+// SumInt64 computes the sum of the int64 column over the objects matching the query,
+// without boxing the values. It panics if the column is not of type int64.
+func (txn *Txn) SumInt64(columnName string) (sum int64) {
+	column := int64ReaderFor(txn, columnName).reader
+	txn.initialize()
+	txn.rangeRead(func(offset uint32, index bitmap.Bitmap) {
+		sum += column.sum(offset, index)
+	})
+	return
+}
+
 // --------------------------- Uints ----------------------------
 
 // uintColumn represents a generic column
 type uintColumn struct {
-	fill bitmap.Bitmap // The fill-list
-	data []uint        // The actual values
+	fill     bitmap.Bitmap // The fill-list
+	data     []uint        // The actual values
+	extremes []uintBounds  // The extremes of the values of each chunk
+}
+
+// uintBounds represents the extremes of the values of a chunk
+type uintBounds struct {
+	min   uint // The minimum value, if known
+	max   uint // The maximum value, if known
+	known bool // Whether the extremes are known
+	stale bool // Whether the extremes need to be recomputed
 }
 
 // makeUints creates a new vector for Uints
@@ -1192,6 +2023,10 @@ func makeUints() Column {
 
 // Grow grows the size of the column until we have enough to store
 func (c *uintColumn) Grow(idx uint32) {
+	for len(c.extremes) <= int(commit.ChunkAt(idx)) {
+		c.extremes = append(c.extremes, uintBounds{})
+	}
+
 	if idx < uint32(len(c.data)) {
 		return
 	}
@@ -1208,28 +2043,124 @@ func (c *uintColumn) Grow(idx uint32) {
 	c.data = clone
 }
 
+// compact reclaims the spare capacity of the column
+func (c *uintColumn) compact() {
+	c.fill = clip(c.fill)
+	c.data = clip(c.data)
+}
+
 // Apply applies a set of operations to the column.
 func (c *uintColumn) Apply(r *commit.Reader) {
 	for r.Next() {
+		bounds := &c.extremes[commit.ChunkAt(r.Index())]
 		switch r.Type {
 		case commit.Put:
+			value := r.Uint()
+			c.bound(bounds, r.Index(), value)
 			c.fill[r.Offset>>6] |= 1 << (r.Offset & 0x3f)
-			c.data[r.Offset] = r.Uint()
+			c.data[r.Offset] = value
 
 		// If this is an atomic increment/decrement, we need to change the operation to
 		// the final value, since after this update an index needs to be recalculated.
 		case commit.Add:
-			c.fill[r.Offset>>6] |= 1 << (r.Offset & 0x3f)
 			value := c.data[r.Offset] + r.Uint()
+			c.bound(bounds, r.Index(), value)
+			c.fill[r.Offset>>6] |= 1 << (r.Offset & 0x3f)
 			c.data[r.Offset] = value
 			r.SwapUint(value)
 
 		case commit.Delete:
+			c.unbound(bounds, r.Index())
 			c.fill.Remove(r.Index())
 		}
 	}
 }
 
+// bound updates the extremes of a chunk with a value about to be stored at the index. If the
+// value replaces one of the extremes with a less extreme one, the extremes become stale.
+func (c *uintColumn) bound(b *uintBounds, idx uint32, value uint) {
+	if b.stale {
+		return
+	}
+
+	if c.fill.Contains(idx) {
+		if prev := c.data[idx]; (prev == b.min && value > prev) || (prev == b.max && value < prev) {
+			b.stale = true
+			return
+		}
+	}
+
+	switch {
+	case !b.known:
+		b.min, b.max, b.known = value, value, true
+	case value < b.min:
+		b.min = value
+	case value > b.max:
+		b.max = value
+	}
+}
+
+// unbound marks the extremes of a chunk as stale if the value about to be removed is one of them
+func (c *uintColumn) unbound(b *uintBounds, idx uint32) {
+	if !b.stale && c.fill.Contains(idx) {
+		prev := c.data[idx]
+		b.stale = prev == b.min || prev == b.max
+	}
+}
+
+// bounds returns the minimum and maximum values of the column, combining the extremes of
+// each chunk. The latch function must hold the latch of the chunk while calling back, for
+// writing if it is exclusive, since only the stale chunks are recomputed.
+func (c *uintColumn) bounds(chunks int, latch func(chunk commit.Chunk, exclusive bool, fn func())) (min, max interface{}, ok bool) {
+	var lo, hi uint
+	for chunk := commit.Chunk(0); int(chunk) < chunks; chunk++ {
+		var b uintBounds
+		latch(chunk, false, func() {
+			if int(chunk) < len(c.extremes) {
+				b = c.extremes[chunk]
+			}
+		})
+
+		if b.stale {
+			latch(chunk, true, func() {
+				b = c.rebound(chunk)
+			})
+		}
+
+		switch {
+		case !b.known:
+			continue
+		case !ok:
+			lo, hi, ok = b.min, b.max, true
+		default:
+			if b.min < lo {
+				lo = b.min
+			}
+			if b.max > hi {
+				hi = b.max
+			}
+		}
+	}
+
+	if !ok {
+		return nil, nil, false
+	}
+	return lo, hi, true
+}
+
+// rebound recomputes the extremes of a chunk, if they are still stale. The caller must hold
+// the write latch of the chunk.
+func (c *uintColumn) rebound(chunk commit.Chunk) uintBounds {
+	b := &c.extremes[chunk]
+	if b.stale {
+		b.known, b.stale = false, false
+		chunk.Range(c.fill, func(idx uint32) {
+			c.bound(b, idx, c.data[idx])
+		})
+	}
+	return *b
+}
+
 // Contains checks whether the column has a value at a specified index.
 func (c *uintColumn) Contains(idx uint32) bool {
 	return c.fill.Contains(idx)
@@ -1308,6 +2239,18 @@ func (c *uintColumn) FilterUint64(offset uint32, index bitmap.Bitmap, predicate
 	})
 }
 
+// sum computes the sum of the values present at the indices of the bitmap, which
+// starts at the specified offset.
+func (c *uintColumn) sum(offset uint32, index bitmap.Bitmap) (sum uint) {
+	index.Range(func(idx uint32) {
+		idx = offset + idx
+		if idx < uint32(len(c.data)) && c.fill.Contains(idx) {
+			sum += c.data[idx]
+		}
+	})
+	return
+}
+
 // Snapshot writes the entire column into the specified destination buffer
 func (c *uintColumn) Snapshot(chunk commit.Chunk, dst *commit.Buffer) {
 	chunk.Range(c.fill, func(idx uint32) {
@@ -1319,10 +2262,12 @@ func (c *uintColumn) Snapshot(chunk commit.Chunk, dst *commit.Buffer) {
 type uintReader struct {
 	cursor *uint32
 	reader *uintColumn
+	stats  *columnStats
 }
 
 // Get loads the value at the current transaction cursor
 func (s uintReader) Get() (uint, bool) {
+	s.stats.read()
 	return s.reader.load(*s.cursor)
 }
 
@@ -1341,6 +2286,7 @@ func uintReaderFor(txn *Txn, columnName string) uintReader {
 	return uintReader{
 		cursor: &txn.cursor,
 		reader: reader,
+		stats:  column.stats,
 	}
 }
 
@@ -1352,11 +2298,13 @@ type uintWriter struct {
 
 // Set sets the value at the current transaction cursor
 func (s uintWriter) Set(value uint) {
+	s.stats.write()
 	s.writer.PutUint(*s.cursor, value)
 }
 
 // Add atomically adds a delta to the value at the current transaction cursor
 func (s uintWriter) Add(delta uint) {
+	s.stats.write()
 	s.writer.AddUint(*s.cursor, delta)
 }
 
@@ -1368,12 +2316,32 @@ func (txn *Txn) Uint(columnName string) uintWriter {
 	}
 }
 
+// SumUint computes the sum of the uint column over the objects matching the query,
+// without boxing the values. It panics if the column is not of type uint.
+func (txn *Txn) SumUint(columnName string) (sum uint) {
+	column := uintReaderFor(txn, columnName).reader
+	txn.initialize()
+	txn.rangeRead(func(offset uint32, index bitmap.Bitmap) {
+		sum += column.sum(offset, index)
+	})
+	return
+}
+
 // --------------------------- Uint16s ----------------------------
 
 // uint16Column represents a generic column
 type uint16Column struct {
-	fill bitmap.Bitmap // The fill-list
-	data []uint16      // The actual values
+	fill     bitmap.Bitmap  // The fill-list
+	data     []uint16       // The actual values
+	extremes []uint16Bounds // The extremes of the values of each chunk
+}
+
+// uint16Bounds represents the extremes of the values of a chunk
+type uint16Bounds struct {
+	min   uint16 // The minimum value, if known
+	max   uint16 // The maximum value, if known
+	known bool   // Whether the extremes are known
+	stale bool   // Whether the extremes need to be recomputed
 }
 
 // makeUint16s creates a new vector for Uint16s
@@ -1386,6 +2354,10 @@ func makeUint16s() Column {
 
 // Grow grows the size of the column until we have enough to store
 func (c *uint16Column) Grow(idx uint32) {
+	for len(c.extremes) <= int(commit.ChunkAt(idx)) {
+		c.extremes = append(c.extremes, uint16Bounds{})
+	}
+
 	if idx < uint32(len(c.data)) {
 		return
 	}
@@ -1402,28 +2374,124 @@ func (c *uint16Column) Grow(idx uint32) {
 	c.data = clone
 }
 
+// compact reclaims the spare capacity of the column
+func (c *uint16Column) compact() {
+	c.fill = clip(c.fill)
+	c.data = clip(c.data)
+}
+
 // Apply applies a set of operations to the column.
 func (c *uint16Column) Apply(r *commit.Reader) {
 	for r.Next() {
+		bounds := &c.extremes[commit.ChunkAt(r.Index())]
 		switch r.Type {
 		case commit.Put:
+			value := r.Uint16()
+			c.bound(bounds, r.Index(), value)
 			c.fill[r.Offset>>6] |= 1 << (r.Offset & 0x3f)
-			c.data[r.Offset] = r.Uint16()
+			c.data[r.Offset] = value
 
 		// If this is an atomic increment/decrement, we need to change the operation to
 		// the final value, since after this update an index needs to be recalculated.
 		case commit.Add:
-			c.fill[r.Offset>>6] |= 1 << (r.Offset & 0x3f)
 			value := c.data[r.Offset] + r.Uint16()
+			c.bound(bounds, r.Index(), value)
+			c.fill[r.Offset>>6] |= 1 << (r.Offset & 0x3f)
 			c.data[r.Offset] = value
 			r.SwapUint16(value)
 
 		case commit.Delete:
+			c.unbound(bounds, r.Index())
 			c.fill.Remove(r.Index())
 		}
 	}
 }
 
+// bound updates the extremes of a chunk with a value about to be stored at the index. If the
+// value replaces one of the extremes with a less extreme one, the extremes become stale.
+func (c *uint16Column) bound(b *uint16Bounds, idx uint32, value uint16) {
+	if b.stale {
+		return
+	}
+
+	if c.fill.Contains(idx) {
+		if prev := c.data[idx]; (prev == b.min && value > prev) || (prev == b.max && value < prev) {
+			b.stale = true
+			return
+		}
+	}
+
+	switch {
+	case !b.known:
+		b.min, b.max, b.known = value, value, true
+	case value < b.min:
+		b.min = value
+	case value > b.max:
+		b.max = value
+	}
+}
+
+// unbound marks the extremes of a chunk as stale if the value about to be removed is one of them
+func (c *uint16Column) unbound(b *uint16Bounds, idx uint32) {
+	if !b.stale && c.fill.Contains(idx) {
+		prev := c.data[idx]
+		b.stale = prev == b.min || prev == b.max
+	}
+}
+
+// bounds returns the minimum and maximum values of the column, combining the extremes of
+// each chunk. The latch function must hold the latch of the chunk while calling back, for
+// writing if it is exclusive, since only the stale chunks are recomputed.
+func (c *uint16Column) bounds(chunks int, latch func(chunk commit.Chunk, exclusive bool, fn func())) (min, max interface{}, ok bool) {
+	var lo, hi uint16
+	for chunk := commit.Chunk(0); int(chunk) < chunks; chunk++ {
+		var b uint16Bounds
+		latch(chunk, false, func() {
+			if int(chunk) < len(c.extremes) {
+				b = c.extremes[chunk]
+			}
+		})
+
+		if b.stale {
+			latch(chunk, true, func() {
+				b = c.rebound(chunk)
+			})
+		}
+
+		switch {
+		case !b.known:
+			continue
+		case !ok:
+			lo, hi, ok = b.min, b.max, true
+		default:
+			if b.min < lo {
+				lo = b.min
+			}
+			if b.max > hi {
+				hi = b.max
+			}
+		}
+	}
+
+	if !ok {
+		return nil, nil, false
+	}
+	return lo, hi, true
+}
+
+// rebound recomputes the extremes of a chunk, if they are still stale. The caller must hold
+// the write latch of the chunk.
+func (c *uint16Column) rebound(chunk commit.Chunk) uint16Bounds {
+	b := &c.extremes[chunk]
+	if b.stale {
+		b.known, b.stale = false, false
+		chunk.Range(c.fill, func(idx uint32) {
+			c.bound(b, idx, c.data[idx])
+		})
+	}
+	return *b
+}
+
 // Contains checks whether the column has a value at a specified index.
 func (c *uint16Column) Contains(idx uint32) bool {
 	return c.fill.Contains(idx)
@@ -1502,6 +2570,18 @@ func (c *uint16Column) FilterUint64(offset uint32, index bitmap.Bitmap, predicat
 	})
 }
 
+// sum computes the sum of the values present at the indices of the bitmap, which
+// starts at the specified offset.
+func (c *uint16Column) sum(offset uint32, index bitmap.Bitmap) (sum uint16) {
+	index.Range(func(idx uint32) {
+		idx = offset + idx
+		if idx < uint32(len(c.data)) && c.fill.Contains(idx) {
+			sum += c.data[idx]
+		}
+	})
+	return
+}
+
 // Snapshot writes the entire column into the specified destination buffer
 func (c *uint16Column) Snapshot(chunk commit.Chunk, dst *commit.Buffer) {
 	chunk.Range(c.fill, func(idx uint32) {
@@ -1513,10 +2593,12 @@ func (c *uint16Column) Snapshot(chunk commit.Chunk, dst *commit.Buffer) {
 type uint16Reader struct {
 	cursor *uint32
 	reader *uint16Column
+	stats  *columnStats
 }
 
 // Get loads the value at the current transaction cursor
 func (s uint16Reader) Get() (uint16, bool) {
+	s.stats.read()
 	return s.reader.load(*s.cursor)
 }
 
@@ -1535,6 +2617,7 @@ func uint16ReaderFor(txn *Txn, columnName string) uint16Reader {
 	return uint16Reader{
 		cursor: &txn.cursor,
 		reader: reader,
+		stats:  column.stats,
 	}
 }
 
@@ -1546,11 +2629,13 @@ type uint16Writer struct {
 
 // Set sets the value at the current transaction cursor
 func (s uint16Writer) Set(value uint16) {
+	s.stats.write()
 	s.writer.PutUint16(*s.cursor, value)
 }
 
 // Add atomically adds a delta to the value at the current transaction cursor
 func (s uint16Writer) Add(delta uint16) {
+	s.stats.write()
 	s.writer.AddUint16(*s.cursor, delta)
 }
 
@@ -1562,12 +2647,32 @@ func (txn *Txn) Uint16(columnName string) uint16Writer {
 	}
 }
 
+// SumUint16 computes the sum of the uint16 column over the objects matching the query,
+// without boxing the values. It panics if the column is not of type uint16.
+func (txn *Txn) SumUint16(columnName string) (sum uint16) {
+	column := uint16ReaderFor(txn, columnName).reader
+	txn.initialize()
+	txn.rangeRead(func(offset uint32, index bitmap.Bitmap) {
+		sum += column.sum(offset, index)
+	})
+	return
+}
+
 // --------------------------- Uint32s ----------------------------
 
 // uint32Column represents a generic column
 type uint32Column struct {
-	fill bitmap.Bitmap // The fill-list
-	data []uint32      // The actual values
+	fill     bitmap.Bitmap  // The fill-list
+	data     []uint32       // The actual values
+	extremes []uint32Bounds // The extremes of the values of each chunk
+}
+
+// uint32Bounds represents the extremes of the values of a chunk
+type uint32Bounds struct {
+	min   uint32 // The minimum value, if known
+	max   uint32 // The maximum value, if known
+	known bool   // Whether the extremes are known
+	stale bool   // Whether the extremes need to be recomputed
 }
 
 // makeUint32s creates a new vector for Uint32s
@@ -1580,6 +2685,10 @@ func makeUint32s() Column {
 
 // Grow grows the size of the column until we have enough to store
 func (c *uint32Column) Grow(idx uint32) {
+	for len(c.extremes) <= int(commit.ChunkAt(idx)) {
+		c.extremes = append(c.extremes, uint32Bounds{})
+	}
+
 	if idx < uint32(len(c.data)) {
 		return
 	}
@@ -1596,28 +2705,124 @@ func (c *uint32Column) Grow(idx uint32) {
 	c.data = clone
 }
 
+// compact reclaims the spare capacity of the column
+func (c *uint32Column) compact() {
+	c.fill = clip(c.fill)
+	c.data = clip(c.data)
+}
+
 // Apply applies a set of operations to the column.
 func (c *uint32Column) Apply(r *commit.Reader) {
 	for r.Next() {
+		bounds := &c.extremes[commit.ChunkAt(r.Index())]
 		switch r.Type {
 		case commit.Put:
+			value := r.Uint32()
+			c.bound(bounds, r.Index(), value)
 			c.fill[r.Offset>>6] |= 1 << (r.Offset & 0x3f)
-			c.data[r.Offset] = r.Uint32()
+			c.data[r.Offset] = value
 
 		// If this is an atomic increment/decrement, we need to change the operation to
 		// the final value, since after this update an index needs to be recalculated.
 		case commit.Add:
-			c.fill[r.Offset>>6] |= 1 << (r.Offset & 0x3f)
 			value := c.data[r.Offset] + r.Uint32()
+			c.bound(bounds, r.Index(), value)
+			c.fill[r.Offset>>6] |= 1 << (r.Offset & 0x3f)
 			c.data[r.Offset] = value
 			r.SwapUint32(value)
 
 		case commit.Delete:
+			c.unbound(bounds, r.Index())
 			c.fill.Remove(r.Index())
 		}
 	}
 }
 
+// bound updates the extremes of a chunk with a value about to be stored at the index. If the
+// value replaces one of the extremes with a less extreme one, the extremes become stale.
+func (c *uint32Column) bound(b *uint32Bounds, idx uint32, value uint32) {
+	if b.stale {
+		return
+	}
+
+	if c.fill.Contains(idx) {
+		if prev := c.data[idx]; (prev == b.min && value > prev) || (prev == b.max && value < prev) {
+			b.stale = true
+			return
+		}
+	}
+
+	switch {
+	case !b.known:
+		b.min, b.max, b.known = value, value, true
+	case value < b.min:
+		b.min = value
+	case value > b.max:
+		b.max = value
+	}
+}
+
+// unbound marks the extremes of a chunk as stale if the value about to be removed is one of them
+func (c *uint32Column) unbound(b *uint32Bounds, idx uint32) {
+	if !b.stale && c.fill.Contains(idx) {
+		prev := c.data[idx]
+		b.stale = prev == b.min || prev == b.max
+	}
+}
+
+// bounds returns the minimum and maximum values of the column, combining the extremes of
+// each chunk. The latch function must hold the latch of the chunk while calling back, for
+// writing if it is exclusive, since only the stale chunks are recomputed.
+func (c *uint32Column) bounds(chunks int, latch func(chunk commit.Chunk, exclusive bool, fn func())) (min, max interface{}, ok bool) {
+	var lo, hi uint32
+	for chunk := commit.Chunk(0); int(chunk) < chunks; chunk++ {
+		var b uint32Bounds
+		latch(chunk, false, func() {
+			if int(chunk) < len(c.extremes) {
+				b = c.extremes[chunk]
+			}
+		})
+
+		if b.stale {
+			latch(chunk, true, func() {
+				b = c.rebound(chunk)
+			})
+		}
+
+		switch {
+		case !b.known:
+			continue
+		case !ok:
+			lo, hi, ok = b.min, b.max, true
+		default:
+			if b.min < lo {
+				lo = b.min
+			}
+			if b.max > hi {
+				hi = b.max
+			}
+		}
+	}
+
+	if !ok {
+		return nil, nil, false
+	}
+	return lo, hi, true
+}
+
+// rebound recomputes the extremes of a chunk, if they are still stale. The caller must hold
+// the write latch of the chunk.
+func (c *uint32Column) rebound(chunk commit.Chunk) uint32Bounds {
+	b := &c.extremes[chunk]
+	if b.stale {
+		b.known, b.stale = false, false
+		chunk.Range(c.fill, func(idx uint32) {
+			c.bound(b, idx, c.data[idx])
+		})
+	}
+	return *b
+}
+
 // Contains checks whether the column has a value at a specified index.
 func (c *uint32Column) Contains(idx uint32) bool {
 	return c.fill.Contains(idx)
@@ -1696,6 +2901,18 @@ func (c *uint32Column) FilterUint64(offset uint32, index bitmap.Bitmap, predicat
 	})
 }
 
+// sum computes the sum of the values present at the indices of the bitmap, which
+// starts at the specified offset.
+func (c *uint32Column) sum(offset uint32, index bitmap.Bitmap) (sum uint32) {
+	index.Range(func(idx uint32) {
+		idx = offset + idx
+		if idx < uint32(len(c.data)) && c.fill.Contains(idx) {
+			sum += c.data[idx]
+		}
+	})
+	return
+}
+
 // Snapshot writes the entire column into the specified destination buffer
 func (c *uint32Column) Snapshot(chunk commit.Chunk, dst *commit.Buffer) {
 	chunk.Range(c.fill, func(idx uint32) {
@@ -1707,10 +2924,12 @@ func (c *uint32Column) Snapshot(chunk commit.Chunk, dst *commit.Buffer) {
 type uint32Reader struct {
 	cursor *uint32
 	reader *uint32Column
+	stats  *columnStats
 }
 
 // Get loads the value at the current transaction cursor
 func (s uint32Reader) Get() (uint32, bool) {
+	s.stats.read()
 	return s.reader.load(*s.cursor)
 }
 
@@ -1729,6 +2948,7 @@ func uint32ReaderFor(txn *Txn, columnName string) uint32Reader {
 	return uint32Reader{
 		cursor: &txn.cursor,
 		reader: reader,
+		stats:  column.stats,
 	}
 }
 
@@ -1740,11 +2960,13 @@ type uint32Writer struct {
 
 // Set sets the value at the current transaction cursor
 func (s uint32Writer) Set(value uint32) {
+	s.stats.write()
 	s.writer.PutUint32(*s.cursor, value)
 }
 
 // Add atomically adds a delta to the value at the current transaction cursor
 func (s uint32Writer) Add(delta uint32) {
+	s.stats.write()
 	s.writer.AddUint32(*s.cursor, delta)
 }
 
@@ -1756,12 +2978,32 @@ func (txn *Txn) Uint32(columnName string) uint32Writer {
 	}
 }
 
+// SumUint32 computes the sum of the uint32 column over the objects matching the query,
+// without boxing the values. It panics if the column is not of type uint32.
+func (txn *Txn) SumUint32(columnName string) (sum uint32) {
+	column := uint32ReaderFor(txn, columnName).reader
+	txn.initialize()
+	txn.rangeRead(func(offset uint32, index bitmap.Bitmap) {
+		sum += column.sum(offset, index)
+	})
+	return
+}
+
 // --------------------------- Uint64s ----------------------------
 
 // uint64Column represents a generic column
 type uint64Column struct {
-	fill bitmap.Bitmap // The fill-list
-	data []uint64      // The actual values
+	fill     bitmap.Bitmap  // The fill-list
+	data     []uint64       // The actual values
+	extremes []uint64Bounds // The extremes of the values of each chunk
+}
+
+// uint64Bounds represents the extremes of the values of a chunk
+type uint64Bounds struct {
+	min   uint64 // The minimum value, if known
+	max   uint64 // The maximum value, if known
+	known bool   // Whether the extremes are known
+	stale bool   // Whether the extremes need to be recomputed
 }
 
 // makeUint64s creates a new vector for Uint64s
@@ -1774,6 +3016,10 @@ func makeUint64s() Column {
 
 // Grow grows the size of the column until we have enough to store
 func (c *uint64Column) Grow(idx uint32) {
+	for len(c.extremes) <= int(commit.ChunkAt(idx)) {
+		c.extremes = append(c.extremes, uint64Bounds{})
+	}
+
 	if idx < uint32(len(c.data)) {
 		return
 	}
@@ -1790,28 +3036,124 @@ func (c *uint64Column) Grow(idx uint32) {
 	c.data = clone
 }
 
+// compact reclaims the spare capacity of the column
+func (c *uint64Column) compact() {
+	c.fill = clip(c.fill)
+	c.data = clip(c.data)
+}
+
 // Apply applies a set of operations to the column.
 func (c *uint64Column) Apply(r *commit.Reader) {
 	for r.Next() {
+		bounds := &c.extremes[commit.ChunkAt(r.Index())]
 		switch r.Type {
 		case commit.Put:
+			value := r.Uint64()
+			c.bound(bounds, r.Index(), value)
 			c.fill[r.Offset>>6] |= 1 << (r.Offset & 0x3f)
-			c.data[r.Offset] = r.Uint64()
+			c.data[r.Offset] = value
 
 		// If this is an atomic increment/decrement, we need to change the operation to
 		// the final value, since after this update an index needs to be recalculated.
 		case commit.Add:
-			c.fill[r.Offset>>6] |= 1 << (r.Offset & 0x3f)
 			value := c.data[r.Offset] + r.Uint64()
+			c.bound(bounds, r.Index(), value)
+			c.fill[r.Offset>>6] |= 1 << (r.Offset & 0x3f)
 			c.data[r.Offset] = value
 			r.SwapUint64(value)
 
 		case commit.Delete:
+			c.unbound(bounds, r.Index())
 			c.fill.Remove(r.Index())
 		}
 	}
 }
 
+// bound updates the extremes of a chunk with a value about to be stored at the index. If the
+// value replaces one of the extremes with a less extreme one, the extremes become stale.
+func (c *uint64Column) bound(b *uint64Bounds, idx uint32, value uint64) {
+	if b.stale {
+		return
+	}
+
+	if c.fill.Contains(idx) {
+		if prev := c.data[idx]; (prev == b.min && value > prev) || (prev == b.max && value < prev) {
+			b.stale = true
+			return
+		}
+	}
+
+	switch {
+	case !b.known:
+		b.min, b.max, b.known = value, value, true
+	case value < b.min:
+		b.min = value
+	case value > b.max:
+		b.max = value
+	}
+}
+
+// unbound marks the extremes of a chunk as stale if the value about to be removed is one of them
+func (c *uint64Column) unbound(b *uint64Bounds, idx uint32) {
+	if !b.stale && c.fill.Contains(idx) {
+		prev := c.data[idx]
+		b.stale = prev == b.min || prev == b.max
+	}
+}
+
+// bounds returns the minimum and maximum values of the column, combining the extremes of
+// each chunk. The latch function must hold the latch of the chunk while calling back, for
+// writing if it is exclusive, since only the stale chunks are recomputed.
+func (c *uint64Column) bounds(chunks int, latch func(chunk commit.Chunk, exclusive bool, fn func())) (min, max interface{}, ok bool) {
+	var lo, hi uint64
+	for chunk := commit.Chunk(0); int(chunk) < chunks; chunk++ {
+		var b uint64Bounds
+		latch(chunk, false, func() {
+			if int(chunk) < len(c.extremes) {
+				b = c.extremes[chunk]
+			}
+		})
+
+		if b.stale {
+			latch(chunk, true, func() {
+				b = c.rebound(chunk)
+			})
+		}
+
+		switch {
+		case !b.known:
+			continue
+		case !ok:
+			lo, hi, ok = b.min, b.max, true
+		default:
+			if b.min < lo {
+				lo = b.min
+			}
+			if b.max > hi {
+				hi = b.max
+			}
+		}
+	}
+
+	if !ok {
+		return nil, nil, false
+	}
+	return lo, hi, true
+}
+
+// rebound recomputes the extremes of a chunk, if they are still stale. The caller must hold
+// the write latch of the chunk.
+func (c *uint64Column) rebound(chunk commit.Chunk) uint64Bounds {
+	b := &c.extremes[chunk]
+	if b.stale {
+		b.known, b.stale = false, false
+		chunk.Range(c.fill, func(idx uint32) {
+			c.bound(b, idx, c.data[idx])
+		})
+	}
+	return *b
+}
+
 // Contains checks whether the column has a value at a specified index.
 func (c *uint64Column) Contains(idx uint32) bool {
 	return c.fill.Contains(idx)
@@ -1890,6 +3232,18 @@ func (c *uint64Column) FilterUint64(offset uint32, index bitmap.Bitmap, predicat
 	})
 }
 
+// sum computes the sum of the values present at the indices of the bitmap, which
+// starts at the specified offset.
+func (c *uint64Column) sum(offset uint32, index bitmap.Bitmap) (sum uint64) {
+	index.Range(func(idx uint32) {
+		idx = offset + idx
+		if idx < uint32(len(c.data)) && c.fill.Contains(idx) {
+			sum += c.data[idx]
+		}
+	})
+	return
+}
+
 // Snapshot writes the entire column into the specified destination buffer
 func (c *uint64Column) Snapshot(chunk commit.Chunk, dst *commit.Buffer) {
 	chunk.Range(c.fill, func(idx uint32) {
@@ -1901,10 +3255,12 @@ func (c *uint64Column) Snapshot(chunk commit.Chunk, dst *commit.Buffer) {
 type uint64Reader struct {
 	cursor *uint32
 	reader *uint64Column
+	stats  *columnStats
 }
 
 // Get loads the value at the current transaction cursor
 func (s uint64Reader) Get() (uint64, bool) {
+	s.stats.read()
 	return s.reader.load(*s.cursor)
 }
 
@@ -1923,6 +3279,7 @@ func uint64ReaderFor(txn *Txn, columnName string) uint64Reader {
 	return uint64Reader{
 		cursor: &txn.cursor,
 		reader: reader,
+		stats:  column.stats,
 	}
 }
 
@@ -1934,11 +3291,13 @@ type uint64Writer struct {
 
 // Set sets the value at the current transaction cursor
 func (s uint64Writer) Set(value uint64) {
+	s.stats.write()
 	s.writer.PutUint64(*s.cursor, value)
 }
 
 // Add atomically adds a delta to the value at the current transaction cursor
 func (s uint64Writer) Add(delta uint64) {
+	s.stats.write()
 	s.writer.AddUint64(*s.cursor, delta)
 }
 
@@ -1949,3 +3308,14 @@ func (txn *Txn) Uint64(columnName string) uint64Writer {
 		writer:       txn.bufferFor(columnName),
 	}
 }
+
+// SumUint64 computes the sum of the uint64 column over the objects matching the query,
+// without boxing the values. It panics if the column is not of type uint64.
+func (txn *Txn) SumUint64(columnName string) (sum uint64) {
+	column := uint64ReaderFor(txn, columnName).reader
+	txn.initialize()
+	txn.rangeRead(func(offset uint32, index bitmap.Bitmap) {
+		sum += column.sum(offset, index)
+	})
+	return
+}
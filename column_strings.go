@@ -6,6 +6,7 @@ package column
 import (
 	"fmt"
 	"math"
+	"sync/atomic"
 
 	"github.com/kelindar/bitmap"
 	"github.com/kelindar/column/commit"
@@ -19,6 +20,7 @@ var _ Textual = new(columnEnum)
 
 // columnEnum represents a string column
 type columnEnum struct {
+	size int64         // The approximate size of the dictionary, in bytes
 	fill bitmap.Bitmap // The fill-list
 	locs []uint32      // The list of locations
 	seek *intmap.Sync  // The hash->location table
@@ -60,7 +62,7 @@ func (c *columnEnum) Apply(r *commit.Reader) {
 		case commit.Put:
 			// Set the value at the index
 			c.fill[r.Offset>>6] |= 1 << (r.Offset & 0x3f)
-			c.locs[r.Offset] = c.findOrAdd(r.Bytes())
+			c.locs[r.Offset] = c.findOrAdd(r.BytesRef())
 
 		case commit.Delete:
 			c.fill.Remove(r.Index())
@@ -70,10 +72,32 @@ func (c *columnEnum) Apply(r *commit.Reader) {
 	}
 }
 
+// compact rebuilds the dictionary with the strings which are still referenced and reclaims
+// the spare capacity of the column
+func (c *columnEnum) compact() {
+	data, remap := c.data, make(map[uint32]uint32, len(c.data))
+	c.data = make([]string, 0, len(c.data))
+	c.seek = intmap.NewSync(len(data), .95)
+	atomic.StoreInt64(&c.size, 0)
+	c.fill.Range(func(idx uint32) {
+		at, ok := remap[c.locs[idx]]
+		if !ok {
+			at = c.findOrAdd([]byte(data[c.locs[idx]]))
+			remap[c.locs[idx]] = at
+		}
+		c.locs[idx] = at
+	})
+
+	c.fill = clip(c.fill)
+	c.locs = clip(c.locs)
+	c.data = clip(c.data)
+}
+
 // Search for the string or adds it and returns the offset
 func (c *columnEnum) findOrAdd(v []byte) uint32 {
 	target := uint32(xxh3.Hash(v))
 	at, _ := c.seek.LoadOrStore(target, func() uint32 {
+		atomic.AddInt64(&c.size, int64(len(v))+dictEntrySize)
 		c.data = append(c.data, string(v))
 		return uint32(len(c.data)) - 1
 	})
@@ -149,10 +173,12 @@ func (c *columnEnum) Snapshot(chunk commit.Chunk, dst *commit.Buffer) {
 type enumReader struct {
 	cursor *uint32
 	reader *columnEnum
+	stats  *columnStats
 }
 
 // Get loads the value at the current transaction cursor
 func (s enumReader) Get() (string, bool) {
+	s.stats.read()
 	return s.reader.LoadString(*s.cursor)
 }
 
@@ -171,6 +197,7 @@ func enumReaderFor(txn *Txn, columnName string) enumReader {
 	return enumReader{
 		cursor: &txn.cursor,
 		reader: reader,
+		stats:  column.stats,
 	}
 }
 
@@ -182,6 +209,7 @@ type enumSlice struct {
 
 // Set sets the value at the current transaction cursor
 func (s enumSlice) Set(value string) {
+	s.stats.write()
 	s.writer.PutString(commit.Put, *s.cursor, value)
 }
 
@@ -237,13 +265,25 @@ func (c *columnString) Apply(r *commit.Reader) {
 		switch r.Type {
 		case commit.Put:
 			c.fill[r.Offset>>6] |= 1 << (r.Offset & 0x3f)
-			c.data[r.Offset] = string(r.Bytes())
+			c.data[r.Offset] = string(r.BytesRef())
 		case commit.Delete:
 			c.fill.Remove(r.Index())
 		}
 	}
 }
 
+// compact releases the strings which were deleted and reclaims the spare capacity of the column
+func (c *columnString) compact() {
+	for i := range c.data {
+		if !c.fill.Contains(uint32(i)) {
+			c.data[i] = ""
+		}
+	}
+
+	c.fill = clip(c.fill)
+	c.data = clip(c.data)
+}
+
 // Value retrieves a value at a specified index
 func (c *columnString) Value(idx uint32) (v interface{}, ok bool) {
 	if idx < uint32(len(c.data)) && c.fill.Contains(idx) {
@@ -290,10 +330,12 @@ func (c *columnString) Snapshot(chunk commit.Chunk, dst *commit.Buffer) {
 type stringReader struct {
 	cursor *uint32
 	reader *columnString
+	stats  *columnStats
 }
 
 // Get loads the value at the current transaction cursor
 func (s stringReader) Get() (string, bool) {
+	s.stats.read()
 	return s.reader.LoadString(*s.cursor)
 }
 
@@ -312,6 +354,7 @@ func stringReaderFor(txn *Txn, columnName string) stringReader {
 	return stringReader{
 		cursor: &txn.cursor,
 		reader: reader,
+		stats:  column.stats,
 	}
 }
 
@@ -323,6 +366,7 @@ type stringWriter struct {
 
 // Set sets the value at the current transaction cursor
 func (s stringWriter) Set(value string) {
+	s.stats.write()
 	s.writer.PutString(commit.Put, *s.cursor, value)
 }
 
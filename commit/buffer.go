@@ -4,8 +4,13 @@
 package commit
 
 import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
 	"fmt"
 	"math"
+	"reflect"
+	"time"
 
 	"github.com/kelindar/bitmap"
 )
@@ -17,6 +22,7 @@ const (
 	size8    = 3 << 4 // 8 bytes in size
 	isNext   = 1 << 7 // is immediate next
 	isString = 1 << 6 // is variable-size string
+	isRepeat = 1 << 3 // is a repeat of the previous value
 )
 
 // --------------------------- Operation Type ----------------------------
@@ -38,12 +44,28 @@ const (
 
 // Buffer represents a buffer of delta operations.
 type Buffer struct {
-	last   int32    // The last offset written
-	chunk  Chunk    // The current chunk
-	buffer []byte   // The destination buffer
-	chunks []header // The offsets of chunks
-	_      [8]byte  // padding
-	Column string   // The column for the queue
+	last   int32        // The last offset written
+	chunk  Chunk        // The current chunk
+	buffer []byte       // The destination buffer
+	chunks []header     // The offsets of chunks
+	extra  *bufferExtra // The optional schema header and encoding state
+	Column string       // The column for the queue
+}
+
+// bufferExtra represents the optional state of a buffer, allocated only when required.
+type bufferExtra struct {
+	schema []ColumnDef // The schema header, persisted along with the buffer
+	runs   bool        // Whether the repeated values are run-length encoded
+	at     int32       // The offset of the last value written
+	end    int         // The end of the last record written, to detect other writes
+	head   byte        // The header of the last value written, without the neighbour flag
+	i0, i1 int         // The start and end of the last value written
+}
+
+// ColumnDef represents a column definition in the schema header of a buffer.
+type ColumnDef struct {
+	Name string       // The name of the column
+	Kind reflect.Kind // The kind of the values
 }
 
 // header represents a chunk metadata header.
@@ -51,6 +73,17 @@ type header struct {
 	Chunk Chunk  // The chunk number
 	Start uint32 // The offset at which the chunk starts in the buffer
 	Value uint32 // The previous offset value for delta
+	Count uint32 // The number of records in the chunk, or uncounted if decoded
+}
+
+// uncounted marks the count of a decoded chunk header, until its records are counted
+const uncounted = math.MaxUint32
+
+// ChunkMeta represents the metadata of a chunk written in the buffer.
+type ChunkMeta struct {
+	Chunk Chunk // The chunk number
+	Count int   // The number of records in the chunk
+	Size  int   // The size of the chunk in bytes
 }
 
 // NewBuffer creates a new queue to store individual operations.
@@ -68,13 +101,70 @@ func (b *Buffer) Clone() *Buffer {
 
 	chunks := make([]header, 0, len(b.chunks))
 	chunks = append(chunks, b.chunks...)
-	return &Buffer{
+	clone := &Buffer{
 		Column: b.Column,
 		buffer: buffer,
 		chunks: chunks,
 		last:   b.last,
 		chunk:  b.chunk,
 	}
+
+	if b.extra != nil {
+		extra := *b.extra
+		extra.schema = append([]ColumnDef(nil), b.extra.schema...)
+		clone.extra = &extra
+	}
+	return clone
+}
+
+// Merge appends the records of the other buffer after the ones of this buffer, so that
+// reading the merged buffer yields the records of both buffers in order. The offsets of the
+// records are preserved rather than rebased, hence records of both buffers at the same offset
+// are kept and the ones of the other buffer are read last. The records are encoded again
+// relative to the ones of this buffer, which keeps its column and schema.
+func (b *Buffer) Merge(other *Buffer) {
+	rangeRecords(other, func(idx uint32, v record) {
+		b.putRecord(idx, v)
+	})
+}
+
+// WriteSchema sets the optional schema header of the buffer, which describes the columns
+// and is persisted along with the buffer. An empty schema removes the header.
+func (b *Buffer) WriteSchema(cols []ColumnDef) {
+	if len(cols) == 0 {
+		if b.extra != nil {
+			b.extra.schema = nil
+		}
+		return
+	}
+
+	if b.extra == nil {
+		b.extra = new(bufferExtra)
+	}
+
+	b.extra.schema = make([]ColumnDef, len(cols))
+	copy(b.extra.schema, cols)
+}
+
+// schemaOf returns the schema header of the buffer, or nil if it does not have one
+func (b *Buffer) schemaOf() *[]ColumnDef {
+	if b.extra == nil || len(b.extra.schema) == 0 {
+		return nil
+	}
+	return &b.extra.schema
+}
+
+// EncodeRuns enables or disables the run-length encoding of repeated values. When enabled,
+// a value put at the offset right after the previous value put, and equal to it, is written
+// as a single byte rather than repeating the value. Readers decode these transparently and
+// still yield a record for every offset. The encoding is disabled when the buffer is reset.
+func (b *Buffer) EncodeRuns(enabled bool) {
+	if b.extra == nil {
+		b.extra = new(bufferExtra)
+	}
+
+	b.extra.runs = enabled
+	b.extra.end = -1
 }
 
 // Reset resets the queue so it can be reused.
@@ -83,14 +173,37 @@ func (b *Buffer) Reset(column string) {
 	b.chunk = math.MaxUint32
 	b.buffer = b.buffer[:0]
 	b.chunks = b.chunks[:0]
+	b.extra = nil
 	b.Column = column
 }
 
+// Clear removes all of the records so the buffer can be reused, while keeping its column,
+// schema header and encoding, as well as the memory allocated for the records.
+func (b *Buffer) Clear() {
+	b.last = 0
+	b.chunk = math.MaxUint32
+	b.buffer = b.buffer[:0]
+	b.chunks = b.chunks[:0]
+	if b.extra != nil {
+		b.extra.end = -1 // The last value written is gone
+	}
+}
+
 // IsEmpty returns whether the buffer is empty or not.
 func (b *Buffer) IsEmpty() bool {
 	return len(b.buffer) == 0
 }
 
+// Len returns the number of bytes written into the buffer.
+func (b *Buffer) Len() int {
+	return len(b.buffer)
+}
+
+// Cap returns the number of bytes allocated for the buffer.
+func (b *Buffer) Cap() int {
+	return cap(b.buffer)
+}
+
 // Range iterates over the chunks present in the buffer
 func (b *Buffer) RangeChunks(fn func(chunk Chunk)) {
 	for _, c := range b.chunks {
@@ -98,6 +211,80 @@ func (b *Buffer) RangeChunks(fn func(chunk Chunk)) {
 	}
 }
 
+// ChunkInfo returns the metadata of every chunk present in the buffer, without iterating
+// over the records, except on the first call for a decoded buffer, which counts them. If a
+// chunk was written non-contiguously, it appears multiple times.
+func (b *Buffer) ChunkInfo() []ChunkMeta {
+	out := make([]ChunkMeta, 0, len(b.chunks))
+	for i, c := range b.chunks {
+		until := uint32(len(b.buffer))
+		if len(b.chunks) > i+1 {
+			until = b.chunks[i+1].Start
+		}
+
+		out = append(out, ChunkMeta{
+			Chunk: c.Chunk,
+			Count: b.countOf(i),
+			Size:  int(until - c.Start),
+		})
+	}
+	return out
+}
+
+// rechunk recomputes the chunk headers for the specified chunk shift. Since every record is
+// encoded relative to the previous one, only the headers depend on the size of a chunk and the
+// records themselves remain untouched.
+func (b *Buffer) rechunk(shift uint8) {
+	r := NewReader()
+	chunks := make([]header, 0, len(b.chunks))
+	for i, c := range b.chunks {
+		buffer := b.buffer[c.Start:]
+		if len(b.chunks) > i+1 {
+			buffer = b.buffer[c.Start:b.chunks[i+1].Start]
+		}
+
+		// Start a new chunk whenever the chunk of the record changes
+		r.use(buffer)
+		r.Offset = int32(c.Value)
+		for prev, head := r.Offset, 0; r.Next(); prev, head = r.Offset, r.head {
+			chunk := Chunk(uint32(r.Offset) >> shift)
+			if n := len(chunks); n == 0 || chunks[n-1].Chunk != chunk {
+				chunks = append(chunks, header{
+					Chunk: chunk,
+					Start: c.Start + uint32(head),
+					Value: uint32(prev),
+				})
+			}
+
+			chunks[len(chunks)-1].Count++
+		}
+	}
+
+	b.chunks = chunks
+	if len(chunks) > 0 {
+		b.chunk = chunks[len(chunks)-1].Chunk
+	}
+}
+
+// countOf returns the number of records of a chunk. The records of a decoded chunk header
+// are only counted when this is first called, since it requires to iterate over them.
+func (b *Buffer) countOf(i int) int {
+	c := &b.chunks[i]
+	if c.Count == uncounted {
+		buffer := b.buffer[c.Start:]
+		if len(b.chunks) > i+1 {
+			buffer = b.buffer[c.Start:b.chunks[i+1].Start]
+		}
+
+		r := NewReader()
+		c.Count = 0
+		for r.use(buffer); r.Next(); {
+			c.Count++
+		}
+	}
+	return int(c.Count)
+}
+
 // PutAny appends a supported value onto the buffer.
 func (b *Buffer) PutAny(op OpType, idx uint32, value interface{}) {
 	switch v := value.(type) {
@@ -131,6 +318,8 @@ func (b *Buffer) PutAny(op OpType, idx uint32, value interface{}) {
 		b.PutUint64(idx, uint64(v))
 	case bool:
 		b.PutBool(idx, v)
+	case time.Time:
+		b.PutTime(idx, v)
 	case nil:
 		b.PutOperation(op, idx)
 	default:
@@ -138,6 +327,51 @@ func (b *Buffer) PutAny(op OpType, idx uint32, value interface{}) {
 	}
 }
 
+// KindOf returns the kind of column the value is written for by PutAny, which can be used to
+// validate values before writing them. Narrow integers are widened, binary values are written
+// as strings, times as int64 and nil is written as an operation without a value, hence has an
+// invalid kind. If PutAny does not support the type of the value, ok will be false.
+func KindOf(value interface{}) (kind reflect.Kind, ok bool) {
+	switch value.(type) {
+	case uint64:
+		return reflect.Uint64, true
+	case uint32:
+		return reflect.Uint32, true
+	case uint16:
+		return reflect.Uint16, true
+	case uint8:
+		return reflect.Uint16, true
+	case int64:
+		return reflect.Int64, true
+	case int32:
+		return reflect.Int32, true
+	case int16:
+		return reflect.Int16, true
+	case int8:
+		return reflect.Int16, true
+	case string:
+		return reflect.String, true
+	case []byte:
+		return reflect.String, true
+	case float32:
+		return reflect.Float32, true
+	case float64:
+		return reflect.Float64, true
+	case int:
+		return reflect.Int, true
+	case uint:
+		return reflect.Uint, true
+	case bool:
+		return reflect.Bool, true
+	case time.Time:
+		return reflect.Int64, true
+	case nil:
+		return reflect.Invalid, true
+	default:
+		return reflect.Invalid, false
+	}
+}
+
 // --------------------------- Numbers ----------------------------
 
 // PutUint64 appends an uint64 value.
@@ -266,6 +500,11 @@ func (b *Buffer) PutOperation(op OpType, idx uint32) {
 	}
 }
 
+// PutDelete appends a deletion of the value at the index, which carries no value.
+func (b *Buffer) PutDelete(idx uint32) {
+	b.PutOperation(Delete, idx)
+}
+
 // PutBool appends a boolean value.
 func (b *Buffer) PutBool(idx uint32, value bool) {
 
@@ -278,8 +517,14 @@ func (b *Buffer) PutBool(idx uint32, value bool) {
 	b.PutOperation(op, idx)
 }
 
-// PutBytes appends a binary value.
+// PutBytes appends a binary value. The value is copied inline into the single backing
+// slice of the buffer, next to its length, hence once the buffer has grown, appending
+// variable-width values does not allocate and readers slice them without copying.
 func (b *Buffer) PutBytes(op OpType, idx uint32, value []byte) {
+	if b.extra != nil && b.writeRepeat(op, idx, byte(op)|size2|isString, value) {
+		return
+	}
+
 	delta := b.writeChunk(idx)
 	length := len(value) // max 65K slices
 	switch delta {
@@ -301,11 +546,39 @@ func (b *Buffer) PutBytes(op OpType, idx uint32, value []byte) {
 	}
 }
 
+// PutJSON appends a raw JSON value. The value is validated prior to being appended
+// and if it is not well-formed, an error is returned and nothing is written.
+func (b *Buffer) PutJSON(op OpType, idx uint32, value json.RawMessage) error {
+	if !json.Valid(value) {
+		return fmt.Errorf("column: unable to put, invalid JSON value at %d", idx)
+	}
+
+	b.PutBytes(op, idx, value)
+	return nil
+}
+
 // PutString appends a string value.
 func (b *Buffer) PutString(op OpType, idx uint32, value string) {
 	b.PutBytes(op, idx, toBytes(value))
 }
 
+// PutTime appends a time as the number of nanoseconds elapsed since the Unix epoch, written
+// as an int64 and read back with Reader.Time. The monotonic clock reading and the location
+// are not kept, and the times which can not be represented this way, such as the zero time,
+// are not read back the same.
+func (b *Buffer) PutTime(idx uint32, value time.Time) {
+	b.PutInt64(idx, value.UnixNano())
+}
+
+// PutUint128 appends a 128-bit unsigned integer, given as its high and low 64 bits. It is
+// written as a 16-byte binary value, in big-endian order as the other integers.
+func (b *Buffer) PutUint128(idx uint32, hi, lo uint64) {
+	var value [16]byte
+	binary.BigEndian.PutUint64(value[0:8], hi)
+	binary.BigEndian.PutUint64(value[8:16], lo)
+	b.PutBytes(Put, idx, value[:])
+}
+
 // PutBitmap iterates over the bitmap values and appends an operation for each bit set to one
 func (b *Buffer) PutBitmap(op OpType, chunk Chunk, value bitmap.Bitmap) {
 	chunk.Range(value, func(idx uint32) {
@@ -315,6 +588,14 @@ func (b *Buffer) PutBitmap(op OpType, chunk Chunk, value bitmap.Bitmap) {
 
 // writeUint64 appends a uint64 value.
 func (b *Buffer) writeUint64(op OpType, idx uint32, value uint64) {
+	if b.extra != nil {
+		var v [8]byte
+		binary.BigEndian.PutUint64(v[:], value)
+		if b.writeRepeat(op, idx, byte(op)|size8, v[:]) {
+			return
+		}
+	}
+
 	delta := b.writeChunk(idx)
 	switch delta {
 	case 1:
@@ -335,6 +616,14 @@ func (b *Buffer) writeUint64(op OpType, idx uint32, value uint64) {
 
 // writeUint32 appends a uint32 value.
 func (b *Buffer) writeUint32(op OpType, idx uint32, value uint32) {
+	if b.extra != nil {
+		var v [4]byte
+		binary.BigEndian.PutUint32(v[:], value)
+		if b.writeRepeat(op, idx, byte(op)|size4, v[:]) {
+			return
+		}
+	}
+
 	delta := b.writeChunk(idx)
 	switch delta {
 	case 1:
@@ -353,6 +642,14 @@ func (b *Buffer) writeUint32(op OpType, idx uint32, value uint32) {
 
 // writeUint16 appends a uint16 value.
 func (b *Buffer) writeUint16(op OpType, idx uint32, value uint16) {
+	if b.extra != nil {
+		var v [2]byte
+		binary.BigEndian.PutUint16(v[:], value)
+		if b.writeRepeat(op, idx, byte(op)|size2, v[:]) {
+			return
+		}
+	}
+
 	delta := b.writeChunk(idx)
 	switch delta {
 	case 1:
@@ -363,6 +660,50 @@ func (b *Buffer) writeUint16(op OpType, idx uint32, value uint16) {
 	}
 }
 
+// writeRepeat writes a repeat of the previous value if the run-length encoding is enabled and
+// the same value was put at the previous offset, right before. Otherwise, it remembers where
+// the value is about to be written by the caller and returns false.
+func (b *Buffer) writeRepeat(op OpType, idx uint32, head byte, value []byte) bool {
+	x := b.extra
+	if !x.runs {
+		return false
+	}
+
+	// Runs are split every 64 offsets, so that a chunk never starts with a repeat
+	start := len(b.buffer)
+	if op == Put && x.end == start && x.at == int32(idx)-1 && idx&63 != 0 &&
+		x.head == head && bytes.Equal(b.buffer[x.i0:x.i1], value) {
+		b.writeChunk(idx)
+		b.buffer = append(b.buffer, byte(Put)|isRepeat|isNext)
+		x.at = int32(idx)
+		x.end = len(b.buffer)
+		return true
+	}
+
+	// Only the values which are put can be repeated
+	x.end = -1
+	if op != Put {
+		return false
+	}
+
+	x.at, x.head = int32(idx), head
+	x.i0 = start + 1
+	if head&isString != 0 {
+		x.i0 = start + 3
+	}
+
+	// Compute the end of the record, which is followed by the offset if not a neighbour
+	x.i1 = x.i0 + len(value)
+	x.end = x.i1
+	if delta := int32(idx) - b.last; delta != 1 {
+		for v := uint32(delta); v >= 0x80; v >>= 7 {
+			x.end++
+		}
+		x.end++
+	}
+	return false
+}
+
 // writeOffset writes the offset at the current head.
 func (b *Buffer) writeOffset(delta uint32) {
 	for delta >= 0x80 {
@@ -384,6 +725,12 @@ func (b *Buffer) writeChunk(idx uint32) int32 {
 		})
 	}
 
+	last := len(b.chunks) - 1
+	if b.chunks[last].Count == uncounted {
+		b.countOf(last) // Count the decoded records before appending
+	}
+
+	b.chunks[last].Count++
 	delta := int32(idx) - b.last
 	b.last = int32(idx)
 	return delta
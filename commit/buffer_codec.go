@@ -4,7 +4,9 @@
 package commit
 
 import (
+	"bytes"
 	"encoding/binary"
+	"fmt"
 	"io"
 	"reflect"
 	"unsafe"
@@ -12,12 +14,46 @@ import (
 	"github.com/kelindar/iostream"
 )
 
+// schemaMarker precedes the optional schema header, in place of the column name. Buffers
+// without a schema are encoded exactly as before.
+const schemaMarker = "\x00schema"
+
+// shiftMarker precedes the optional chunk shift header, in place of the column name. It is
+// only written when the chunk headers were computed with a shift other than the default one,
+// so buffers without it are decoded with the default shift.
+const shiftMarker = "\x00shift"
+
+// frameMagic identifies a buffer written with WriteFramedTo, followed by the version of
+// the encoding and the length of the encoded buffer.
+const (
+	frameMagic   = "CBUF"
+	frameVersion = 1
+	frameHeader  = len(frameMagic) + 1 + 8
+)
+
 // --------------------------- WriteTo ----------------------------
 
 // WriteTo writes data to w until there's no more data to write or when an error occurs. The return
 // value n is the number of bytes written. Any error encountered during the write is also returned.
 func (b *Buffer) WriteTo(dst io.Writer) (int64, error) {
+	return b.writeTo(dst, chunkShift)
+}
+
+// writeTo writes the buffer whose chunk headers were computed with the specified chunk shift.
+func (b *Buffer) writeTo(dst io.Writer, shift uint8) (int64, error) {
 	w := iostream.NewWriter(dst)
+	if shift != chunkShift {
+		if err := writeShiftTo(w, shift); err != nil {
+			return w.Offset(), err
+		}
+	}
+
+	if schema := b.schemaOf(); schema != nil {
+		if err := writeSchemaTo(w, *schema); err != nil {
+			return w.Offset(), err
+		}
+	}
+
 	if err := w.WriteString(b.Column); err != nil {
 		return w.Offset(), err
 	}
@@ -42,6 +78,28 @@ func (b *Buffer) WriteTo(dst io.Writer) (int64, error) {
 	return w.Offset(), err
 }
 
+// writeShiftTo writes the chunk shift header, preceded by its marker
+func writeShiftTo(w *iostream.Writer, shift uint8) error {
+	if err := w.WriteString(shiftMarker); err != nil {
+		return err
+	}
+	return w.WriteUvarint(uint64(shift))
+}
+
+// writeSchemaTo writes the schema header, preceded by its marker
+func writeSchemaTo(w *iostream.Writer, schema []ColumnDef) error {
+	if err := w.WriteString(schemaMarker); err != nil {
+		return err
+	}
+
+	return w.WriteRange(len(schema), func(i int, w *iostream.Writer) error {
+		if err := w.WriteString(schema[i].Name); err != nil {
+			return err
+		}
+		return w.WriteUvarint(uint64(schema[i].Kind))
+	})
+}
+
 // --------------------------- ReadFrom ----------------------------
 
 // ReadFrom reads data from r until EOF or error. The return value n is the number of
@@ -53,6 +111,34 @@ func (b *Buffer) ReadFrom(src io.Reader) (int64, error) {
 		return r.Offset(), err
 	}
 
+	// If the buffer has a chunk shift header, read it before the schema
+	shift := uint8(chunkShift)
+	if b.Column == shiftMarker {
+		v, err := r.ReadUvarint()
+		if err != nil {
+			return r.Offset(), err
+		}
+
+		shift = uint8(v)
+		if b.Column, err = r.ReadString(); err != nil {
+			return r.Offset(), err
+		}
+	}
+
+	// If the buffer has a schema header, read it before the column name
+	b.extra = nil
+	if b.Column == schemaMarker {
+		schema, err := readSchemaFrom(r)
+		if err != nil {
+			return r.Offset(), err
+		}
+
+		b.WriteSchema(schema)
+		if b.Column, err = r.ReadString(); err != nil {
+			return r.Offset(), err
+		}
+	}
+
 	if b.last, err = r.ReadInt32(); err != nil {
 		return r.Offset(), err
 	}
@@ -65,6 +151,12 @@ func (b *Buffer) ReadFrom(src io.Reader) (int64, error) {
 		return r.Offset(), err
 	}
 
+	// Chunk headers written with a different chunk size need to be split again
+	if shift != chunkShift {
+		b.rechunk(chunkShift)
+		return r.Offset(), nil
+	}
+
 	if len(b.chunks) > 0 {
 		last := b.chunks[len(b.chunks)-1]
 		b.chunk = last.Chunk
@@ -73,6 +165,28 @@ func (b *Buffer) ReadFrom(src io.Reader) (int64, error) {
 	return r.Offset(), nil
 }
 
+// readSchemaFrom reads the schema header from the reader
+func readSchemaFrom(r *iostream.Reader) (schema []ColumnDef, err error) {
+	err = r.ReadRange(func(i int, r *iostream.Reader) error {
+		name, err := r.ReadString()
+		if err != nil {
+			return err
+		}
+
+		kind, err := r.ReadUvarint()
+		if err != nil {
+			return err
+		}
+
+		schema = append(schema, ColumnDef{
+			Name: name,
+			Kind: reflect.Kind(kind),
+		})
+		return nil
+	})
+	return
+}
+
 // readChunksFrom reads the list of chunks from the reader
 func readChunksFrom(r *iostream.Reader) ([]header, error) {
 	size, err := r.ReadUvarint()
@@ -90,6 +204,7 @@ func readChunksFrom(r *iostream.Reader) ([]header, error) {
 		v[i].Chunk = Chunk(binary.BigEndian.Uint32(temp[0:4]))
 		v[i].Start = binary.BigEndian.Uint32(temp[4:8])
 		v[i].Value = binary.BigEndian.Uint32(temp[8:12])
+		v[i].Count = uncounted
 	}
 	return v, nil
 }
@@ -105,3 +220,58 @@ func toBytes(v string) (b []byte) {
 	byteHeader.Cap = l
 	return
 }
+
+// --------------------------- Framing ----------------------------
+
+// WriteFramedTo writes the buffer, same as WriteTo, preceded by a small header made of a magic
+// number, the version of the encoding and the length of the encoded buffer. Unlike with WriteTo,
+// a framed buffer can be recognised and validated before being decoded, which is suitable for
+// durable storage. The header is opt-in, so the encoding of WriteTo is left unchanged.
+func (b *Buffer) WriteFramedTo(dst io.Writer) (int64, error) {
+	var body bytes.Buffer
+	if _, err := b.WriteTo(&body); err != nil {
+		return 0, err
+	}
+
+	var header [frameHeader]byte
+	copy(header[:], frameMagic)
+	header[len(frameMagic)] = frameVersion
+	binary.BigEndian.PutUint64(header[len(frameMagic)+1:], uint64(body.Len()))
+	n, err := dst.Write(header[:])
+	if err != nil {
+		return int64(n), err
+	}
+
+	m, err := body.WriteTo(dst)
+	return int64(n) + m, err
+}
+
+// ReadFramedFrom reads a buffer written with WriteFramedTo. It returns an error if the header
+// is invalid, if the version of the encoding is not supported or if the encoded buffer does
+// not match the length of the header. Only the bytes of the buffer are read from the source,
+// so several framed buffers can be read one after the other.
+func (b *Buffer) ReadFramedFrom(src io.Reader) (int64, error) {
+	var header [frameHeader]byte
+	n, err := io.ReadFull(src, header[:])
+	if err != nil {
+		return int64(n), err
+	}
+
+	switch {
+	case string(header[:len(frameMagic)]) != frameMagic:
+		return int64(n), fmt.Errorf("column: unable to read buffer, invalid header")
+	case header[len(frameMagic)] != frameVersion:
+		return int64(n), fmt.Errorf("column: unable to read buffer, unsupported version %d", header[len(frameMagic)])
+	}
+
+	size := int64(binary.BigEndian.Uint64(header[len(frameMagic)+1:]))
+	m, err := b.ReadFrom(io.LimitReader(src, size))
+	switch {
+	case err == io.EOF:
+		return int64(n) + m, io.ErrUnexpectedEOF
+	case err == nil && m != size:
+		return int64(n) + m, fmt.Errorf("column: unable to read buffer, expected %d bytes but read %d", size, m)
+	default:
+		return int64(n) + m, err
+	}
+}
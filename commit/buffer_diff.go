@@ -0,0 +1,102 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package commit
+
+import (
+	"bytes"
+	"encoding/binary"
+	"sort"
+)
+
+// Diff computes a buffer which turns the state written in the previous buffer into the
+// state written in the next one. Both buffers are read as a state, where the last record
+// written at an offset wins. Records which are identical in both are omitted, and a delete
+// is written for every offset which is present in the previous buffer but not in the next.
+func Diff(prev, next *Buffer) *Buffer {
+	before, after := stateOf(prev), stateOf(next)
+	offsets := make([]uint32, 0, len(after))
+	for idx, v := range after {
+		if u, ok := before[idx]; !ok || !u.equals(v) {
+			offsets = append(offsets, idx)
+		}
+	}
+
+	for idx := range before {
+		if _, ok := after[idx]; !ok {
+			offsets = append(offsets, idx)
+		}
+	}
+
+	// Write the records in the order of their offsets, to keep the deltas small
+	sort.Slice(offsets, func(i, j int) bool { return offsets[i] < offsets[j] })
+	out := NewBuffer(len(next.buffer))
+	out.Column = next.Column
+	if schema := next.schemaOf(); schema != nil {
+		out.WriteSchema(*schema)
+	}
+
+	for _, idx := range offsets {
+		if v, ok := after[idx]; ok {
+			out.putRecord(idx, v)
+			continue
+		}
+
+		out.PutOperation(Delete, idx)
+	}
+	return out
+}
+
+// record represents a single encoded record of a buffer
+type record struct {
+	head  byte   // The header of the record, without the neighbour flag
+	value []byte // The encoded value of the record
+}
+
+// equals returns whether both records have the same operation and value
+func (r record) equals(other record) bool {
+	return r.head == other.head && bytes.Equal(r.value, other.value)
+}
+
+// stateOf reads the last record written at every offset of the buffer
+func stateOf(b *Buffer) map[uint32]record {
+	state := make(map[uint32]record, 64)
+	rangeRecords(b, func(idx uint32, v record) {
+		state[idx] = v
+	})
+	return state
+}
+
+// rangeRecords iterates over every record of the buffer, in the order they were written
+func rangeRecords(b *Buffer, fn func(idx uint32, v record)) {
+	r := NewReader()
+	head := byte(0)
+	for r.Seek(b); r.head < len(r.buffer); {
+		if v := r.buffer[r.head]; v&(isNext|isRepeat) != isNext|isRepeat {
+			head = v &^ isNext // A repeat has the same header as the previous value
+		}
+
+		r.Next()
+		fn(r.Index(), record{
+			head:  head,
+			value: r.buffer[r.i0:r.i1],
+		})
+	}
+}
+
+// putRecord appends a previously encoded record, preserving its size.
+func (b *Buffer) putRecord(idx uint32, v record) {
+	op := OpType(v.head & 0xf)
+	switch {
+	case v.head&isString != 0:
+		b.PutBytes(op, idx, v.value)
+	case len(v.value) == 2:
+		b.writeUint16(op, idx, binary.BigEndian.Uint16(v.value))
+	case len(v.value) == 4:
+		b.writeUint32(op, idx, binary.BigEndian.Uint32(v.value))
+	case len(v.value) == 8:
+		b.writeUint64(op, idx, binary.BigEndian.Uint64(v.value))
+	default:
+		b.PutOperation(op, idx)
+	}
+}
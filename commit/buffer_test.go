@@ -5,7 +5,13 @@ package commit
 
 import (
 	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 	"unsafe"
 
 	"github.com/kelindar/bitmap"
@@ -117,6 +123,13 @@ func BenchmarkQueue(b *testing.B) {
 			_ = r.Bool()
 		}
 	})
+
+	values := []interface{}{true, "hello world", 12.34, uint(12), int16(34)}
+	run("any-mixed", b, count, func(buf *Buffer, r *Reader) {
+		for i := uint32(0); i < count; i++ {
+			buf.PutAny(Put, i, values[i%5])
+		}
+	})
 }
 
 // Run runs a single benchmark
@@ -268,6 +281,37 @@ func TestBufferClone(t *testing.T) {
 
 	cloned := buf.Clone()
 	assert.EqualValues(t, buf, cloned)
+
+	// Mutating either of the buffers must not affect the other one
+	buf.PutInt16(1<<chunkShift, 200)
+	cloned.PutString(Put, 30, "world")
+	r := NewReader()
+	r.Seek(buf)
+	r.Next()
+	r.SwapInt16(99)
+
+	r.Seek(cloned)
+	assert.True(t, r.Next())
+	assert.Equal(t, int16(100), r.Int16())
+	assert.True(t, r.Next())
+	assert.Equal(t, "hello", r.String())
+	assert.True(t, r.Next())
+	assert.Equal(t, "world", r.String())
+	assert.False(t, r.Next())
+	assert.Equal(t, 1, len(cloned.ChunkInfo()))
+}
+
+func TestPutJSON(t *testing.T) {
+	buf := NewBuffer(0)
+	assert.NoError(t, buf.PutJSON(Put, 10, json.RawMessage(`{"name":"Roman"}`)))
+	assert.Error(t, buf.PutJSON(Put, 20, json.RawMessage(`{"name":`)))
+
+	r := NewReader()
+	r.Seek(buf)
+	assert.True(t, r.Next())
+	assert.Equal(t, uint32(10), r.Index())
+	assert.Equal(t, `{"name":"Roman"}`, string(r.JSON()))
+	assert.False(t, r.Next())
 }
 
 func TestPutNil(t *testing.T) {
@@ -290,6 +334,37 @@ func TestPutBitmap(t *testing.T) {
 	assert.Equal(t, Insert, r.Type)
 }
 
+func TestBufferChunkInfo(t *testing.T) {
+	buf := NewBuffer(0)
+	buf.PutInt16(10, 100)
+	buf.PutInt16(11, 100)
+	buf.PutString(Put, 20, "hello")
+	buf.PutOperation(Delete, 1<<chunkShift)
+	assert.Equal(t, []ChunkMeta{
+		{Chunk: 0, Count: 3, Size: 16},
+		{Chunk: 1, Count: 1, Size: 3},
+	}, buf.ChunkInfo())
+
+	// Decoded buffers must have the same metadata
+	encoded := bytes.NewBuffer(nil)
+	_, err := buf.WriteTo(encoded)
+	assert.NoError(t, err)
+	data := encoded.Bytes()
+
+	out := NewBuffer(0)
+	_, err = out.ReadFrom(encoded)
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(uncounted), out.chunks[0].Count)
+	assert.Equal(t, buf.ChunkInfo(), out.ChunkInfo())
+
+	// Appending to a decoded chunk counts its records first
+	out = NewBuffer(0)
+	_, err = out.ReadFrom(bytes.NewBuffer(data))
+	assert.NoError(t, err)
+	out.PutOperation(Delete, 1<<chunkShift+1)
+	assert.Equal(t, 2, out.ChunkInfo()[1].Count)
+}
+
 func TestBufferWriteTo(t *testing.T) {
 	input := NewBuffer(0)
 	input.Column = "test"
@@ -308,6 +383,281 @@ func TestBufferWriteTo(t *testing.T) {
 	assert.Equal(t, input, output)
 }
 
+func TestBufferVariableWidth(t *testing.T) {
+	values := make([]string, 100)
+	for i := range values {
+		values[i] = strings.Repeat("x", i)
+	}
+
+	input := NewBuffer(0)
+	write := func() {
+		input.Reset("test")
+		for i := uint32(0); i < 1000; i++ {
+			input.PutString(Put, i*3, values[i%100])
+			input.PutBytes(Put, i*3+1, toBytes(values[i%100]))
+		}
+	}
+
+	// Once grown, variable-width values are appended without allocating
+	write()
+	assert.Equal(t, float64(0), testing.AllocsPerRun(10, write))
+
+	// Values must survive a round-trip through the codec
+	buffer := bytes.NewBuffer(nil)
+	_, err := input.WriteTo(buffer)
+	assert.NoError(t, err)
+
+	output := NewBuffer(0)
+	_, err = output.ReadFrom(buffer)
+	assert.NoError(t, err)
+
+	count, r := 0, NewReader()
+	for r.Seek(output); r.Next(); count++ {
+		i := uint32(r.Offset) / 3
+		switch uint32(r.Offset) % 3 {
+		case 0:
+			assert.Equal(t, strings.Repeat("x", int(i%100)), r.String())
+		default:
+			assert.Equal(t, []byte(values[i%100]), r.Bytes())
+		}
+	}
+	assert.Equal(t, 2000, count)
+}
+
+func TestKindOf(t *testing.T) {
+	tests := []struct {
+		value interface{}
+		kind  reflect.Kind
+		ok    bool
+	}{
+		{value: uint64(1), kind: reflect.Uint64, ok: true},
+		{value: uint32(1), kind: reflect.Uint32, ok: true},
+		{value: uint16(1), kind: reflect.Uint16, ok: true},
+		{value: uint8(1), kind: reflect.Uint16, ok: true},
+		{value: int64(1), kind: reflect.Int64, ok: true},
+		{value: int32(1), kind: reflect.Int32, ok: true},
+		{value: int16(1), kind: reflect.Int16, ok: true},
+		{value: int8(1), kind: reflect.Int16, ok: true},
+		{value: "hi", kind: reflect.String, ok: true},
+		{value: []byte("hi"), kind: reflect.String, ok: true},
+		{value: float32(1), kind: reflect.Float32, ok: true},
+		{value: float64(1), kind: reflect.Float64, ok: true},
+		{value: int(1), kind: reflect.Int, ok: true},
+		{value: uint(1), kind: reflect.Uint, ok: true},
+		{value: true, kind: reflect.Bool, ok: true},
+		{value: nil, kind: reflect.Invalid, ok: true},
+		{value: time.Time{}, kind: reflect.Int64, ok: true},
+		{value: []int{1}, kind: reflect.Invalid, ok: false},
+		{value: struct{}{}, kind: reflect.Invalid, ok: false},
+	}
+
+	for _, tc := range tests {
+		kind, ok := KindOf(tc.value)
+		assert.Equal(t, tc.kind, kind, "%T", tc.value)
+		assert.Equal(t, tc.ok, ok, "%T", tc.value)
+
+		// Must be supported by PutAny if and only if it has a kind
+		put := func() { NewBuffer(0).PutAny(Put, 0, tc.value) }
+		if ok {
+			assert.NotPanics(t, put, "%T", tc.value)
+		} else {
+			assert.Panics(t, put, "%T", tc.value)
+		}
+	}
+}
+
+func TestDiff(t *testing.T) {
+	prev := NewBuffer(0)
+	prev.Reset("test")
+	next := NewBuffer(0)
+	next.Reset("test")
+	for i := uint32(0); i < 20000; i += 7 {
+		switch i % 4 {
+		case 0:
+			prev.PutUint16(i, uint16(i))
+			next.PutUint16(i, uint16(i))
+		case 1:
+			prev.PutUint32(i, i)
+			next.PutUint64(i, uint64(i))
+		case 2:
+			prev.PutString(Put, i, "hello")
+		default:
+			next.PutString(Put, i, "world")
+			next.PutBool(i+1, true)
+		}
+	}
+
+	// Applies the buffer onto a store, where deletions remove the value
+	apply := func(store map[uint32]record, buffer *Buffer) map[uint32]record {
+		for idx, v := range stateOf(buffer) {
+			switch {
+			case OpType(v.head&0xf) == Delete && len(v.value) == 0:
+				delete(store, idx)
+			default:
+				store[idx] = v
+			}
+		}
+		return store
+	}
+
+	diff := Diff(prev, next)
+	assert.Equal(t, "test", diff.Column)
+	assert.Less(t, len(diff.buffer), len(next.buffer))
+	assert.Equal(t,
+		apply(map[uint32]record{}, next),
+		apply(apply(map[uint32]record{}, prev), diff),
+	)
+
+	// Identical buffers have nothing to apply
+	assert.True(t, Diff(next, next).IsEmpty())
+}
+
+func TestBufferSchema(t *testing.T) {
+	schema := []ColumnDef{
+		{Name: "name", Kind: reflect.String},
+		{Name: "age", Kind: reflect.Int},
+	}
+
+	input := NewBuffer(0)
+	input.Reset("test")
+	input.WriteSchema(schema)
+	input.PutInt16(10, 100)
+
+	r := NewReader()
+	r.Seek(input)
+	assert.Equal(t, schema, r.Schema())
+
+	// The schema must be persisted along with the buffer
+	buffer := bytes.NewBuffer(nil)
+	_, err := input.WriteTo(buffer)
+	assert.NoError(t, err)
+
+	output := NewBuffer(0)
+	_, err = output.ReadFrom(buffer)
+	assert.NoError(t, err)
+	assert.Equal(t, "test", output.Column)
+	r.Range(output, 0, func(r *Reader) {
+		assert.Equal(t, schema, r.Schema())
+		assert.True(t, r.Next())
+		assert.Equal(t, int16(100), r.Int16())
+	})
+
+	// Buffers without a schema do not have a header
+	output.Reset("test")
+	r.Seek(output)
+	assert.Nil(t, r.Schema())
+}
+
+func TestBufferChunkShift(t *testing.T) {
+	input := NewBuffer(0)
+	input.Reset("test")
+	for i := uint32(0); i < 40000; i += 7 {
+		input.PutUint32(i, i)
+	}
+
+	// Encode the buffer as if it was written with a different chunk size
+	for _, shift := range []uint8{10, 16} {
+		other := input.Clone()
+		other.rechunk(shift)
+		assert.NotEqual(t, input.chunks, other.chunks)
+
+		buffer := bytes.NewBuffer(nil)
+		_, err := other.writeTo(buffer, shift)
+		assert.NoError(t, err)
+
+		output := NewBuffer(0)
+		_, err = output.ReadFrom(buffer)
+		assert.NoError(t, err)
+		assert.Equal(t, "test", output.Column)
+		assert.Equal(t, input.chunks, output.chunks)
+
+		// Each chunk must contain exactly the records of that chunk
+		r := NewReader()
+		output.RangeChunks(func(chunk Chunk) {
+			r.Range(output, chunk, func(r *Reader) {
+				for r.Next() {
+					assert.Equal(t, chunk, ChunkAt(r.Index()))
+					assert.Equal(t, r.Index(), r.Uint32())
+				}
+			})
+		})
+	}
+
+	// Buffers with the default chunk size do not have a header
+	buffer := bytes.NewBuffer(nil)
+	_, err := input.WriteTo(buffer)
+	assert.NoError(t, err)
+	assert.False(t, bytes.Contains(buffer.Bytes(), []byte(shiftMarker)))
+}
+
+func TestBufferRuns(t *testing.T) {
+	write := func(b *Buffer) {
+		for i := uint32(0); i < 200; i++ {
+			b.PutUint64(i, 42)
+		}
+		for i := uint32(200); i < 300; i++ {
+			b.PutString(Put, i, "hello")
+		}
+
+		b.PutInt32(300, 1)
+		b.PutInt32(301, 1)
+		b.AddInt32(302, 1)
+		b.PutInt32(303, 1)
+		b.PutOperation(Delete, 304)
+		b.PutInt32(305, 1)
+		b.PutInt16(306, 1)
+		b.PutInt16(chunkSize+1, 7)
+		b.PutInt16(chunkSize+2, 7)
+		b.PutInt16(307, 1)
+		b.PutInt16(308, 1)
+		b.PutFloat64(400, 1)
+		b.PutFloat64(402, 1)
+	}
+
+	// Every record must be read exactly the same, whether or not it was encoded as a run
+	recordsOf := func(b *Buffer) (out []string) {
+		r := NewReader()
+		b.RangeChunks(func(chunk Chunk) {
+			r.Range(b, chunk, func(r *Reader) {
+				for r.Next() {
+					out = append(out, fmt.Sprintf("%d %d %x", r.Offset, r.Type, r.BytesRef()))
+				}
+			})
+		})
+		return
+	}
+
+	plain := NewBuffer(0)
+	write(plain)
+	runs := NewBuffer(0)
+	runs.EncodeRuns(true)
+	write(runs)
+
+	assert.Less(t, len(runs.buffer), len(plain.buffer)/4)
+	assert.Equal(t, recordsOf(plain), recordsOf(runs))
+	assert.Equal(t, plain.ChunkInfo()[0].Count, runs.ChunkInfo()[0].Count)
+	assert.Equal(t, recordsOf(plain), recordsOf(runs.Clone()))
+
+	// Runs are decoded after being persisted, and the state is read from them
+	buffer := bytes.NewBuffer(nil)
+	_, err := runs.WriteTo(buffer)
+	assert.NoError(t, err)
+	output := NewBuffer(0)
+	_, err = output.ReadFrom(buffer)
+	assert.NoError(t, err)
+	assert.Equal(t, recordsOf(plain), recordsOf(output))
+	assert.Equal(t, recordsOf(Diff(NewBuffer(0), plain)), recordsOf(Diff(NewBuffer(0), runs)))
+
+	r := NewReader()
+	r.Seek(runs)
+	assert.Len(t, r.Uint64s(nil), 200)
+
+	// The encoding is disabled when the buffer is reset
+	runs.Reset("test")
+	write(runs)
+	assert.Equal(t, plain.buffer, runs.buffer)
+}
+
 func TestBufferWriteToFailures(t *testing.T) {
 	buf := NewBuffer(0)
 	buf.Column = "test"
@@ -337,3 +687,188 @@ func TestBufferReadFromFailures(t *testing.T) {
 		assert.Error(t, err)
 	}
 }
+
+func TestBufferMerge(t *testing.T) {
+	a := NewBuffer(0)
+	a.PutUint64(10, 1)
+	a.PutString(Put, 20000, "a")
+	a.PutBool(20001, true)
+
+	b := NewBuffer(0)
+	b.PutUint64(10, 2)
+	b.PutDelete(5)
+	b.AddInt32(40000, 3)
+
+	// Records of both buffers are read in order, at their original offsets
+	a.Merge(b)
+	r := NewReader()
+	r.Seek(a)
+	for _, expect := range []struct {
+		op     OpType
+		offset uint32
+	}{{Put, 10}, {Put, 20000}, {PutTrue, 20001}, {Put, 10}, {Delete, 5}, {Add, 40000}} {
+		assert.True(t, r.Next())
+		assert.Equal(t, expect.op, r.Type)
+		assert.Equal(t, expect.offset, r.Index())
+	}
+	assert.False(t, r.Next())
+
+	// Chunks can also be read on their own
+	var offsets []uint32
+	r.Range(a, 0, func(r *Reader) {
+		for r.Next() {
+			offsets = append(offsets, r.Index())
+		}
+	})
+	assert.Equal(t, []uint32{10, 10, 5}, offsets)
+
+	// Merging an empty buffer is a no-op, and merging into an empty one copies it
+	size := a.Len()
+	a.Merge(NewBuffer(0))
+	assert.Equal(t, size, a.Len())
+
+	c := NewBuffer(0)
+	c.Merge(b)
+	assert.Equal(t, b.buffer, c.buffer)
+	assert.Equal(t, b.ChunkInfo(), c.ChunkInfo())
+}
+
+func TestBufferClear(t *testing.T) {
+	buf := NewBuffer(0)
+	buf.Reset("test")
+	buf.WriteSchema([]ColumnDef{{Name: "test"}})
+	buf.EncodeRuns(true)
+	for i := uint32(0); i < 100; i++ {
+		buf.PutUint64(i*100, 7)
+	}
+
+	// Clearing keeps the column, schema and allocated memory
+	size := buf.Cap()
+	buf.Clear()
+	assert.True(t, buf.IsEmpty())
+	assert.Equal(t, "test", buf.Column)
+	assert.Equal(t, size, buf.Cap())
+	assert.NotNil(t, buf.schemaOf())
+
+	r := NewReader()
+	r.Seek(buf)
+	assert.False(t, r.Next())
+	buf.RangeChunks(func(Chunk) {
+		assert.Fail(t, "no chunks expected")
+	})
+
+	// A value equal to the one written before clearing is written in full
+	buf.PutUint64(1, 7)
+	buf.PutUint64(2, 7)
+	r.Seek(buf)
+	assert.True(t, r.Next())
+	assert.Equal(t, uint32(1), r.Index())
+	assert.Equal(t, uint64(7), r.Uint64())
+	assert.True(t, r.Next())
+	assert.Equal(t, uint32(2), r.Index())
+	assert.Equal(t, uint64(7), r.Uint64())
+	assert.False(t, r.Next())
+}
+
+func TestBufferFramed(t *testing.T) {
+	first := NewBuffer(0)
+	first.Reset("first")
+	first.PutInt16(10, 100)
+	first.PutString(Put, 20, "hello")
+
+	second := NewBuffer(0)
+	second.Reset("second")
+	second.PutUint64(chunkSize*2, 7)
+
+	// Framed buffers can be read back one after the other
+	stream := bytes.NewBuffer(nil)
+	for _, buf := range []*Buffer{first, second} {
+		n, err := buf.WriteFramedTo(stream)
+		assert.NoError(t, err)
+
+		plain := bytes.NewBuffer(nil)
+		m, err := buf.WriteTo(plain)
+		assert.NoError(t, err)
+		assert.Equal(t, m+int64(frameHeader), n)
+	}
+
+	encoded := stream.Bytes()
+	for _, buf := range []*Buffer{first, second} {
+		output := NewBuffer(0)
+		_, err := output.ReadFramedFrom(stream)
+		assert.NoError(t, err)
+
+		expect, actual := NewReader(), NewReader()
+		expect.Seek(buf)
+		actual.Seek(output)
+		assert.Equal(t, buf.Column, output.Column)
+		for expect.Next() {
+			assert.True(t, actual.Next())
+			assert.Equal(t, expect.Type, actual.Type)
+			assert.Equal(t, expect.Index(), actual.Index())
+			assert.Equal(t, expect.Bytes(), actual.Bytes())
+		}
+		assert.False(t, actual.Next())
+	}
+
+	// Invalid headers and truncated buffers fail
+	invalid := append([]byte("XBUF"), encoded[4:]...)
+	_, err := NewBuffer(0).ReadFramedFrom(bytes.NewReader(invalid))
+	assert.Error(t, err)
+
+	version := append([]byte(nil), encoded...)
+	version[len(frameMagic)] = 2
+	_, err = NewBuffer(0).ReadFramedFrom(bytes.NewReader(version))
+	assert.Error(t, err)
+
+	for size := 0; size < len(encoded)/2; size++ {
+		_, err := NewBuffer(0).ReadFramedFrom(bytes.NewReader(encoded[:size]))
+		assert.Error(t, err)
+	}
+}
+
+func TestUint128(t *testing.T) {
+	buf := NewBuffer(0)
+	buf.PutUint128(10, 0x0123456789abcdef, 0xfedcba9876543210)
+	buf.PutUint128(11, 0, math.MaxUint64)
+	buf.PutUint128(200000, math.MaxUint64, 1)
+
+	r := NewReader()
+	r.Seek(buf)
+	for _, expect := range []struct {
+		offset uint32
+		hi, lo uint64
+	}{
+		{10, 0x0123456789abcdef, 0xfedcba9876543210},
+		{11, 0, math.MaxUint64},
+		{200000, math.MaxUint64, 1},
+	} {
+		assert.True(t, r.Next())
+		assert.Equal(t, expect.offset, r.Index())
+		hi, lo := r.Uint128()
+		assert.Equal(t, expect.hi, hi)
+		assert.Equal(t, expect.lo, lo)
+	}
+	assert.False(t, r.Next())
+
+	// The value is big-endian, same as the other integers
+	r.Seek(buf)
+	r.Next()
+	assert.Equal(t, []byte{1, 0x23, 0x45, 0x67, 0x89, 0xab, 0xcd, 0xef}, r.Bytes()[:8])
+}
+
+func TestPutTime(t *testing.T) {
+	now := time.Now()
+	buf := NewBuffer(0)
+	buf.PutTime(10, now)
+	buf.PutAny(Put, 11, now.Add(time.Hour))
+
+	r := NewReader()
+	r.Seek(buf)
+	assert.True(t, r.Next())
+	assert.True(t, now.Equal(r.Time()))
+	assert.Equal(t, now.Round(0), r.Time().In(now.Location()))
+	assert.True(t, r.Next())
+	assert.Equal(t, now.Add(time.Hour).UnixNano(), r.Int64())
+	assert.False(t, r.Next())
+}
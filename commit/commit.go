@@ -183,6 +183,7 @@ func (c *Commit) ReadFrom(src io.Reader) (int64, error) {
 		r.ReadRange(func(i int, r *iostream.Reader) error {
 			header := header{
 				Chunk: Chunk(chunk),
+				Count: uncounted,
 			}
 
 			// Previous offset and index in the byte array
@@ -198,8 +199,10 @@ func (c *Commit) ReadFrom(src io.Reader) (int64, error) {
 		})
 
 		// Read the combined buffer
-		buffer.buffer, err = r.ReadBytes()
-		return err
+		if buffer.buffer, err = r.ReadBytes(); err != nil {
+			return err
+		}
+		return nil
 	}); err != nil {
 		return r.Offset(), err
 	}
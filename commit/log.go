@@ -12,7 +12,10 @@ import (
 	"github.com/klauspost/compress/s2"
 )
 
-// Logger represents a contract that a commit logger must implement
+// Logger represents a contract that a commit logger must implement. Commits are appended
+// once applied, by which point every addition was swapped with a put of its resulting value,
+// so a logger receives absolute values rather than increments and the commit can be replayed
+// without adding the deltas again.
 type Logger interface {
 	Append(commit Commit) error
 }
@@ -5,18 +5,23 @@ package commit
 
 import (
 	"encoding/binary"
+	"encoding/json"
 	"math"
+	"time"
 	"unsafe"
 )
 
 // Reader represnts a commit log reader (iterator).
 type Reader struct {
-	head   int    // The read position
-	i0, i1 int    // The value start and end
-	Type   OpType // The current operation type
-	buffer []byte // The log slice
-	Offset int32  // The current offset
-	start  int32  // The start offset
+	head   int          // The read position
+	i0, i1 int          // The value start and end
+	Type   OpType       // The current operation type
+	skip   bool         // Whether records without a value are skipped
+	cutoff bool         // Whether Next stopped on an incomplete record
+	buffer []byte       // The log slice
+	Offset int32        // The current offset
+	start  int32        // The start offset
+	schema *[]ColumnDef // The schema header of the buffer
 }
 
 // NewReader creates a new reader for a commit log.
@@ -27,6 +32,23 @@ func NewReader() *Reader {
 // Seek resets the reader so it can be reused.
 func (r *Reader) Seek(b *Buffer) {
 	r.use(b.buffer)
+	r.schema = b.schemaOf()
+}
+
+// Schema returns the schema header of the buffer being read, or nil if the buffer
+// does not have one.
+func (r *Reader) Schema() []ColumnDef {
+	if r.schema == nil {
+		return nil
+	}
+	return *r.schema
+}
+
+// SkipEmpty sets whether Next skips the records which do not carry a value, such as the
+// booleans and deletions, so that only the records with a value are read. This is off by
+// default and the setting is kept when the reader is reused.
+func (r *Reader) SkipEmpty(enabled bool) {
+	r.skip = enabled
 }
 
 // Rewind rewinds the reader back to zero.
@@ -35,9 +57,41 @@ func (r *Reader) Rewind() {
 	r.Offset = r.start
 }
 
+// ReaderPos represents a saved position of a reader, see Mark.
+type ReaderPos struct {
+	head   int    // The read position
+	i0, i1 int    // The value start and end
+	offset int32  // The current offset
+	op     OpType // The current operation type
+}
+
+// Mark returns the current position of the reader, which can be restored with Reset in
+// order to read the same records again. A position remains valid for as long as the buffer
+// being read is not modified.
+func (r *Reader) Mark() ReaderPos {
+	return ReaderPos{
+		head:   r.head,
+		i0:     r.i0,
+		i1:     r.i1,
+		offset: r.Offset,
+		op:     r.Type,
+	}
+}
+
+// Reset restores a position returned by Mark, including the current record, so that the
+// getters return the values they returned when the position was marked.
+func (r *Reader) Reset(pos ReaderPos) {
+	r.head = pos.head
+	r.i0 = pos.i0
+	r.i1 = pos.i1
+	r.Offset = pos.offset
+	r.Type = pos.op
+}
+
 // Use sets the buffer and resets the reader.
 func (r *Reader) use(buffer []byte) {
 	r.buffer = buffer
+	r.cutoff = false
 	r.head = 0
 	r.i0 = 0
 	r.i1 = 0
@@ -77,7 +131,20 @@ func (r *Reader) Uint64() uint64 {
 	return binary.BigEndian.Uint64(r.buffer[r.i0:r.i1])
 }
 
-// Float32 reads a float32 value.
+// Time reads a time written with PutTime, in the local location.
+func (r *Reader) Time() time.Time {
+	return time.Unix(0, r.Int64())
+}
+
+// Uint128 reads a 128-bit unsigned integer written with PutUint128, as its high and low
+// 64 bits.
+func (r *Reader) Uint128() (hi, lo uint64) {
+	value := r.buffer[r.i0:r.i1]
+	return binary.BigEndian.Uint64(value[0:8]), binary.BigEndian.Uint64(value[8:16])
+}
+
+// Float32 reads a float32 value. The record must have been written as a float32, use
+// Float32Narrow to read a record of any floating-point size.
 func (r *Reader) Float32() float32 {
 	return math.Float32frombits(binary.BigEndian.Uint32(r.buffer[r.i0:r.i1]))
 }
@@ -92,11 +159,32 @@ func (r *Reader) Number() float64 {
 	return r.Float64()
 }
 
-// Bytes reads a binary value.
+// Bytes reads a binary value and returns a copy of it, which is safe to retain. To copy
+// the value into an existing slice, use BytesAppend instead.
 func (r *Reader) Bytes() []byte {
+	out := make([]byte, r.i1-r.i0)
+	copy(out, r.buffer[r.i0:r.i1])
+	return out
+}
+
+// BytesAppend reads a binary value and appends it to dst, returning the extended slice. When
+// dst has enough capacity, reading the value does not allocate.
+func (r *Reader) BytesAppend(dst []byte) []byte {
+	return append(dst, r.buffer[r.i0:r.i1]...)
+}
+
+// BytesRef reads a binary value without copying it. The returned slice aliases the
+// underlying buffer and is only valid until the next call to Next(), it must not be
+// retained or modified.
+func (r *Reader) BytesRef() []byte {
 	return r.buffer[r.i0:r.i1]
 }
 
+// JSON reads a raw JSON value and returns a copy of it, which is safe to retain.
+func (r *Reader) JSON() json.RawMessage {
+	return json.RawMessage(r.Bytes())
+}
+
 // --------------------------- Reader Interface ----------------------------
 
 // Index returns the current index of the reader.
@@ -135,7 +223,23 @@ func (r *Reader) Float() float64 {
 	}
 }
 
-// String reads a string value.
+// Float32Narrow reads a floating-point value of any size and narrows it to a float32. A
+// float64 value is rounded to the nearest float32, losing precision, and values which are
+// out of the float32 range become infinities.
+func (r *Reader) Float32Narrow() float32 {
+	switch r.i1 - r.i0 {
+	case 4:
+		return r.Float32()
+	case 8:
+		return float32(r.Float64())
+	default:
+		panic("column: unable to read, unsupported float size")
+	}
+}
+
+// String reads a string value without copying it, so it does not allocate. The string
+// aliases the buffer, hence it is only valid until the buffer is reset or modified and it
+// must be copied in order to be retained.
 func (r *Reader) String() string {
 	b := r.buffer[r.i0:r.i1]
 	return *(*string)(unsafe.Pointer(&b))
@@ -148,59 +252,77 @@ func (r *Reader) Bool() bool {
 
 // --------------------------- Value Swap ----------------------------
 
+// swapPut marks the current record as a put operation. Once a value is swapped, the record
+// holds the resulting value rather than a delta, so it can be replayed as-is.
+func (r *Reader) swapPut() {
+	r.buffer[r.i0-1] = r.buffer[r.i0-1]&0xf0 | byte(Put)
+	r.Type = Put
+}
+
 // SwapInt16 swaps a uint16 value with a new one.
 func (r *Reader) SwapInt16(v int16) {
 	binary.BigEndian.PutUint16(r.buffer[r.i0:r.i1], uint16(v))
+	r.swapPut()
 }
 
 // SwapInt32 swaps a uint32 value with a new one.
 func (r *Reader) SwapInt32(v int32) {
 	binary.BigEndian.PutUint32(r.buffer[r.i0:r.i1], uint32(v))
+	r.swapPut()
 }
 
 // SwapInt64 swaps a uint64 value with a new one.
 func (r *Reader) SwapInt64(v int64) {
 	binary.BigEndian.PutUint64(r.buffer[r.i0:r.i1], uint64(v))
+	r.swapPut()
 }
 
 // SwapInt swaps a uint64 value with a new one.
 func (r *Reader) SwapInt(v int) {
 	binary.BigEndian.PutUint64(r.buffer[r.i0:r.i1], uint64(v))
+	r.swapPut()
 }
 
 // SwapUint16 swaps a uint16 value with a new one.
 func (r *Reader) SwapUint16(v uint16) {
 	binary.BigEndian.PutUint16(r.buffer[r.i0:r.i1], v)
+	r.swapPut()
 }
 
 // SwapUint32 swaps a uint32 value with a new one.
 func (r *Reader) SwapUint32(v uint32) {
 	binary.BigEndian.PutUint32(r.buffer[r.i0:r.i1], v)
+	r.swapPut()
 }
 
 // SwapUint64 swaps a uint64 value with a new one.
 func (r *Reader) SwapUint64(v uint64) {
 	binary.BigEndian.PutUint64(r.buffer[r.i0:r.i1], v)
+	r.swapPut()
 }
 
 // SwapUint swaps a uint64 value with a new one.
 func (r *Reader) SwapUint(v uint) {
 	binary.BigEndian.PutUint64(r.buffer[r.i0:r.i1], uint64(v))
+	r.swapPut()
 }
 
 // SwapFloat32 swaps a float32 value with a new one.
 func (r *Reader) SwapFloat32(v float32) {
 	binary.BigEndian.PutUint32(r.buffer[r.i0:r.i1], math.Float32bits(v))
+	r.swapPut()
 }
 
 // SwapFloat64 swaps a float64 value with a new one.
 func (r *Reader) SwapFloat64(v float64) {
 	binary.BigEndian.PutUint64(r.buffer[r.i0:r.i1], math.Float64bits(v))
+	r.swapPut()
 }
 
 // SwapNumber swaps a float64 value with a new one.
 func (r *Reader) SwapNumber(v interface{}) {
 	binary.BigEndian.PutUint64(r.buffer[r.i0:r.i1], math.Float64bits(v.(float64)))
+	r.swapPut()
 }
 
 // SwapBool swaps a boolean value with a new one.
@@ -220,32 +342,112 @@ func (r *Reader) Range(buf *Buffer, chunk Chunk, fn func(*Reader)) {
 			continue // Not the right chunk, skip it
 		}
 
-		// Find the next offset
-		offset := c.Start
-		buffer := buf.buffer[offset:]
-		if len(buf.chunks) > i+1 {
-			until := uint32(buf.chunks[i+1].Start)
-			buffer = buf.buffer[offset:until]
-		}
+		r.useChunk(buf, i)
+		fn(r)
+	}
+}
 
-		// Set the reader to the subset buffer and call the delegate
-		r.use(buffer)
-		r.Offset = int32(c.Value)
-		r.start = int32(c.Value)
+// RangeAll iterates over all of the parts of the buffer in a single pass, in the order they
+// were written, with the reader positioned at the start of each part. A part holds the
+// records of a single chunk, and unless the records of several chunks were interleaved, each
+// chunk present in the buffer is a single part.
+func (r *Reader) RangeAll(buf *Buffer, fn func(*Reader)) {
+	for i := range buf.chunks {
+		r.useChunk(buf, i)
 		fn(r)
 	}
 }
 
+// useChunk sets the reader to the records of the i-th part of the buffer
+func (r *Reader) useChunk(buf *Buffer, i int) {
+	c := buf.chunks[i]
+
+	// Find the next offset
+	offset := c.Start
+	buffer := buf.buffer[offset:]
+	if len(buf.chunks) > i+1 {
+		until := uint32(buf.chunks[i+1].Start)
+		buffer = buf.buffer[offset:until]
+	}
+
+	// Set the reader to the subset buffer
+	r.use(buffer)
+	r.schema = buf.schemaOf()
+	r.Offset = int32(c.Value)
+	r.start = int32(c.Value)
+}
+
+// RangeNamed iterates over the entire buffer if it contains the records of the specified
+// column. Since a buffer holds the records of a single column, the name is only checked once.
+func (r *Reader) RangeNamed(buf *Buffer, name string, fn func(*Reader)) {
+	if buf.Column != name {
+		return
+	}
+
+	r.Seek(buf)
+	fn(r)
+}
+
+// Discard skips the entire buffer without decoding its records and returns the number
+// of records it contains, which are only counted once for a decoded buffer. The reader
+// is left at the end of the buffer, on the last offset.
+func (r *Reader) Discard(buf *Buffer) (count int) {
+	for i := range buf.chunks {
+		count += buf.countOf(i)
+	}
+
+	r.Seek(buf)
+	r.head = len(r.buffer)
+	r.i0, r.i1 = r.head, r.head
+	r.Offset = buf.last
+	return
+}
+
 // --------------------------- Next Iterator ----------------------------
 
 // Next reads the current operation and returns false if there is no more
 // operations in the log.
 func (r *Reader) Next() bool {
+	for r.next() {
+		if !r.skip || r.i1 > r.i0 {
+			return true
+		}
+	}
+	return false
+}
+
+// PeekType returns the operation type of the next record without consuming it, so that the
+// following call to Next reads that same record. It returns false if there is no record left.
+func (r *Reader) PeekType() (OpType, bool) {
+	pos := r.Mark()
+	defer r.Reset(pos)
+	if !r.Next() {
+		return 0, false
+	}
+	return r.Type, true
+}
+
+// Incomplete returns whether Next stopped on a record which is cut short by the end of the
+// buffer, for example because the buffer was only partially received. The flag is never set
+// when reading a complete buffer and it is cleared when the reader is reused.
+func (r *Reader) Incomplete() bool {
+	return r.cutoff
+}
+
+// next reads the next record, regardless of whether it carries a value.
+func (r *Reader) next() bool {
 	if r.head >= len(r.buffer) {
 		return false
 	}
 
+	// Only the records close to the end of the buffer, or of a variable size, can be cut short
 	head := r.buffer[r.head]
+	if head&isString != 0 || len(r.buffer)-r.head < maxFixedRecord {
+		if _, ok := sizeOfRecord(r.buffer[r.head:]); !ok {
+			r.cutoff = true
+			return false
+		}
+	}
 	switch head & 0xc0 {
 
 	// If this is a variable-size value but not a next neighbour, read the
@@ -265,6 +467,13 @@ func (r *Reader) Next() bool {
 	// If the first bit is set, this means that the delta is one and we
 	// can skip reading the actual offset. (special case)
 	case isNext:
+		if head&isRepeat != 0 {
+			r.head++ // Repeat of the previous value, keep its position
+			r.Type = Put
+			r.Offset++
+			return true
+		}
+
 		r.readFixed(head)
 		r.Offset++
 		return true
@@ -278,6 +487,60 @@ func (r *Reader) Next() bool {
 	}
 }
 
+// Uint64s reads the values of the remaining records into dst and returns the extended slice.
+// It stops before the first record which does not hold an 8-byte fixed-size value, so the
+// remaining records can still be read with Next. The reader is left on the last record read.
+// Records are neither padded nor aligned, so the values are still decoded one record at a time.
+func (r *Reader) Uint64s(dst []uint64) []uint64 {
+	for r.head < len(r.buffer) {
+		switch head := r.buffer[r.head]; {
+		case head&isRepeat != 0 && r.i1-r.i0 == 8:
+		case head&isString != 0 || head>>4&0b11 != 0b11:
+			return dst
+		}
+
+		r.Next()
+		dst = append(dst, binary.BigEndian.Uint64(r.buffer[r.i0:r.i1]))
+	}
+	return dst
+}
+
+// maxFixedRecord is the maximum size of a record with a fixed-size value
+const maxFixedRecord = 1 + 8 + binary.MaxVarintLen32
+
+// sizeOfRecord returns the size of the record at the start of the buffer, or false if the
+// buffer ends before the record does.
+func sizeOfRecord(b []byte) (int, bool) {
+	head := b[0]
+	size := 1
+	switch {
+	case head&isString != 0:
+		if len(b) < 3 {
+			return 0, false
+		}
+		size = 3 + (int(b[2]) | int(b[1])<<8)
+	case head&isNext != 0 && head&isRepeat != 0:
+		return 1, true
+	default:
+		size += int(1 << (head >> 4 & 0b11) & 0b1110)
+	}
+
+	// Unless this is the next neighbour, the offset follows as a variable-size integer
+	if head&isNext == 0 {
+		for i := 0; i < binary.MaxVarintLen32; i++ {
+			if size >= len(b) {
+				return 0, false
+			}
+
+			size++
+			if b[size-1] < 0x80 {
+				break
+			}
+		}
+	}
+	return size, size <= len(b)
+}
+
 // readOffset reads the signed variable-size integer at the current tail. While
 // this is a signed integer, it is encoded as a variable-size unsigned integer.
 // This would lead to negative values not being packed well, but given the
@@ -342,3 +605,43 @@ func (r *Reader) readString(v byte) {
 	r.i1 = r.head
 	r.Type = OpType(v & 0xf)
 }
+
+// --------------------------- Profile ----------------------------
+
+// ReaderProfile represents the decoding throughput of a reader over a buffer.
+type ReaderProfile struct {
+	Records int           // The number of records decoded
+	Bytes   int           // The number of bytes decoded
+	Elapsed time.Duration // The time spent decoding the records
+	Counts  [4]int        // The number of records of each operation type, indexed by OpType
+}
+
+// RecordsPerSec returns the number of records decoded per second
+func (p *ReaderProfile) RecordsPerSec() float64 {
+	return float64(p.Records) / p.Elapsed.Seconds()
+}
+
+// BytesPerSec returns the number of bytes decoded per second
+func (p *ReaderProfile) BytesPerSec() float64 {
+	return float64(p.Bytes) / p.Elapsed.Seconds()
+}
+
+// Profile decodes all of the records of the buffer and measures the decoding throughput.
+// The records are first decoded without any bookkeeping other than counting them, so the
+// time measured reflects the cost of decoding, then counted per operation type in a second
+// pass which is not timed. The reader is left at the end of the buffer.
+func (r *Reader) Profile(buf *Buffer) (profile ReaderProfile) {
+	r.Seek(buf)
+	start := time.Now()
+	for r.Next() {
+		profile.Records++
+	}
+	profile.Elapsed = time.Since(start)
+	profile.Bytes = len(buf.buffer)
+
+	r.Seek(buf)
+	for r.Next() {
+		profile.Counts[r.Type&0x3]++
+	}
+	return
+}
@@ -4,6 +4,8 @@
 package commit
 
 import (
+	"bytes"
+	"math"
 	"math/rand"
 	"testing"
 	"time"
@@ -19,7 +21,8 @@ func TestQueue(t *testing.T) {
 	}
 
 	i := 0
-	assert.Equal(t, 91, len(buf.buffer))
+	assert.Equal(t, 91, buf.Len())
+	assert.GreaterOrEqual(t, buf.Cap(), buf.Len())
 
 	r := NewReader()
 	for r.Seek(buf); r.Next(); {
@@ -74,6 +77,69 @@ func TestRange(t *testing.T) {
 	}
 }
 
+func TestRangeNamed(t *testing.T) {
+	buf := NewBuffer(0)
+	buf.Reset("test")
+	buf.PutUint32(10, 1)
+	buf.PutUint32(1<<chunkShift, 2)
+
+	count := 0
+	r := NewReader()
+	r.RangeNamed(buf, "other", func(r *Reader) {
+		count++
+	})
+	assert.Equal(t, 0, count)
+
+	r.RangeNamed(buf, "test", func(r *Reader) {
+		for r.Next() {
+			count++
+		}
+	})
+	assert.Equal(t, 2, count)
+}
+
+func TestDiscard(t *testing.T) {
+	buf := NewBuffer(0)
+	buf.Reset("test")
+	for i := uint32(0); i < 1000; i++ {
+		idx := i * uint32(1+i%7) * 50
+		switch i % 5 {
+		case 0:
+			buf.PutBool(idx, true)
+		case 1:
+			buf.PutUint16(idx, uint16(i))
+		case 2:
+			buf.PutUint32(idx, i)
+		case 3:
+			buf.PutUint64(idx, uint64(i))
+		default:
+			buf.PutString(Put, idx, "hello")
+		}
+	}
+
+	expect, last := 0, int32(0)
+	r := NewReader()
+	for r.Seek(buf); r.Next(); expect++ {
+		last = r.Offset
+	}
+
+	// Discarding must count the same records as a full iteration, also once decoded
+	assert.Equal(t, 1000, expect)
+	assert.Equal(t, expect, r.Discard(buf))
+	assert.Equal(t, last, r.Offset)
+	assert.False(t, r.Next())
+
+	encoded := bytes.NewBuffer(nil)
+	_, err := buf.WriteTo(encoded)
+	assert.NoError(t, err)
+
+	clone := NewBuffer(0)
+	_, err = clone.ReadFrom(encoded)
+	assert.NoError(t, err)
+	assert.Equal(t, expect, r.Discard(clone))
+	assert.Equal(t, 0, r.Discard(NewBuffer(0)))
+}
+
 func TestReadSwap(t *testing.T) {
 	buf := NewBuffer(0)
 	buf.PutAny(Put, 10, int16(100))
@@ -177,10 +243,93 @@ func TestReadSwap(t *testing.T) {
 	assert.Equal(t, float64(800), r.Float64())
 }
 
+func TestSwapAddition(t *testing.T) {
+	buf := NewBuffer(0)
+	buf.AddInt64(10, 1)
+	buf.AddFloat32(11, 2)
+
+	r := NewReader()
+	r.Seek(buf)
+	assert.True(t, r.Next())
+	assert.Equal(t, Add, r.Type)
+	r.SwapInt64(5)
+	assert.Equal(t, Put, r.Type)
+
+	// Once swapped, the additions must be read back as puts
+	r.Seek(buf)
+	assert.True(t, r.Next())
+	assert.Equal(t, Put, r.Type)
+	assert.Equal(t, int64(5), r.Int64())
+	assert.True(t, r.Next())
+	assert.Equal(t, Add, r.Type)
+	assert.Equal(t, float32(2), r.Float32())
+}
+
+func TestReadBytes(t *testing.T) {
+	buf := NewBuffer(0)
+	buf.PutBytes(Put, 10, []byte("hello"))
+
+	r := NewReader()
+	r.Seek(buf)
+	assert.True(t, r.Next())
+
+	// The copy must not alias the buffer
+	copied := r.Bytes()
+	copied[0] = 'j'
+	assert.Equal(t, "hello", string(r.BytesRef()))
+	assert.Equal(t, "jello", string(copied))
+
+	// The reference must alias the buffer
+	ref := r.BytesRef()
+	ref[0] = 'y'
+	assert.Equal(t, "yello", string(r.Bytes()))
+}
+
+func TestReadString(t *testing.T) {
+	buf := NewBuffer(0)
+	buf.PutString(Put, 10, "hello")
+
+	r := NewReader()
+	r.Seek(buf)
+	assert.True(t, r.Next())
+	assert.Equal(t, "hello", r.String())
+	assert.Zero(t, testing.AllocsPerRun(100, func() {
+		_ = r.String()
+	}))
+
+	// The string aliases the buffer
+	value := r.String()
+	r.BytesRef()[0] = 'j'
+	assert.Equal(t, "jello", value)
+}
+
+func TestReadBytesAppend(t *testing.T) {
+	buf := NewBuffer(0)
+	buf.PutBytes(Put, 10, []byte("hello"))
+	buf.PutBytes(Put, 11, []byte{})
+	buf.PutBytes(Put, 12, []byte("world"))
+
+	r := NewReader()
+	r.Seek(buf)
+	scratch := make([]byte, 0, 64)
+	for r.Next() {
+		out := r.BytesAppend(scratch[:0])
+		assert.Equal(t, r.Bytes(), out)
+	}
+
+	// The value is appended after the existing content, without allocating
+	r.Seek(buf)
+	assert.True(t, r.Next())
+	assert.Equal(t, "> hello", string(r.BytesAppend([]byte("> "))))
+	assert.Zero(t, testing.AllocsPerRun(100, func() {
+		scratch = r.BytesAppend(scratch[:0])
+	}))
+}
+
 func TestWriteUnsupported(t *testing.T) {
 	assert.Panics(t, func() {
 		buf := NewBuffer(0)
-		buf.PutAny(Put, 10, time.Time{})
+		buf.PutAny(Put, 10, complex(1, 2))
 	})
 }
 
@@ -234,6 +383,27 @@ func TestReadFloatMixedSize(t *testing.T) {
 	})
 }
 
+func TestReadFloat32Narrow(t *testing.T) {
+	buf := NewBuffer(0)
+	buf.PutFloat32(0, 1.5)
+	buf.PutFloat64(1, 0.1)
+	buf.PutFloat64(2, math.MaxFloat64)
+	buf.PutString(Put, 3, "hello")
+
+	r := NewReader()
+	r.Seek(buf)
+	assert.True(t, r.Next())
+	assert.Equal(t, float32(1.5), r.Float32Narrow())
+	assert.True(t, r.Next())
+	assert.Equal(t, float32(0.1), r.Float32Narrow())
+	assert.True(t, r.Next())
+	assert.True(t, math.IsInf(float64(r.Float32Narrow()), 1))
+	assert.True(t, r.Next())
+	assert.Panics(t, func() {
+		r.Float32Narrow()
+	})
+}
+
 func TestReadSize(t *testing.T) {
 	buf := NewBuffer(0)
 	buf.Reset("test")
@@ -243,3 +413,233 @@ func TestReadSize(t *testing.T) {
 	r.readFixed(buf.buffer[0])
 	assert.Equal(t, 0, r.i1-r.i0)
 }
+
+func TestReadUint64s(t *testing.T) {
+	buf := NewBuffer(0)
+	for i := uint32(0); i < 100; i++ {
+		buf.PutUint64(i*3, uint64(i))
+	}
+	buf.PutUint32(500, 42)
+	buf.PutUint64(501, 7)
+
+	r := NewReader()
+	r.Seek(buf)
+	values := r.Uint64s(nil)
+	assert.Len(t, values, 100)
+	for i, v := range values {
+		assert.Equal(t, uint64(i), v)
+	}
+
+	// The reader stops on the last value read and the rest can be read as usual
+	assert.Equal(t, int32(297), r.Offset)
+	assert.True(t, r.Next())
+	assert.Equal(t, uint32(42), r.Uint32())
+	assert.Equal(t, []uint64{7}, r.Uint64s(nil))
+	assert.Equal(t, int32(501), r.Offset)
+	assert.False(t, r.Next())
+	assert.Empty(t, r.Uint64s(nil))
+}
+
+func TestReadDelete(t *testing.T) {
+	buf := NewBuffer(0)
+	buf.PutUint64(10, 1)
+	buf.PutDelete(11)
+	buf.PutDelete(200)
+	buf.PutUint64(201, 2)
+
+	r := NewReader()
+	r.Seek(buf)
+	assert.True(t, r.Next())
+	assert.Equal(t, Put, r.Type)
+	assert.Equal(t, uint64(1), r.Uint64())
+
+	// Deletions carry no value and the reader advances past them
+	assert.True(t, r.Next())
+	assert.Equal(t, Delete, r.Type)
+	assert.Equal(t, uint32(11), r.Index())
+	assert.Empty(t, r.Bytes())
+	assert.True(t, r.Next())
+	assert.Equal(t, Delete, r.Type)
+	assert.Equal(t, uint32(200), r.Index())
+
+	assert.True(t, r.Next())
+	assert.Equal(t, Put, r.Type)
+	assert.Equal(t, uint32(201), r.Index())
+	assert.Equal(t, uint64(2), r.Uint64())
+	assert.False(t, r.Next())
+}
+
+func TestReadSkipEmpty(t *testing.T) {
+	buf := NewBuffer(0)
+	buf.PutUint64(10, 1)
+	buf.PutBool(11, true)
+	buf.PutDelete(12)
+	buf.PutBytes(Put, 13, []byte{})
+	buf.PutString(Put, 14, "hello")
+	buf.PutBool(200, false)
+
+	// By default, every record is read
+	r := NewReader()
+	r.Seek(buf)
+	count := 0
+	for r.Next() {
+		count++
+	}
+	assert.Equal(t, 6, count)
+
+	// Records without a value are skipped, but offsets are still tracked
+	r.SkipEmpty(true)
+	r.Seek(buf)
+	assert.True(t, r.Next())
+	assert.Equal(t, uint32(10), r.Index())
+	assert.Equal(t, uint64(1), r.Uint64())
+	assert.True(t, r.Next())
+	assert.Equal(t, uint32(14), r.Index())
+	assert.Equal(t, "hello", r.String())
+	assert.False(t, r.Next())
+}
+
+func TestReadMark(t *testing.T) {
+	buf := NewBuffer(0)
+	for i := uint32(0); i < 5; i++ {
+		buf.PutUint64(i*100, uint64(i))
+	}
+
+	r := NewReader()
+	r.Seek(buf)
+	start := r.Mark()
+	assert.True(t, r.Next())
+	assert.True(t, r.Next())
+	second := r.Mark()
+
+	// Read until the end, then go back to the second record
+	for r.Next() {
+	}
+
+	r.Reset(second)
+	assert.Equal(t, uint32(100), r.Index())
+	assert.Equal(t, uint64(1), r.Uint64())
+	assert.True(t, r.Next())
+	assert.Equal(t, uint32(200), r.Index())
+	assert.Equal(t, uint64(2), r.Uint64())
+
+	// Positions can be restored several times and in any order
+	r.Reset(start)
+	assert.True(t, r.Next())
+	assert.Equal(t, uint32(0), r.Index())
+	r.Reset(second)
+	assert.Equal(t, uint64(1), r.Uint64())
+}
+
+func TestReadPeekType(t *testing.T) {
+	buf := NewBuffer(0)
+	buf.PutUint64(10, 1)
+	buf.PutDelete(11)
+	buf.AddUint64(12, 2)
+
+	r := NewReader()
+	r.Seek(buf)
+	for _, expect := range []OpType{Put, Delete, Add} {
+		op, ok := r.PeekType()
+		assert.True(t, ok)
+		assert.Equal(t, expect, op)
+		assert.True(t, r.Next())
+		assert.Equal(t, expect, r.Type)
+	}
+
+	// The current record is left untouched, even at the end
+	_, ok := r.PeekType()
+	assert.False(t, ok)
+	assert.Equal(t, uint32(12), r.Index())
+	assert.Equal(t, uint64(2), r.Uint64())
+}
+
+func TestReadIncomplete(t *testing.T) {
+	buf := NewBuffer(0)
+	buf.EncodeRuns(true)
+	buf.PutUint64(10, 1)
+	buf.PutUint64(11, 1)
+	buf.PutString(Put, 300, "hello")
+	buf.PutString(Put, 301, "world")
+	buf.PutBool(100000, true)
+	buf.PutInt16(5, -1)
+	buf.AddFloat64(6, 1.5)
+
+	// Find where each of the records ends
+	r := NewReader()
+	r.Seek(buf)
+	var ends []int
+	for r.Next() {
+		ends = append(ends, r.head)
+	}
+	assert.False(t, r.Incomplete())
+	assert.Equal(t, len(buf.buffer), ends[len(ends)-1])
+
+	// A buffer cut short reads the complete records only, and flags the one cut
+	for size := 0; size <= len(buf.buffer); size++ {
+		r.use(buf.buffer[:size])
+		count := 0
+		for r.Next() {
+			count++
+		}
+
+		expect := 0
+		for expect < len(ends) && ends[expect] <= size {
+			expect++
+		}
+
+		assert.Equal(t, expect, count)
+		assert.Equal(t, size > 0 && (count == 0 || ends[count-1] != size), r.Incomplete())
+	}
+}
+
+func TestReadProfile(t *testing.T) {
+	buf := NewBuffer(0)
+	for i := uint32(0); i < 1000; i++ {
+		buf.PutUint64(i, uint64(i))
+	}
+	buf.AddInt32(5, 1)
+	buf.PutOperation(Delete, 6)
+	buf.PutOperation(Insert, 7)
+
+	r := NewReader()
+	profile := r.Profile(buf)
+	assert.Equal(t, 1003, profile.Records)
+	assert.Equal(t, len(buf.buffer), profile.Bytes)
+	assert.Equal(t, [4]int{1, 1, 1000, 1}, profile.Counts)
+	assert.Greater(t, profile.Elapsed, time.Duration(0))
+	assert.Greater(t, profile.RecordsPerSec(), 0.0)
+	assert.Greater(t, profile.BytesPerSec(), 0.0)
+	assert.False(t, r.Next())
+}
+
+func TestRangeAll(t *testing.T) {
+	buf := NewBuffer(0)
+	buf.PutUint32(10, 1)
+	buf.PutUint32(11, 2)
+	buf.PutUint32(3<<chunkShift, 3)
+	buf.PutUint32(1<<chunkShift, 4)
+	buf.PutUint32(12, 5)
+
+	// Every part is visited in the order it was written, starting from its first record
+	var chunks []int32
+	var values []uint32
+	r := NewReader()
+	r.RangeAll(buf, func(r *Reader) {
+		for i := 0; r.Next(); i++ {
+			if i == 0 {
+				chunks = append(chunks, r.Offset>>chunkShift)
+			}
+			values = append(values, r.Uint32())
+		}
+	})
+	assert.Equal(t, []int32{0, 3, 1, 0}, chunks)
+	assert.Equal(t, []uint32{1, 2, 3, 4, 5}, values)
+
+	// An empty buffer has no parts
+	count := 0
+	r.RangeAll(NewBuffer(0), func(r *Reader) {
+		count++
+	})
+	assert.Zero(t, count)
+}
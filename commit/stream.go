@@ -0,0 +1,215 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package commit
+
+import (
+	"encoding/binary"
+	"io"
+
+	"github.com/kelindar/iostream"
+)
+
+// streamWindow is the initial size of the window of a stream reader, in bytes
+const streamWindow = 4096
+
+// StreamReader reads the records of a stream of buffers, each written with Buffer.WriteTo,
+// one record at a time. Unlike a Reader, the buffers are not read in memory entirely, only
+// a small window of bytes is kept, which is large enough to hold a single record. Hence it
+// can be used to replay a stream which is much larger than the memory available.
+type StreamReader struct {
+	Column string           // The column of the buffer being read
+	src    *iostream.Reader // The source stream
+	reader Reader           // The reader of the window
+	window []byte           // The window of bytes read from the buffer
+	remain int              // The number of bytes of the buffer not read yet
+	schema []ColumnDef      // The schema header of the buffer being read
+	err    error            // The error encountered, if any
+}
+
+// NewStreamReader creates a new reader for a stream of buffers.
+func NewStreamReader(src io.Reader) *StreamReader {
+	return &StreamReader{
+		src:    iostream.NewReader(src),
+		window: make([]byte, 0, streamWindow),
+	}
+}
+
+// Next reads the next record of the stream, moving on to the next buffer when the current
+// one was read entirely. It returns false at the end of the stream or if an error occurs,
+// in which case Err returns it.
+func (s *StreamReader) Next() bool {
+	for s.err == nil {
+		if s.reader.head < len(s.reader.buffer) || s.remain > 0 {
+			return s.fillRecord() && s.reader.next()
+		}
+
+		if s.err = s.readHeader(); s.err == io.EOF {
+			s.err = nil
+			return false
+		}
+	}
+	return false
+}
+
+// Op returns the reader positioned on the current record, whose getters read the values
+// of the record. The reader is only valid until the next call to Next and must not be
+// advanced, nor its values retained without being copied.
+func (s *StreamReader) Op() *Reader {
+	return &s.reader
+}
+
+// Schema returns the schema header of the buffer being read, or nil if the buffer does
+// not have one.
+func (s *StreamReader) Schema() []ColumnDef {
+	return s.schema
+}
+
+// Err returns the error encountered while reading the stream, if any. A stream which ends
+// in the middle of a buffer returns io.ErrUnexpectedEOF.
+func (s *StreamReader) Err() error {
+	return s.err
+}
+
+// readHeader reads the header of the next buffer, up to its records, same as ReadFrom
+func (s *StreamReader) readHeader() (err error) {
+	if s.Column, err = s.src.ReadString(); err != nil {
+		return err
+	}
+
+	// The chunk headers are not required to read the records, so the shift is ignored
+	s.schema = nil
+	if s.Column == shiftMarker {
+		if _, err = s.src.ReadUvarint(); err != nil {
+			return unexpected(err)
+		}
+		if s.Column, err = s.src.ReadString(); err != nil {
+			return unexpected(err)
+		}
+	}
+
+	if s.Column == schemaMarker {
+		if s.schema, err = readSchemaFrom(s.src); err != nil {
+			return unexpected(err)
+		}
+		if s.Column, err = s.src.ReadString(); err != nil {
+			return unexpected(err)
+		}
+	}
+
+	if _, err = s.src.ReadInt32(); err != nil {
+		return unexpected(err)
+	}
+
+	// Skip the chunk headers, the offsets of the records are encoded relative to each other
+	chunks, err := s.src.ReadUvarint()
+	if err != nil {
+		return unexpected(err)
+	}
+	if _, err = io.CopyN(io.Discard, s.src, int64(chunks)*12); err != nil {
+		return unexpected(err)
+	}
+
+	size, err := s.src.ReadUvarint()
+	if err != nil {
+		return unexpected(err)
+	}
+
+	s.remain = int(size)
+	s.window = s.window[:0]
+	s.reader.use(s.window)
+	s.reader.schema = nil
+	if len(s.schema) > 0 {
+		s.reader.schema = &s.schema
+	}
+	return nil
+}
+
+// fillRecord makes sure that the entire record at the read position is in the window
+func (s *StreamReader) fillRecord() bool {
+	if !s.fill(1) {
+		return false
+	}
+
+	r := &s.reader
+	head := r.buffer[r.head]
+	size := 1
+	switch {
+	case head&isString != 0:
+		if !s.fill(3) {
+			return false
+		}
+		size = 3 + (int(r.buffer[r.head+2]) | int(r.buffer[r.head+1])<<8)
+	case head&isNext != 0 && head&isRepeat != 0:
+		return true // Repeat of the previous value, which is kept in the window
+	default:
+		size += int(1 << (head >> 4 & 0b11) & 0b1110)
+	}
+
+	// The offset is encoded as a variable-size integer, unless this is the next neighbour
+	if head&isNext == 0 {
+		size += binary.MaxVarintLen32
+	}
+
+	if available := len(r.buffer) - r.head + s.remain; size > available {
+		size = available
+	}
+	return s.fill(size)
+}
+
+// fill makes sure that at least n bytes are available in the window after the read position,
+// reading more of the buffer if required. The previous value is kept in the window since a
+// repeated record refers to it.
+func (s *StreamReader) fill(n int) bool {
+	r := &s.reader
+	if len(r.buffer)-r.head >= n {
+		return true
+	}
+
+	if n > len(r.buffer)-r.head+s.remain {
+		s.err = io.ErrUnexpectedEOF
+		return false
+	}
+
+	// Move the bytes still required to the front of the window
+	from := r.head
+	if r.i0 < from {
+		from = r.i0
+	}
+
+	s.window = s.window[:copy(s.window, r.buffer[from:])]
+	r.head -= from
+	r.i0 -= from
+	r.i1 -= from
+
+	// Grow the window if the record does not fit, and read as much of the buffer as it can hold
+	if need := r.head + n; need > cap(s.window) {
+		window := make([]byte, len(s.window), 2*need)
+		copy(window, s.window)
+		s.window = window
+	}
+
+	size := cap(s.window) - len(s.window)
+	if size > s.remain {
+		size = s.remain
+	}
+
+	read, err := io.ReadFull(s.src, s.window[len(s.window):len(s.window)+size])
+	s.window = s.window[:len(s.window)+read]
+	s.remain -= read
+	r.buffer = s.window
+	if err != nil {
+		s.err = unexpected(err)
+		return false
+	}
+	return true
+}
+
+// unexpected converts the end of the stream into an unexpected one, for the errors which
+// occur in the middle of a buffer.
+func unexpected(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
@@ -0,0 +1,67 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package commit
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStreamReader(t *testing.T) {
+	a := NewBuffer(0)
+	a.Reset("a")
+	a.WriteSchema([]ColumnDef{{Name: "a"}})
+	for i := uint32(0); i < 2000; i++ {
+		a.PutUint64(i*10, uint64(i))
+	}
+
+	b := NewBuffer(0)
+	b.Reset("b")
+	b.EncodeRuns(true)
+	for i := uint32(0); i < 100; i++ {
+		b.PutUint32(i, 7)
+	}
+	b.PutString(Put, 40000, strings.Repeat("x", 10000))
+	b.PutBool(40001, true)
+	b.PutDelete(5)
+	b.AddInt16(chunkSize*3, -2)
+
+	empty := NewBuffer(0)
+	empty.Reset("empty")
+
+	var stream bytes.Buffer
+	for _, buf := range []*Buffer{a, empty, b} {
+		_, err := buf.WriteTo(&stream)
+		assert.NoError(t, err)
+	}
+
+	// Records straddle the reads since the stream is read one byte at a time
+	s := NewStreamReader(iotest.OneByteReader(bytes.NewReader(stream.Bytes())))
+	for _, buf := range []*Buffer{a, b} {
+		r := NewReader()
+		r.Seek(buf)
+		for r.Next() {
+			assert.True(t, s.Next())
+			assert.Equal(t, buf.Column, s.Column)
+			assert.Equal(t, r.Schema(), s.Schema())
+			assert.Equal(t, r.Type, s.Op().Type)
+			assert.Equal(t, r.Index(), s.Op().Index())
+			assert.Equal(t, r.Bytes(), s.Op().Bytes())
+		}
+	}
+
+	assert.False(t, s.Next())
+	assert.NoError(t, s.Err())
+
+	// A stream which ends in the middle of a buffer fails
+	s = NewStreamReader(bytes.NewReader(stream.Bytes()[:stream.Len()-100]))
+	for s.Next() {
+	}
+	assert.Equal(t, io.ErrUnexpectedEOF, s.Err())
+}
@@ -0,0 +1,287 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package column
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sync"
+
+	"github.com/kelindar/column/commit"
+)
+
+// FetchByKey reads the object whose properties are equal to the specified values, using a
+// composite key which joins the values of these properties together. The composite key is
+// built on the first lookup of a set of properties and maintained on every commit after that.
+// Values are compared by their textual representation, so an int can be used to look up an
+// int64 column. Keys are expected to be unique, if several objects share a key only the one
+// written last can be found.
+func (c *Collection) FetchByKey(keyProps []string, values []interface{}) (Object, bool) {
+	if len(keyProps) == 0 || len(keyProps) != len(values) {
+		return nil, false
+	}
+
+	key, ok := c.compositeKeyOf(keyProps)
+	if !ok {
+		return nil, false
+	}
+
+	value := encodeKey(values)
+	idx, ok := key.OffsetOf(value)
+	if !ok {
+		return nil, false
+	}
+
+	// The object might have changed since the lookup, so it needs to be checked again
+	chunk := commit.ChunkAt(idx)
+	c.slock.RLock(uint(chunk))
+	defer c.slock.RUnlock(uint(chunk))
+	if current, ok := key.OffsetOf(value); !ok || current != idx {
+		return nil, false
+	}
+
+	object := make(Object, c.cols.Count())
+	c.readObject(idx, object)
+	return object, true
+}
+
+// BulkUpsert updates the objects whose value of the key column matches the one of an object
+// of the batch and inserts the others, in a single transaction. Existing objects are found
+// using the composite key of the column, as in FetchByKey, hence its values are expected to
+// be unique. If several objects of the batch share a key, only the last one is written, and
+// objects without a key are always inserted. If the key column does not exist, nothing is.
+func (c *Collection) BulkUpsert(keyCol string, objs []Object) (inserted, updated int) {
+	key, ok := c.compositeKeyOf([]string{keyCol})
+	if !ok {
+		return 0, 0
+	}
+
+	// Find the last object of the batch for each key, so the others can be skipped
+	last := make(map[string]int, len(objs))
+	for i, obj := range objs {
+		if v, ok := obj[keyCol]; ok && v != nil {
+			last[encodeKey([]interface{}{v})] = i
+		}
+	}
+
+	c.Query(func(txn *Txn) error {
+		for i, obj := range objs {
+			if v, ok := obj[keyCol]; ok && v != nil {
+				value := encodeKey([]interface{}{v})
+				if last[value] != i {
+					continue
+				}
+
+				if idx, ok := key.OffsetOf(value); ok && txn.Update(idx, obj) {
+					updated++
+					continue
+				}
+			}
+
+			if _, err := txn.InsertObject(obj); err == nil {
+				inserted++
+			}
+		}
+		return nil
+	})
+	return
+}
+
+// Upsert updates the object whose value of the key column is equal to the specified value
+// with the values of obj, or inserts obj if there is no such object, and returns its index.
+// The key column must be present in obj with the same value, otherwise the object would no
+// longer match it. If the key column is the primary key, the object is found with a lookup,
+// otherwise the column is scanned and if several objects match, the lowest index is updated.
+func (c *Collection) Upsert(key string, value interface{}, obj Object) (index uint32) {
+	c.Query(func(txn *Txn) error {
+		if idx, ok := txn.upsertIndexOf(key, value); ok && txn.Update(idx, obj) {
+			index = idx
+			return nil
+		}
+
+		index, _ = txn.InsertObject(obj)
+		return nil
+	})
+	return
+}
+
+// upsertIndexOf finds the lowest index of the objects whose value of the key column is equal
+// to the specified value.
+func (txn *Txn) upsertIndexOf(key string, value interface{}) (uint32, bool) {
+	if pk := txn.owner.pk; pk != nil && pk.name == key {
+		if v, ok := value.(string); ok {
+			return pk.OffsetOf(v)
+		}
+	}
+
+	txn.WhereEq(key, value)
+	idx, ok := txn.index.Min()
+	return idx, ok
+}
+
+// compositeKeyOf returns the composite key for a set of properties, building it if required
+func (c *Collection) compositeKeyOf(props []string) (*compositeKey, bool) {
+	for _, name := range props {
+		if column, ok := c.cols.Load(name); !ok || column.IsIndex() {
+			return nil, false
+		}
+	}
+
+	name := encodeKey(toInterfaces(props))
+	c.keys.lock.Lock()
+	if key, ok := c.keys.list[name]; ok {
+		c.keys.lock.Unlock()
+		return key, true
+	}
+
+	// Register the key first, so it is maintained by every commit from this point on
+	key := &compositeKey{
+		props: append([]string(nil), props...),
+		seek:  make(map[string]uint32, 64),
+		value: make(map[uint32]string, 64),
+	}
+
+	if c.keys.list == nil {
+		c.keys.list = make(map[string]*compositeKey, 1)
+	}
+	c.keys.list[name] = key
+	c.keys.lock.Unlock()
+
+	// Build the key from the objects which were committed before. Since refreshing an index
+	// is idempotent, a commit racing with this does not leave a stale entry behind.
+	c.lock.RLock()
+	chunks := len(c.commits)
+	c.lock.RUnlock()
+	for chunk := commit.Chunk(0); chunk < commit.Chunk(chunks); chunk++ {
+		c.slock.RLock(uint(chunk))
+		c.lock.RLock()
+		fill := chunk.OfBitmap(c.fill)
+		c.lock.RUnlock()
+
+		offset := chunk.Min()
+		fill.Range(func(x uint32) {
+			key.refresh(c, offset+x)
+		})
+		c.slock.RUnlock(uint(chunk))
+	}
+
+	return key, true
+}
+
+// commitKeys refreshes the composite keys of every object changed in the chunk. The caller
+// must hold the write latch of the chunk, after the updates have been applied.
+func (txn *Txn) commitKeys(chunk commit.Chunk, markers *commit.Buffer) {
+	txn.owner.keys.lock.RLock()
+	defer txn.owner.keys.lock.RUnlock()
+	if len(txn.owner.keys.list) == 0 {
+		return
+	}
+
+	for _, key := range txn.owner.keys.list {
+		for _, u := range txn.updates {
+			if u.IsEmpty() || (u != markers && !key.contains(u.Column)) {
+				continue
+			}
+
+			txn.reader.Range(u, chunk, func(r *commit.Reader) {
+				for r.Next() {
+					key.refresh(txn.owner, r.Index())
+				}
+			})
+		}
+	}
+}
+
+// --------------------------- Composite Key ----------------------------
+
+// compositeKeys represents a set of composite keys of a collection
+type compositeKeys struct {
+	lock sync.RWMutex             // The lock to protect the list
+	list map[string]*compositeKey // The composite keys, by their properties
+}
+
+// compositeKey represents a lookup table of the joined values of multiple properties
+type compositeKey struct {
+	lock  sync.RWMutex      // The lock to protect the lookup tables
+	props []string          // The properties joined together
+	seek  map[string]uint32 // The lookup table of the index by key
+	value map[uint32]string // The lookup table of the key by index
+}
+
+// contains returns whether the property is part of the key
+func (k *compositeKey) contains(prop string) bool {
+	for _, v := range k.props {
+		if v == prop {
+			return true
+		}
+	}
+	return false
+}
+
+// OffsetOf returns the offset for a particular key
+func (k *compositeKey) OffsetOf(v string) (uint32, bool) {
+	k.lock.RLock()
+	idx, ok := k.seek[v]
+	k.lock.RUnlock()
+	return idx, ok
+}
+
+// refresh recomputes the key of the object at the index from its current values. The
+// caller must hold a latch of the chunk.
+func (k *compositeKey) refresh(owner *Collection, idx uint32) {
+	owner.lock.RLock()
+	exists := owner.fill.Contains(idx)
+	owner.lock.RUnlock()
+
+	// Read the values of the properties, an object without all of them has no key
+	var values []interface{}
+	for _, name := range k.props {
+		column, ok := owner.cols.Load(name)
+		if !exists || !ok {
+			break
+		}
+
+		if v, ok := column.Value(idx); ok {
+			values = append(values, v)
+		}
+	}
+
+	k.lock.Lock()
+	defer k.lock.Unlock()
+	if prev, ok := k.value[idx]; ok {
+		if k.seek[prev] == idx {
+			delete(k.seek, prev)
+		}
+		delete(k.value, idx)
+	}
+
+	if len(values) == len(k.props) {
+		value := encodeKey(values)
+		k.seek[value] = idx
+		k.value[idx] = value
+	}
+}
+
+// encodeKey joins the values together, each prefixed with its length, so that distinct
+// tuples of values are always encoded into distinct keys.
+func encodeKey(values []interface{}) string {
+	var temp [binary.MaxVarintLen64]byte
+	out := make([]byte, 0, 16*len(values))
+	for _, v := range values {
+		s := fmt.Sprint(v)
+		n := binary.PutUvarint(temp[:], uint64(len(s)))
+		out = append(out, temp[:n]...)
+		out = append(out, s...)
+	}
+	return string(out)
+}
+
+// toInterfaces converts a slice of strings into a slice of interfaces
+func toInterfaces(values []string) []interface{} {
+	out := make([]interface{}, 0, len(values))
+	for _, v := range values {
+		out = append(out, v)
+	}
+	return out
+}
@@ -1,6 +1,6 @@
 module github.com/kelindar/column
 
-go 1.17
+go 1.18
 
 require (
 	github.com/kelindar/bitmap v1.1.5
@@ -0,0 +1,196 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package column
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/kelindar/bitmap"
+	"github.com/kelindar/column/commit"
+)
+
+var (
+	errNoHistory = errors.New("column: collection does not retain any history")
+)
+
+// --------------------------- History ----------------------------
+
+// history represents a bounded log of operations for the most recent versions of the
+// collection. It is used both for the inverse operations, which allow to roll back the
+// collection, and for the journal of operations used by incremental snapshots.
+type history struct {
+	lock    sync.Mutex     // The lock to protect the entries
+	size    int            // The maximum number of versions retained
+	entries []historyEntry // The entries, ordered by version
+}
+
+// historyEntry represents the operations of a single version
+type historyEntry struct {
+	version uint64           // The version produced by the commit
+	updates []*commit.Buffer // The operations
+}
+
+// newHistory creates a new history retaining the specified number of versions
+func newHistory(size int) *history {
+	return &history{
+		size:    size,
+		entries: make([]historyEntry, 0, size),
+	}
+}
+
+// Append adds the operations for a specific version into the history and
+// evicts the oldest entry if the history is full.
+func (h *history) Append(version uint64, updates []*commit.Buffer) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	// Concurrent commits may complete out of order, keep entries sorted by version
+	h.entries = append(h.entries, historyEntry{
+		version: version,
+		updates: updates,
+	})
+	for i := len(h.entries) - 1; i > 0 && h.entries[i-1].version > version; i-- {
+		h.entries[i-1], h.entries[i] = h.entries[i], h.entries[i-1]
+	}
+
+	if len(h.entries) > h.size {
+		copy(h.entries, h.entries[1:])
+		h.entries = h.entries[:h.size]
+	}
+}
+
+// Since returns the entries for all of the versions after the specified one, up to
+// the current version. If some of these versions are no longer retained, an error
+// is returned instead.
+func (h *history) Since(version, current uint64) ([]historyEntry, error) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	if version > current {
+		return nil, fmt.Errorf("column: version %d does not exist", version)
+	}
+
+	for i, entry := range h.entries {
+		if entry.version <= version {
+			continue
+		}
+
+		if entry.version != version+1 {
+			break
+		}
+
+		out := make([]historyEntry, len(h.entries)-i)
+		copy(out, h.entries[i:])
+		return out, nil
+	}
+
+	if version == current {
+		return nil, nil
+	}
+
+	return nil, fmt.Errorf("column: version %d is no longer retained", version)
+}
+
+// --------------------------- Rollback ----------------------------
+
+// Rollback reverts all of the changes committed after the specified version by applying
+// the inverse operations, most recent first. Each reverted version is committed as a new
+// version, so the rollback itself can also be reverted. The number of versions which can
+// be reverted is limited by the History option of the collection. The rollback should not
+// be performed concurrently with other writes, otherwise they may be interleaved.
+func (c *Collection) Rollback(version uint64) error {
+	if c.history == nil {
+		return errNoHistory
+	}
+
+	entries, err := c.history.Since(version, c.Version())
+	if err != nil {
+		return err
+	}
+
+	for i := len(entries) - 1; i >= 0; i-- {
+		if err := c.Query(func(txn *Txn) error {
+			for _, u := range entries[i].updates {
+				txn.updates = append(txn.updates, u.Clone())
+			}
+			return nil
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// --------------------------- Inverse Operations ----------------------------
+
+// undo represents the inverse operations being collected during a commit
+type undo struct {
+	inserted bitmap.Bitmap    // The rows inserted by the transaction
+	updates  []*commit.Buffer // The inverse operations
+}
+
+// bufferFor loads or creates a buffer for a given column.
+func (u *undo) bufferFor(columnName string) *commit.Buffer {
+	for _, b := range u.updates {
+		if b.Column == columnName {
+			return b
+		}
+	}
+
+	buffer := commit.NewBuffer(64)
+	buffer.Reset(columnName)
+	u.updates = append(u.updates, buffer)
+	return buffer
+}
+
+// captureUndo records the inverse of the pending operations for a chunk. This must be
+// called while holding the write latch of the chunk, before the operations are applied.
+func (txn *Txn) captureUndo(chunk commit.Chunk, markers *commit.Buffer, dst *undo) {
+	if markers != nil {
+		txn.reader.Range(markers, chunk, func(r *commit.Reader) {
+			for r.Next() {
+				switch idx := r.Index(); r.Type {
+				case commit.Insert:
+					dst.inserted.Set(idx)
+					dst.bufferFor(rowColumn).PutOperation(commit.Delete, idx)
+				case commit.Delete:
+					txn.owner.lock.RLock()
+					exists := txn.owner.fill.Contains(idx) && !dst.inserted.Contains(idx)
+					txn.owner.lock.RUnlock()
+					if exists {
+						dst.bufferFor(rowColumn).PutOperation(commit.Insert, idx)
+						txn.owner.cols.Range(func(column *column) {
+							if v, ok := column.Value(idx); ok && !column.IsIndex() {
+								dst.bufferFor(column.name).PutAny(commit.Put, idx, v)
+							}
+						})
+					}
+				}
+			}
+		})
+	}
+
+	for _, u := range txn.updates {
+		if u.IsEmpty() || u.Column == rowColumn {
+			continue
+		}
+
+		column, ok := txn.owner.cols.Load(u.Column)
+		if !ok || column.IsIndex() {
+			continue
+		}
+
+		txn.reader.Range(u, chunk, func(r *commit.Reader) {
+			for r.Next() {
+				idx := r.Index()
+				if v, ok := column.Value(idx); ok {
+					dst.bufferFor(u.Column).PutAny(commit.Put, idx, v)
+				} else {
+					dst.bufferFor(u.Column).PutOperation(commit.Delete, idx)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,121 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package column
+
+import (
+	"strings"
+
+	"github.com/kelindar/column/commit"
+)
+
+// SetNull stores an explicit null in a column at the specified index, which removes the
+// value if there is one. Unlike a value which was never set, an explicit null is included
+// as a nil value when fetching the object. Setting a value overrides the null, and clearing
+// the column at the index removes it. It returns false if the object or the column does not
+// exist, or if the column is an index.
+func (c *Collection) SetNull(idx uint32, columnName string) (updated bool) {
+	c.QueryAt(idx, func(r Row) error {
+		updated = c.Contains(idx) && r.SetNull(columnName)
+		return nil
+	})
+	return
+}
+
+// SetNull stores an explicit null in a column for the current row, see Collection.SetNull
+func (r Row) SetNull(columnName string) bool {
+	return r.txn.nullAt(r.txn.cursor, columnName)
+}
+
+// nullAt removes the value of a column at the specified index and marks it as null. The
+// caller must hold the read latch of the chunk.
+func (txn *Txn) nullAt(idx uint32, columnName string) bool {
+	column, ok := txn.columnAt(columnName)
+	if !ok || column.IsIndex() || column.nullOf != "" {
+		return false
+	}
+
+	nulls := txn.owner.nullsOf(column)
+	column.stats.write()
+	if column.Contains(idx) {
+		txn.bufferFor(column.name).PutOperation(commit.Delete, idx)
+	}
+
+	txn.bufferFor(nulls.name).PutBool(idx, true)
+	return true
+}
+
+// nullsOf returns the column holding the explicit null markers of a column, creating it on
+// first use. The markers are stored in a regular boolean column, so they are committed,
+// replicated and snapshotted along with the values.
+func (c *Collection) nullsOf(owner *column) *column {
+	if nulls, ok := owner.null.Load().(*column); ok {
+		return nulls
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if nulls, ok := owner.null.Load().(*column); ok {
+		return nulls
+	}
+
+	// Grow the markers to cover the chunks already committed, same as CreateColumn
+	markers := makeBools()
+	markers.Grow(uint32(c.opts.Capacity))
+	if chunks := len(c.commits); chunks > 0 {
+		markers.Grow(commit.Chunk(chunks - 1).Max())
+	}
+
+	nulls := columnFor(nullColumnOf(owner.name), markers)
+	nulls.nullOf = owner.name
+	c.cols.Store(nulls.name, nulls)
+	owner.null.Store(nulls)
+	return nulls
+}
+
+// nullsNamed returns the column holding the explicit nulls with the specified name, creating
+// it if the column whose nulls it marks exists. This way, the markers of a snapshot or of a
+// replicated commit are kept even if no null was set in this collection yet.
+func (c *Collection) nullsNamed(columnName string) (*column, bool) {
+	if !strings.HasSuffix(columnName, ":null") {
+		return nil, false
+	}
+
+	owner, ok := c.cols.Load(strings.TrimSuffix(columnName, ":null"))
+	if !ok || owner.IsIndex() || owner.nullOf != "" {
+		return nil, false
+	}
+
+	return c.nullsOf(owner), true
+}
+
+// nullColumnOf returns the name of the column holding the explicit nulls of a column
+func nullColumnOf(columnName string) string {
+	return columnName + ":null"
+}
+
+// IsNull returns whether the column holds an explicit null at the specified index
+func (c *column) IsNull(idx uint32) bool {
+	nulls, ok := c.null.Load().(*column)
+	return ok && nulls.Column.Contains(idx)
+}
+
+// applyNulls removes the explicit nulls overridden by the values put in the column. Since the
+// markers are modified, the lock of their column is held for writing. The caller must hold
+// the write latch of the chunk.
+func (c *column) applyNulls(r *commit.Reader) {
+	nulls, ok := c.null.Load().(*column)
+	if !ok {
+		return
+	}
+
+	nulls.lock.Lock()
+	defer nulls.lock.Unlock()
+	markers := nulls.Column.(*columnBool)
+	for r.Next() {
+		switch r.Type {
+		case commit.Put, commit.Add:
+			markers.data.Remove(r.Index())
+		}
+	}
+}
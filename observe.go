@@ -0,0 +1,240 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package column
+
+import (
+	"context"
+	"sync"
+
+	"github.com/kelindar/bitmap"
+	"github.com/kelindar/column/commit"
+)
+
+// ChangeEvent represents a single committed change of an object.
+type ChangeEvent struct {
+	Type   commit.OpType // The type of the change, either Insert, Delete or Put
+	Index  uint32        // The index of the changed object
+	Column string        // The changed column, empty if the whole object was inserted or deleted
+	Value  interface{}   // The new value of the column, nil if the value was removed
+	Object Object        // The object as it was before the deletion, only set for deletions of objects
+}
+
+// Observe reads a consistent snapshot of the collection, keyed by the index of each object,
+// and streams every change committed after that snapshot until the context is cancelled. The
+// snapshot is read chunk by chunk, and the changes of a chunk are only streamed once the chunk
+// was read, hence no change is either missed or already contained in the snapshot. Changes are
+// buffered while the consumer is busy, so the channel should be drained continuously.
+func (c *Collection) Observe(ctx context.Context) (snapshot map[uint32]Object, changes <-chan ChangeEvent) {
+	o := &observer{
+		signal: make(chan struct{}, 1),
+		output: make(chan ChangeEvent),
+	}
+
+	// Register the observer, chunks which were never committed are observed straight away
+	// since anything they contain will be committed after the snapshot.
+	c.watch.lock.Lock()
+	c.lock.RLock()
+	o.limit = commit.Chunk(len(c.commits))
+	c.lock.RUnlock()
+	c.watch.list = append(c.watch.list, o)
+	c.watch.lock.Unlock()
+
+	// Read the snapshot and start observing each chunk while it is still locked
+	snapshot = make(map[uint32]Object, c.Count())
+	for chunk := commit.Chunk(0); chunk < o.limit; chunk++ {
+		c.readChunk(chunk, func(_ uint64, chunk commit.Chunk, fill bitmap.Bitmap) error {
+			offset := chunk.Min()
+			fill.Range(func(x uint32) {
+				object := make(Object, c.cols.Count())
+				if c.readObject(offset+x, object); len(object) > 0 {
+					snapshot[offset+x] = object
+				}
+			})
+
+			o.lock.Lock()
+			o.ready.Set(uint32(chunk))
+			o.lock.Unlock()
+			return nil
+		})
+	}
+
+	go o.forward(ctx, c)
+	return snapshot, o.output
+}
+
+// observersOf returns the observers which are observing the chunk
+func (c *Collection) observersOf(chunk commit.Chunk) (out []*observer) {
+	c.watch.lock.RLock()
+	defer c.watch.lock.RUnlock()
+	for _, o := range c.watch.list {
+		if o.observes(chunk) {
+			out = append(out, o)
+		}
+	}
+	return
+}
+
+// unobserve removes the observer from the collection
+func (c *Collection) unobserve(o *observer) {
+	c.watch.lock.Lock()
+	defer c.watch.lock.Unlock()
+	for i, v := range c.watch.list {
+		if v == o {
+			c.watch.list = append(c.watch.list[:i], c.watch.list[i+1:]...)
+			return
+		}
+	}
+}
+
+// --------------------------- Observer ----------------------------
+
+// observers represents a set of observers of a collection
+type observers struct {
+	lock sync.RWMutex // The lock to protect the list
+	list []*observer  // The list of registered observers
+}
+
+// observer represents a single observer of the changes
+type observer struct {
+	lock    sync.Mutex       // The lock to protect the pending changes and chunks
+	limit   commit.Chunk     // The number of chunks in the snapshot
+	ready   bitmap.Bitmap    // The chunks which were read into the snapshot
+	pending []ChangeEvent    // The changes pending to be forwarded
+	signal  chan struct{}    // The signal for pending changes
+	output  chan ChangeEvent // The output channel for the changes
+}
+
+// observes returns whether the changes of a chunk need to be forwarded
+func (o *observer) observes(chunk commit.Chunk) bool {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	return chunk >= o.limit || o.ready.Contains(uint32(chunk))
+}
+
+// enqueue appends the changes to the pending list and signals the forwarder
+func (o *observer) enqueue(changes []ChangeEvent) {
+	o.lock.Lock()
+	o.pending = append(o.pending, changes...)
+	o.lock.Unlock()
+
+	select {
+	case o.signal <- struct{}{}:
+	default:
+	}
+}
+
+// forward forwards the pending changes into the output channel, until cancelled.
+func (o *observer) forward(ctx context.Context, owner *Collection) {
+	defer close(o.output)
+	defer owner.unobserve(o)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-o.signal:
+		}
+
+		o.lock.Lock()
+		changes := o.pending
+		o.pending = nil
+		o.lock.Unlock()
+
+		for _, change := range changes {
+			select {
+			case o.output <- change:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+}
+
+// --------------------------- Notify ----------------------------
+
+// notify decodes the committed changes of a chunk and forwards them to the observers, along
+// with the objects removed by the commit. The caller must hold the write latch of the chunk,
+// after the updates have been applied.
+func (txn *Txn) notify(chunk commit.Chunk, markers *commit.Buffer, removed map[uint32]Object) {
+	targets := txn.owner.observersOf(chunk)
+	if len(targets) == 0 {
+		return
+	}
+
+	// Insertions come first and deletions last, so every change refers to an existing object
+	changes := txn.changesOf(chunk, markers, commit.Insert, nil)
+	for _, u := range txn.updates {
+		if u.IsEmpty() || u.Column == rowColumn {
+			continue
+		}
+
+		column, ok := txn.owner.cols.Load(u.Column)
+		if !ok || column.IsIndex() {
+			continue
+		}
+
+		// Booleans are stored as operations, so a delete is equivalent to false
+		_, isBool := column.Column.(*columnBool)
+		txn.reader.Range(u, chunk, func(r *commit.Reader) {
+			for r.Next() {
+				switch value, ok := decodeValue(column.Column, r); {
+				case !ok:
+					continue
+				case r.Type == commit.Delete && !isBool:
+					changes = append(changes, ChangeEvent{Type: commit.Delete, Index: r.Index(), Column: u.Column})
+				default:
+					changes = append(changes, ChangeEvent{Type: commit.Put, Index: r.Index(), Column: u.Column, Value: value})
+				}
+			}
+		})
+	}
+
+	changes = append(changes, txn.changesOf(chunk, markers, commit.Delete, removed)...)
+	for _, o := range targets {
+		o.enqueue(changes)
+	}
+}
+
+// changesOf decodes the insertions or deletions of objects in a chunk
+func (txn *Txn) changesOf(chunk commit.Chunk, markers *commit.Buffer, op commit.OpType, removed map[uint32]Object) (out []ChangeEvent) {
+	if markers == nil {
+		return
+	}
+
+	txn.reader.Range(markers, chunk, func(r *commit.Reader) {
+		for r.Next() {
+			if r.Type == op {
+				out = append(out, ChangeEvent{Type: op, Index: r.Index(), Object: removed[r.Index()]})
+			}
+		}
+	})
+	return
+}
+
+// removedOf reads the objects which are about to be deleted from an observed chunk. The
+// caller must hold the write latch of the chunk, before the deletions are applied.
+func (txn *Txn) removedOf(chunk commit.Chunk, markers *commit.Buffer) (out map[uint32]Object) {
+	if markers == nil || len(txn.owner.observersOf(chunk)) == 0 {
+		return nil
+	}
+
+	txn.reader.Range(markers, chunk, func(r *commit.Reader) {
+		for r.Next() {
+			idx := r.Index()
+			txn.owner.lock.RLock()
+			exists := txn.owner.fill.Contains(idx)
+			txn.owner.lock.RUnlock()
+			if r.Type != commit.Delete || !exists {
+				continue
+			}
+
+			if out == nil {
+				out = make(map[uint32]Object, 4)
+			}
+
+			out[idx] = make(Object, txn.owner.cols.Count())
+			txn.owner.readObject(idx, out[idx])
+		}
+	})
+	return
+}
@@ -4,6 +4,7 @@
 package column
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 	"io"
@@ -19,6 +20,7 @@ import (
 
 var (
 	errUnexpectedEOF = errors.New("column: unable to restore, unexpected EOF")
+	errNoJournal     = errors.New("column: collection does not journal any changes")
 )
 
 // --------------------------- Commit Replay ---------------------------
@@ -41,6 +43,7 @@ func (c *Collection) Replay(change commit.Commit) error {
 // Restore restores the collection from the underlying snapshot reader. This operation
 // should be called before any of transactions, right after initialization.
 func (c *Collection) Restore(snapshot io.Reader) error {
+	c.invalidateSketches()
 	commits, err := c.readState(s2.NewReader(snapshot))
 	if err != nil {
 		return err
@@ -105,10 +108,194 @@ func (c *Collection) isSnapshotting() (*commit.Log, bool) {
 	return (*commit.Log)(ptr), true
 }
 
+// --------------------------- Incremental Snapshots ---------------------------
+
+// SnapshotSince writes all of the changes committed after the specified version into the
+// underlying writer. The number of versions which can be written is limited by the Journal
+// option of the collection. Applying these changes with RestoreSince on top of a snapshot
+// taken at, or after, the specified version reproduces the current state of the collection.
+func (c *Collection) SnapshotSince(version uint64, dst io.Writer) error {
+	if c.journal == nil {
+		return errNoJournal
+	}
+
+	entries, err := c.journal.Since(version, c.Version())
+	if err != nil {
+		return err
+	}
+
+	writer := iostream.NewWriter(s2.NewWriter(dst))
+	if err := writer.WriteUvarint(0x1); err != nil {
+		return err
+	}
+
+	if err := writer.WriteRange(len(entries), func(i int, w *iostream.Writer) error {
+		if err := w.WriteUvarint(entries[i].version); err != nil {
+			return err
+		}
+
+		updates := entries[i].updates
+		return w.WriteRange(len(updates), func(i int, w *iostream.Writer) error {
+			return w.WriteSelf(updates[i])
+		})
+	}); err != nil {
+		return err
+	}
+
+	return writer.Flush()
+}
+
+// RestoreSince applies the changes written by SnapshotSince to the collection, in the
+// order they were originally committed.
+func (c *Collection) RestoreSince(src io.Reader) error {
+	c.invalidateSketches()
+	r := iostream.NewReader(s2.NewReader(src))
+	version, err := r.ReadUvarint()
+	if err != nil || version != 0x1 {
+		return fmt.Errorf("column: unable to restore (version %d) %v", version, err)
+	}
+
+	return r.ReadRange(func(i int, r *iostream.Reader) error {
+		if _, err := r.ReadUvarint(); err != nil {
+			return err
+		}
+
+		var updates []*commit.Buffer
+		if err := r.ReadRange(func(i int, r *iostream.Reader) error {
+			buffer := commit.NewBuffer(0)
+			if _, err := buffer.ReadFrom(r); err != nil {
+				return err
+			}
+
+			updates = append(updates, buffer)
+			return nil
+		}); err != nil {
+			return err
+		}
+
+		return c.Query(func(txn *Txn) error {
+			txn.updates = append(txn.updates, updates...)
+			return nil
+		})
+	})
+}
+
+// --------------------------- Collection Clone ---------------------------
+
+// Clone creates an independent copy of the collection, with the same columns, indexes and
+// objects at the same indices, so that changes to either of them do not affect the other.
+// The objects are read while holding the read latches of every chunk, so the copy reflects
+// a single point in time, but commits are blocked until it is read. The clone is a full copy
+// which requires as much memory as the collection itself, and it neither writes into the
+// commit log of the collection nor retains its history.
+func (c *Collection) Clone() (*Collection, error) {
+	clone := NewCollection(Options{
+		Capacity: c.opts.Capacity,
+		Vacuum:   c.opts.Vacuum,
+		Stats:    c.opts.Stats,
+	})
+
+	// Create the columns first, followed by the indexes and nulls which depend on them
+	var indexes, nulls []*column
+	if err := c.cols.RangeUntil(func(column *column) error {
+		if column.nullOf != "" {
+			nulls = append(nulls, column)
+			return nil
+		}
+
+		var dst Column
+		switch v := column.Column.(type) {
+		case *columnIndex, *columnValues:
+			indexes = append(indexes, column)
+			return nil
+		case *columnKey:
+			dst = makeKey()
+		default:
+			created, err := columnLike(v)
+			if err != nil {
+				return err
+			}
+			dst = created
+		}
+
+		if _, ok := clone.cols.Load(column.name); ok {
+			return nil // Created along with the collection
+		}
+		return clone.CreateColumn(column.name, dst)
+	}); err != nil {
+		return nil, err
+	}
+
+	for _, index := range indexes {
+		var err error
+		switch v := index.Column.(type) {
+		case *columnIndex:
+			err = clone.CreateIndex(index.name, v.name, v.rule)
+		case *columnValues:
+			err = clone.CreateValueIndex(index.name, v.name)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, null := range nulls {
+		if owner, ok := clone.cols.Load(null.nullOf); ok {
+			clone.nullsOf(owner)
+		}
+	}
+
+	for alias, actual := range *c.cols.alias.Load().(*map[string]string) {
+		if err := clone.cols.StoreAlias(alias, actual); err != nil {
+			return nil, err
+		}
+	}
+
+	// Encode the state while every chunk is latched, hence without latching them again
+	var state bytes.Buffer
+	if err := c.cloneState(&state); err != nil {
+		return nil, err
+	}
+
+	if _, err := clone.readState(&state); err != nil {
+		return nil, err
+	}
+
+	c.lock.RLock()
+	clone.alloc = c.alloc
+	clone.freed = append([]uint32(nil), c.freed...)
+	c.lock.RUnlock()
+	return clone, nil
+}
+
+// cloneState writes the state of the collection while holding the read latches of every
+// chunk, so that no commit is applied while it is written.
+func (c *Collection) cloneState(dst io.Writer) error {
+	for shard := uint(0); shard < 128; shard++ {
+		c.slock.RLock(shard)
+		defer c.slock.RUnlock(shard)
+	}
+
+	_, err := c.encodeState(dst, func(chunk commit.Chunk, fn func(uint64, commit.Chunk, bitmap.Bitmap) error) error {
+		c.lock.RLock()
+		fill := chunk.OfBitmap(c.fill)
+		commitID := c.commits[chunk]
+		c.lock.RUnlock()
+		return fn(commitID, chunk, fill)
+	})
+	return err
+}
+
 // --------------------------- Collection Encoding ---------------------------
 
 // writeState writes collection state into the specified writer.
 func (c *Collection) writeState(dst io.Writer) (int64, error) {
+	return c.encodeState(dst, c.readChunk)
+}
+
+// encodeState writes collection state into the specified writer, reading each of the
+// chunks with the specified function.
+func (c *Collection) encodeState(dst io.Writer, read func(commit.Chunk, func(uint64, commit.Chunk, bitmap.Bitmap) error) error) (int64, error) {
 	writer := iostream.NewWriter(dst)
 	buffer := c.txns.acquirePage(rowColumn)
 	defer c.txns.releasePage(buffer)
@@ -129,7 +316,7 @@ func (c *Collection) writeState(dst io.Writer) (int64, error) {
 
 	// Write each chunk
 	if err := writer.WriteRange(chunks, func(i int, w *iostream.Writer) error {
-		return c.readChunk(commit.Chunk(i), func(lastCommit uint64, chunk commit.Chunk, fill bitmap.Bitmap) error {
+		return read(commit.Chunk(i), func(lastCommit uint64, chunk commit.Chunk, fill bitmap.Bitmap) error {
 			offset := chunk.Min()
 
 			// Write the last written commit for this chunk
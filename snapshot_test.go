@@ -198,6 +198,115 @@ func TestSnapshot(t *testing.T) {
 	assert.Equal(t, amount, output.Count())
 }
 
+func TestSnapshotSince(t *testing.T) {
+	newCollection := func() *Collection {
+		out := NewCollection(Options{Journal: 100})
+		out.CreateColumn("name", ForEnum())
+		out.CreateColumn("age", ForInt())
+		out.CreateColumn("active", ForBool())
+		return out
+	}
+
+	input := newCollection()
+	for i := 0; i < 10; i++ {
+		input.InsertObject(Object{"name": fmt.Sprintf("Player %d", i), "age": i, "active": true})
+	}
+
+	// Take a base snapshot
+	base := bytes.NewBuffer(nil)
+	version := input.Version()
+	assert.NoError(t, input.Snapshot(base))
+
+	// Mutate and take the first diff
+	diff1 := bytes.NewBuffer(nil)
+	input.QueryAt(1, func(r Row) error {
+		r.AddInt("age", 10)
+		r.SetBool("active", false)
+		return nil
+	})
+	input.DeleteAt(2)
+	assert.NoError(t, input.SnapshotSince(version, diff1))
+
+	// Mutate again and take the second diff
+	diff2 := bytes.NewBuffer(nil)
+	version = input.Version()
+	input.InsertObject(Object{"name": "Roman", "age": 35})
+	input.QueryAt(1, func(r Row) error {
+		r.AddInt("age", 5)
+		return nil
+	})
+	assert.NoError(t, input.SnapshotSince(version, diff2))
+
+	// Restore the base and apply the diffs
+	output := newCollection()
+	assert.NoError(t, output.Restore(base))
+	assert.NoError(t, output.RestoreSince(diff1))
+	assert.NoError(t, output.RestoreSince(diff2))
+	assert.Equal(t, input.Keys(), output.Keys())
+	for _, idx := range input.Keys() {
+		expect, _ := input.FetchFields(idx, "name", "age", "active")
+		actual, _ := output.FetchFields(idx, "name", "age", "active")
+		assert.Equal(t, expect, actual)
+	}
+
+	// Versions which are not journaled
+	assert.Error(t, input.SnapshotSince(input.Version()+1, diff1))
+	assert.Equal(t, errNoJournal, NewCollection().SnapshotSince(0, diff1))
+	assert.Error(t, output.RestoreSince(bytes.NewBuffer(nil)))
+}
+
+func TestSnapshotSinceConcurrent(t *testing.T) {
+	newCollection := func() *Collection {
+		out := NewCollection(Options{Journal: 1000})
+		out.CreateColumn("age", ForInt())
+		return out
+	}
+
+	input := newCollection()
+	for i := 0; i < 10; i++ {
+		input.InsertObject(Object{"age": 0})
+	}
+
+	base := bytes.NewBuffer(nil)
+	version := input.Version()
+	assert.NoError(t, input.Snapshot(base))
+
+	// Concurrently overwrite the same rows, the last write of each must be replayed last
+	var wg sync.WaitGroup
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < 50; i++ {
+				if i%10 == 0 {
+					input.WithLock(func(l *LockedCollection) {
+						l.Set(uint32(i%10), "age", w*100+i)
+					})
+					continue
+				}
+
+				input.QueryAt(uint32(i%10), func(r Row) error {
+					r.SetInt("age", w*100+i)
+					return nil
+				})
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	diff := bytes.NewBuffer(nil)
+	assert.NoError(t, input.SnapshotSince(version, diff))
+
+	output := newCollection()
+	assert.NoError(t, output.Restore(base))
+	assert.NoError(t, output.RestoreSince(diff))
+	for _, idx := range input.Keys() {
+		expect, _ := input.FetchFields(idx, "age")
+		actual, _ := output.FetchFields(idx, "age")
+		assert.Equal(t, expect, actual)
+	}
+}
+
 func TestSnapshotFailures(t *testing.T) {
 	input := NewCollection()
 	input.CreateColumn("name", ForString())
@@ -349,6 +458,51 @@ func TestReadFromFailures(t *testing.T) {
 	}
 }
 
+func TestClone(t *testing.T) {
+	input := loadPlayers(500)
+	input.DeleteAt(10)
+	input.AddAlias("nick", "name")
+
+	output, err := input.Clone()
+	assert.NoError(t, err)
+	assert.Equal(t, input.Count(), output.Count())
+	assert.Equal(t, input.Keys(), output.Keys())
+
+	// Objects, indexes, keys and aliases are all copied
+	for _, idx := range []uint32{0, 11, 499} {
+		assert.NotEmpty(t, input.fetch(idx))
+		assert.Equal(t, input.fetch(idx), output.fetch(idx))
+	}
+
+	input.Query(func(txn *Txn) error {
+		count := txn.With("human", "mage").Count()
+		output.Query(func(txn *Txn) error {
+			assert.Equal(t, count, txn.With("human", "mage").Count())
+			return nil
+		})
+		return nil
+	})
+
+	serial, _ := Get[string](input, 20, "serial")
+	found := output.QueryKey(serial, func(r Row) error {
+		name, _ := r.Enum("nick")
+		assert.NotEmpty(t, name)
+		return nil
+	})
+	assert.NoError(t, found)
+
+	// The clone is independent from the collection
+	input.DeleteAt(0)
+	output.QueryAt(1, func(r Row) error {
+		r.SetFloat64("balance", -1)
+		return nil
+	})
+	assert.Equal(t, 498, input.Count())
+	assert.Equal(t, 499, output.Count())
+	balance, _ := Get[float64](input, 1, "balance")
+	assert.NotEqual(t, -1.0, balance)
+}
+
 // --------------------------- Mocks & Fixtures ----------------------------
 
 // noopWriter is a writer that simply counts the commits
@@ -0,0 +1,99 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package column
+
+import (
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/kelindar/column/commit"
+)
+
+// SetTraceWriter sets the writer into which every record applied to the collection is
+// traced, one line per record with the commit ID, operation, column, offset and value.
+// This includes the commits replayed from other collections, which helps to find where
+// replicas diverge. Tracing is disabled by setting a nil writer, which is the default.
+func (c *Collection) SetTraceWriter(w io.Writer) {
+	if w == nil {
+		c.tracer.Store((*tracer)(nil))
+		return
+	}
+
+	c.tracer.Store(&tracer{writer: w})
+}
+
+// tracer represents a destination of the traced records
+type tracer struct {
+	lock   sync.Mutex // The lock to serialize the lines written
+	writer io.Writer  // The destination writer
+}
+
+// trace formats the records applied to the chunk into the trace of the transaction, if a
+// trace writer is set. The caller must hold the write latch of the chunk, after the updates
+// have been applied.
+func (txn *Txn) trace(commitID uint64, chunk commit.Chunk) {
+	if t, _ := txn.owner.tracer.Load().(*tracer); t == nil {
+		return
+	}
+
+	for _, u := range txn.updates {
+		if u.IsEmpty() {
+			continue
+		}
+
+		// Insertions and deletions of objects do not carry any value
+		if u.Column == rowColumn {
+			txn.reader.Range(u, chunk, func(r *commit.Reader) {
+				for r.Next() {
+					fmt.Fprintf(&txn.traced, "%d %s %s %d\n", commitID, opName(r.Type, false), u.Column, r.Offset)
+				}
+			})
+			continue
+		}
+
+		column, ok := txn.owner.cols.Load(u.Column)
+		if !ok {
+			continue
+		}
+
+		_, isBool := column.Column.(*columnBool)
+		txn.reader.Range(u, chunk, func(r *commit.Reader) {
+			for r.Next() {
+				value, _ := decodeValue(column.Column, r)
+				fmt.Fprintf(&txn.traced, "%d %s %s %d %v\n", commitID, opName(r.Type, isBool), u.Column, r.Offset, value)
+			}
+		})
+	}
+}
+
+// flushTrace writes the records traced by the transaction into the trace writer. This is
+// done once the latches are released, so that a slow writer does not block other queries.
+func (txn *Txn) flushTrace() {
+	if txn.traced.Len() == 0 {
+		return
+	}
+
+	if t, _ := txn.owner.tracer.Load().(*tracer); t != nil {
+		t.lock.Lock()
+		t.writer.Write(txn.traced.Bytes())
+		t.lock.Unlock()
+	}
+	txn.traced.Reset()
+}
+
+// opName returns the name of an operation, booleans are stored as operations hence a
+// delete of a boolean is a put of false.
+func opName(op commit.OpType, isBool bool) string {
+	switch {
+	case op == commit.Insert:
+		return "insert"
+	case op == commit.Add:
+		return "add"
+	case op == commit.Put || isBool:
+		return "put"
+	default:
+		return "delete"
+	}
+}
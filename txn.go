@@ -4,7 +4,14 @@
 package column
 
 import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
 	"errors"
+	"fmt"
+	"math/bits"
+	"math/rand"
+	"sort"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -21,8 +28,9 @@ var (
 
 // txnPool is a pool of transactions which are retained for the lifetime of the process.
 type txnPool struct {
-	txns  sync.Pool
-	pages sync.Pool
+	txns    sync.Pool
+	pages   sync.Pool
+	objects sync.Pool
 }
 
 func newTxnPool() *txnPool {
@@ -43,6 +51,11 @@ func newTxnPool() *txnPool {
 				return commit.NewBuffer(chunkSize)
 			},
 		},
+		objects: sync.Pool{
+			New: func() interface{} {
+				return make(Object, 16)
+			},
+		},
 	}
 }
 
@@ -52,6 +65,8 @@ func (p *txnPool) acquire(owner *Collection) *Txn {
 	txn.owner = owner
 	txn.logger = owner.logger
 	txn.setup = false
+	txn.order = nil
+	txn.locked = false
 	return txn
 }
 
@@ -73,6 +88,19 @@ func (p *txnPool) releasePage(buffer *commit.Buffer) {
 	p.pages.Put(buffer)
 }
 
+// acquireObject acquires an empty object
+func (p *txnPool) acquireObject() Object {
+	return p.objects.Get().(Object)
+}
+
+// releaseObject clears the object and releases it back, so none of its values leak
+func (p *txnPool) releaseObject(object Object) {
+	for k := range object {
+		delete(object, k)
+	}
+	p.objects.Put(object)
+}
+
 // --------------------------- Transaction ----------------------------
 
 // Txn represents a transaction which supports filtering and projection.
@@ -86,6 +114,9 @@ type Txn struct {
 	columns []columnCache    // The column mapping
 	logger  commit.Logger    // The optional commit logger
 	reader  *commit.Reader   // The commit reader to re-use
+	order   []uint32         // The optional ordering of the result set
+	locked  bool             // Whether the latches of every chunk are already held
+	traced  bytes.Buffer     // The traced records, written once the latches are released
 }
 
 // Reset resets the transaction state so it can be used again.
@@ -98,10 +129,12 @@ func (txn *Txn) reset() {
 	txn.reader.Rewind()
 	txn.columns = txn.columns[:0]
 	txn.updates = txn.updates[:0]
+	txn.traced.Reset()
 }
 
 // bufferFor loads or creates a buffer for a given column.
 func (txn *Txn) bufferFor(columnName string) *commit.Buffer {
+	columnName = txn.owner.cols.Resolve(columnName)
 	for _, c := range txn.updates {
 		if c.Column == columnName {
 			return c
@@ -184,6 +217,99 @@ func (txn *Txn) Union(columns ...string) *Txn {
 	return txn
 }
 
+// UnionValue adds to the query the objects of the collection whose value of the column satisfies
+// the predicate, which is a logical OR operation as opposed to WithValue. The predicate is
+// evaluated against every object of the collection, rather than only the ones matching the
+// query. Applied to a fresh query, before any other filter, the union starts from an empty
+// result set and selects the same objects as WithValue, hence chaining two unions on a fresh
+// query matches the objects satisfying either of the predicates.
+func (txn *Txn) UnionValue(column string, predicate func(v interface{}) bool) *Txn {
+	fresh := !txn.setup
+	txn.initialize()
+	if fresh {
+		txn.index.Clear()
+	}
+
+	c, ok := txn.columnAt(column)
+	if !ok {
+		return txn
+	}
+
+	// Evaluate the predicate against a copy of the objects currently present
+	var matches bitmap.Bitmap
+	txn.owner.lock.RLock()
+	txn.owner.fill.Clone(&matches)
+	txn.owner.lock.RUnlock()
+
+	limit := commit.Chunk(len(matches) >> bitmapShift)
+	lock := txn.owner.slock
+	for chunk := commit.Chunk(0); chunk <= limit; chunk++ {
+		lock.RLock(uint(chunk))
+		offset, index := chunk.Min(), chunk.OfBitmap(matches)
+		index.Filter(func(x uint32) (match bool) {
+			if v, ok := c.Value(offset + x); ok {
+				match = predicate(v)
+			}
+			return
+		})
+		lock.RUnlock(uint(chunk))
+	}
+
+	txn.index.Or(matches)
+	return txn
+}
+
+// IntersectSorted applies a logical AND operation to the current query and the specified
+// indices, for example the result of an external index. The indices are merged with the
+// query in a single pass, without building a bitmap first, hence they must be sorted in
+// ascending order. If they are not, an error is returned and the query is unchanged.
+func (txn *Txn) IntersectSorted(sorted []uint32) (*Txn, error) {
+	for i := 1; i < len(sorted); i++ {
+		if sorted[i] < sorted[i-1] {
+			return txn, fmt.Errorf("column: unable to intersect, indices are not sorted")
+		}
+	}
+
+	txn.initialize()
+	i := 0
+	for blk := range txn.index {
+		mask, until := uint64(0), uint32(blk+1)<<6
+		for ; i < len(sorted) && sorted[i] < until; i++ {
+			mask |= 1 << (sorted[i] & 63)
+		}
+		txn.index[blk] &= mask
+	}
+	return txn, nil
+}
+
+// Intersect applies a logical AND operation to the current query and the objects matched by
+// another query, which allows to combine queries built independently. Both queries must be
+// on the same collection and it panics otherwise.
+func (txn *Txn) Intersect(other *Txn) *Txn {
+	txn.combine(other, "intersect")
+	txn.index.And(other.index)
+	return txn
+}
+
+// Difference applies a logical AND NOT operation to the current query and the objects matched
+// by another query, hence it removes them from the current query. Both queries must be on the
+// same collection and it panics otherwise.
+func (txn *Txn) Difference(other *Txn) *Txn {
+	txn.combine(other, "difference")
+	txn.index.AndNot(other.index)
+	return txn
+}
+
+// combine prepares the query to be combined with another query on the same collection
+func (txn *Txn) combine(other *Txn, operation string) {
+	if other == nil || other.owner != txn.owner {
+		panic("column: unable to " + operation + ", queries are on different collections")
+	}
+
+	txn.initialize()
+	other.initialize()
+}
+
 // WithValue applies a filter predicate over values for a specific properties. It filters
 // down the items in the query.
 func (txn *Txn) WithValue(column string, predicate func(v interface{}) bool) *Txn {
@@ -269,7 +395,8 @@ func (txn *Txn) WithString(column string, predicate func(v string) bool) *Txn {
 	return txn
 }
 
-// Count returns the number of objects matching the query
+// Count returns the number of objects matching the query, reflecting every filter applied
+// so far. It only counts the bits of the query index, without reading any of the objects.
 func (txn *Txn) Count() int {
 	txn.initialize()
 	return int(txn.index.Count())
@@ -304,6 +431,76 @@ func (txn *Txn) DeleteAt(index uint32) bool {
 	return true
 }
 
+// DeleteMany marks all of the items at the indices present in the specified bitmap for
+// deletion, and returns the number of items that were actually marked. The indices which
+// are not present in this transaction are ignored.
+func (txn *Txn) DeleteMany(indices bitmap.Bitmap) (deleted int) {
+	txn.initialize()
+	indices.Range(func(x uint32) {
+		if txn.index.Contains(x) {
+			txn.deleteAt(x)
+			deleted++
+		}
+	})
+	return
+}
+
+// SetExpiry sets or extends the expiration time of an existing item at the specified
+// index and returns false if the item does not exist. If the expiration time is not in
+// the future, the item is considered expired and is deleted immediately.
+func (txn *Txn) SetExpiry(index uint32, at time.Time) bool {
+	txn.initialize()
+	if !txn.index.Contains(index) {
+		return false
+	}
+
+	if expireAt := at.UnixNano(); expireAt > time.Now().UnixNano() {
+		txn.bufferFor(expireColumn).PutInt64(index, expireAt)
+		return true
+	}
+
+	txn.deleteAt(index)
+	return true
+}
+
+// Update sets the values of the object at the specified index, keeping its index, and
+// returns false if the object does not exist. Keys without a matching column are ignored.
+func (txn *Txn) Update(index uint32, delta Object) bool {
+	txn.initialize()
+	if !txn.index.Contains(index) {
+		return false
+	}
+
+	txn.QueryAt(index, func(Row) error {
+		txn.putObject(delta)
+		return nil
+	})
+	return true
+}
+
+// clearAt removes the value of a column at the specified index, if present. The caller
+// must hold the read latch of the chunk.
+func (txn *Txn) clearAt(idx uint32, columnName string) bool {
+	column, ok := txn.columnAt(columnName)
+	if !ok || column.IsIndex() {
+		return false
+	}
+
+	if column.IsNull(idx) {
+		column.stats.write()
+		txn.bufferFor(nullColumnOf(column.name)).PutBool(idx, false)
+		return true
+	}
+
+	if _, ok := column.Value(idx); !ok {
+		return false
+	}
+
+	column.stats.write()
+	txn.bufferFor(columnName).PutOperation(commit.Delete, idx)
+	return true
+}
+
 // deleteAt marks an index as deleted
 func (txn *Txn) deleteAt(idx uint32) {
 	txn.bufferFor(rowColumn).PutOperation(commit.Delete, idx)
@@ -314,6 +511,20 @@ func (txn *Txn) InsertObject(object Object) (uint32, error) {
 	return txn.insertObject(object, 0)
 }
 
+// InsertMany adds a batch of objects to the collection and returns their allocated indices,
+// in the same order as the objects. The indices are allocated all at once.
+func (txn *Txn) InsertMany(objects []Object) []uint32 {
+	indices := txn.owner.nextMany(len(objects))
+	for i, idx := range indices {
+		txn.bufferFor(rowColumn).PutOperation(commit.Insert, idx)
+		txn.QueryAt(idx, func(Row) error {
+			txn.putObject(objects[i])
+			return nil
+		})
+	}
+	return indices
+}
+
 // InsertObjectWithTTL adds an object to a collection, sets the expiration time
 // based on the specified time-to-live and returns the allocated index.
 func (txn *Txn) InsertObjectWithTTL(object Object, ttl time.Duration) (uint32, error) {
@@ -331,18 +542,75 @@ func (txn *Txn) InsertWithTTL(ttl time.Duration, fn func(Row) error) (uint32, er
 	return txn.insert(fn, time.Now().Add(ttl).UnixNano())
 }
 
+// Append adds a value to a single column at a new index past the last one in the
+// collection and returns the allocated index. Freed indices are never reused, hence
+// for append-only data the index always reflects the insertion order.
+func (txn *Txn) Append(columnName string, value interface{}) (uint32, error) {
+	column, ok := txn.columnAt(columnName)
+	if !ok {
+		return 0, fmt.Errorf("column: unable to append, column '%s' does not exist", columnName)
+	}
+
+	idx := txn.owner.nextTail()
+	column.stats.write()
+	txn.bufferFor(rowColumn).PutOperation(commit.Insert, idx)
+	txn.bufferFor(columnName).PutAny(commit.Put, idx, value)
+	return idx, nil
+}
+
+// Reserve allocates the specified number of indices, reusing the freed ones first, and
+// returns them. The reserved objects are live but have no values, which can be set later
+// on by querying them at their index.
+func (txn *Txn) Reserve(n int) []uint32 {
+	out := make([]uint32, 0, n)
+	for i := 0; i < n; i++ {
+		idx := txn.owner.next()
+		txn.bufferFor(rowColumn).PutOperation(commit.Insert, idx)
+		out = append(out, idx)
+	}
+	return out
+}
+
+// AddAt inserts an object at the specified index rather than at a free one chosen by the
+// collection, for example to keep the indices of replicas aligned. An error is returned if
+// an object already exists at the index.
+func (txn *Txn) AddAt(idx uint32, object Object) error {
+	if !txn.owner.claim(idx) {
+		return fmt.Errorf("column: unable to add at %d, index is already in use", idx)
+	}
+
+	txn.bufferFor(rowColumn).PutOperation(commit.Insert, idx)
+	return txn.QueryAt(idx, func(Row) error {
+		txn.putObject(object)
+		return nil
+	})
+}
+
 // insertObject inserts all of the keys of a map, if previously registered as columns.
 func (txn *Txn) insertObject(object Object, expireAt int64) (uint32, error) {
 	return txn.insert(func(Row) error {
-		for k, v := range object {
-			if _, ok := txn.columnAt(k); ok {
-				txn.bufferFor(k).PutAny(commit.Put, txn.cursor, v)
-			}
-		}
+		txn.putObject(object)
 		return nil
 	}, expireAt)
 }
 
+// putObject writes all of the keys of a map at the cursor, if previously registered as columns.
+// A nil value is stored as an explicit null, the same way as it is read.
+func (txn *Txn) putObject(object Object) {
+	for k, v := range object {
+		column, ok := txn.columnAt(k)
+		switch {
+		case !ok:
+			continue
+		case v == nil:
+			txn.nullAt(txn.cursor, k)
+		default:
+			column.stats.write()
+			txn.bufferFor(k).PutAny(commit.Put, txn.cursor, v)
+		}
+	}
+}
+
 // insert creates an insertion cursor for a given column and expiration time.
 func (txn *Txn) insert(fn func(Row) error, expireAt int64) (uint32, error) {
 
@@ -372,9 +640,15 @@ func (txn *Txn) DeleteAll() {
 }
 
 // Range selects and iterates over result set. In each iteration step, the internal
-// transaction cursor is updated and can be used by various column accessors.
+// transaction cursor is updated and can be used by various column accessors. If the
+// query was ordered with OrderBy, the objects are iterated in that order.
 func (txn *Txn) Range(fn func(idx uint32)) error {
 	txn.initialize()
+	if txn.order != nil {
+		txn.rangeOrdered(fn)
+		return nil
+	}
+
 	txn.rangeRead(func(offset uint32, index bitmap.Bitmap) {
 		index.Range(func(x uint32) {
 			txn.cursor = offset + x
@@ -384,11 +658,481 @@ func (txn *Txn) Range(fn func(idx uint32)) error {
 	return nil
 }
 
+// Select reads all of the objects matching the query, in the order of iteration of Range.
+// Each chunk is latched once while its objects are read, rather than once per object. If
+// no object matches, an empty slice is returned.
+func (txn *Txn) Select() []Object {
+	return txn.SelectTo(make([]Object, 0, 16))
+}
+
+// SelectTo reads all of the objects matching the query into the destination slice and
+// returns it. Both the slice and the objects it contains are reused, so the previous
+// contents of the destination are overwritten.
+func (txn *Txn) SelectTo(dst []Object) []Object {
+	dst = dst[:0]
+	txn.Range(func(idx uint32) {
+		// Reuse the previous object if there is one, otherwise allocate a new one
+		var object Object
+		if len(dst) < cap(dst) {
+			object = dst[:len(dst)+1][len(dst)]
+		}
+
+		if object == nil {
+			object = make(Object, txn.owner.cols.Count())
+		}
+
+		for k := range object {
+			delete(object, k)
+		}
+
+		txn.owner.readObject(idx, object)
+		dst = append(dst, object)
+	})
+	return dst
+}
+
+// OrderBy sorts the objects currently matching the query by the value of the column, using
+// the comparator provided, and remembers this ordering for the subsequent iterations with
+// Range. The sort is stable, so objects with equal values keep the order of their indices,
+// and objects without a value come last. Filters applied afterwards narrow down the result
+// set while preserving the ordering, and Offset or Limit truncate it after the sort.
+func (txn *Txn) OrderBy(columnName string, less func(a, b interface{}) bool) *Txn {
+	type entry struct {
+		index uint32
+		value interface{}
+		ok    bool
+	}
+
+	txn.initialize()
+	column, hasColumn := txn.columnAt(columnName)
+	entries := make([]entry, 0, txn.index.Count())
+	txn.rangeRead(func(offset uint32, index bitmap.Bitmap) {
+		index.Range(func(x uint32) {
+			e := entry{index: offset + x}
+			if hasColumn {
+				e.value, e.ok = column.Value(offset + x)
+			}
+			entries = append(entries, e)
+		})
+	})
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		switch {
+		case a.ok && b.ok:
+			return less(a.value, b.value)
+		default:
+			return a.ok && !b.ok
+		}
+	})
+
+	txn.order = make([]uint32, 0, len(entries))
+	for _, e := range entries {
+		txn.order = append(txn.order, e.index)
+	}
+	return txn
+}
+
+// rangeOrdered iterates over the ordered result set, skipping the objects which were
+// filtered out after ordering. Each object is read while holding the latch of its chunk.
+func (txn *Txn) rangeOrdered(fn func(idx uint32)) {
+	lock := txn.owner.slock
+	for _, idx := range txn.order {
+		if !txn.index.Contains(idx) {
+			continue
+		}
+
+		chunk := commit.ChunkAt(idx)
+		lock.RLock(uint(chunk))
+		txn.cursor = idx
+		fn(idx)
+		lock.RUnlock(uint(chunk))
+	}
+}
+
+// ForEachParallel iterates over the result set across multiple goroutines and calls fn with
+// an object containing all of the values of each row. The rows are partitioned by chunk and
+// each chunk is processed while holding its read latch, so every row is processed exactly
+// once and sees a consistent state. The callback must be safe for concurrent use.
+func (txn *Txn) ForEachParallel(workers int, fn func(idx uint32, obj Object)) {
+	txn.initialize()
+	if workers < 1 {
+		workers = 1
+	}
+
+	// Queue up all of the chunks to be processed
+	limit := commit.Chunk(len(txn.index) >> bitmapShift)
+	chunks := make(chan commit.Chunk, limit+1)
+	for chunk := commit.Chunk(0); chunk <= limit; chunk++ {
+		chunks <- chunk
+	}
+	close(chunks)
+
+	// Process the chunks concurrently
+	var wg sync.WaitGroup
+	lock := txn.owner.slock
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for chunk := range chunks {
+				offset := chunk.Min()
+				lock.RLock(uint(chunk))
+				chunk.OfBitmap(txn.index).Range(func(x uint32) {
+					obj := make(Object, 8)
+					txn.owner.readObject(offset+x, obj)
+					fn(offset+x, obj)
+				})
+				lock.RUnlock(uint(chunk))
+			}
+		}()
+	}
+
+	wg.Wait()
+}
+
+// CountGroupBy counts the objects matching the query for each distinct value of the group
+// column, in a single pass which only reads the group column. Objects without a value in
+// the group column are counted under the nil key.
+func (txn *Txn) CountGroupBy(groupColumn string) map[interface{}]int {
+	txn.initialize()
+	out := make(map[interface{}]int, 8)
+	group, ok := txn.columnAt(groupColumn)
+	if !ok {
+		if count := txn.Count(); count > 0 {
+			out[nil] = count
+		}
+		return out
+	}
+
+	// Enums are counted by their location in the dictionary, then mapped back to strings
+	// before the latch of the chunk is released, since a compaction moves the locations.
+	if enum, ok := group.Column.(*columnEnum); ok {
+		codes := make(map[uint32]int, 8)
+		txn.rangeRead(func(offset uint32, index bitmap.Bitmap) {
+			index.Range(func(x uint32) {
+				if idx := offset + x; enum.Contains(idx) {
+					codes[enum.locs[idx]]++
+				} else {
+					out[nil]++
+				}
+			})
+
+			for at, count := range codes {
+				out[enum.readAt(at)] += count
+				delete(codes, at)
+			}
+		})
+		return out
+	}
+
+	txn.rangeRead(func(offset uint32, index bitmap.Bitmap) {
+		index.Range(func(x uint32) {
+			key, _ := group.Value(offset + x)
+			out[key]++
+		})
+	})
+	return out
+}
+
+// SumGroupBy computes the sum of the numeric value column for each distinct value of the group
+// column, over the objects matching the query, in a single pass. Objects without a value, or
+// whose value column is not numeric, contribute zero to their group, and objects without a
+// value in the group column are grouped under the nil key.
+func (txn *Txn) SumGroupBy(groupColumn, valueColumn string) map[interface{}]float64 {
+	txn.initialize()
+	group, hasGroup := txn.columnAt(groupColumn)
+	var numeric Numeric
+	if value, ok := txn.columnAt(valueColumn); ok && value.IsNumeric() {
+		numeric = value.Column.(Numeric)
+	}
+
+	out := make(map[interface{}]float64, 8)
+	txn.rangeRead(func(offset uint32, index bitmap.Bitmap) {
+		index.Range(func(x uint32) {
+			var key interface{}
+			if hasGroup {
+				key, _ = group.Value(offset + x)
+			}
+
+			var v float64
+			if numeric != nil {
+				v, _ = numeric.LoadFloat64(offset + x)
+			}
+			out[key] += v
+		})
+	})
+	return out
+}
+
+// Sum computes the sum of a numeric column over the objects matching the query, whatever
+// the type of the column. The objects without a value are skipped, and if the column does
+// not exist or is not numeric, the sum is zero.
+func (txn *Txn) Sum(columnName string) (sum float64) {
+	txn.rangeFloat64(columnName, func(v float64) {
+		sum += v
+	})
+	return
+}
+
+// Avg computes the average of a numeric column over the objects matching the query, whatever
+// the type of the column. The objects without a value are skipped, and if there are no values
+// to average, it returns false.
+func (txn *Txn) Avg(columnName string) (avg float64, ok bool) {
+	var sum float64
+	var count int
+	txn.rangeFloat64(columnName, func(v float64) {
+		sum += v
+		count++
+	})
+
+	if count == 0 {
+		return 0, false
+	}
+	return sum / float64(count), true
+}
+
+// Min computes the minimum value of a numeric column over the objects matching the query,
+// whatever the type of the column. The objects without a value are skipped, and if there
+// are no values, it returns false.
+func (txn *Txn) Min(columnName string) (min float64, ok bool) {
+	txn.rangeFloat64(columnName, func(v float64) {
+		if !ok || v < min {
+			min, ok = v, true
+		}
+	})
+	return
+}
+
+// Max computes the maximum value of a numeric column over the objects matching the query,
+// whatever the type of the column. The objects without a value are skipped, and if there
+// are no values, it returns false.
+func (txn *Txn) Max(columnName string) (max float64, ok bool) {
+	txn.rangeFloat64(columnName, func(v float64) {
+		if !ok || v > max {
+			max, ok = v, true
+		}
+	})
+	return
+}
+
+// rangeFloat64 iterates over the values of a numeric column for the objects matching the
+// query, converted to float64. Nothing is iterated if the column is not numeric.
+func (txn *Txn) rangeFloat64(columnName string, fn func(v float64)) {
+	txn.initialize()
+	column, ok := txn.columnAt(columnName)
+	if !ok || !column.IsNumeric() {
+		return
+	}
+
+	numeric := column.Column.(Numeric)
+	txn.rangeRead(func(offset uint32, index bitmap.Bitmap) {
+		index.Range(func(x uint32) {
+			if v, ok := numeric.LoadFloat64(offset + x); ok {
+				fn(v)
+			}
+		})
+	})
+}
+
+// Offset removes the first n objects from the result set, in ascending order of their index
+// or in the order of OrderBy if the query was ordered, so that the subsequent reads and updates
+// skip them. It can be combined with Limit, for example Offset(20).Limit(10) keeps the objects
+// 20 to 29 of the result set.
+func (txn *Txn) Offset(n int) *Txn {
+	txn.initialize()
+	if txn.order != nil {
+		txn.truncateOrdered(func(i int) bool { return i < n })
+		return txn
+	}
+
+	for blk := range txn.index {
+		if n <= 0 {
+			break
+		}
+
+		count := bits.OnesCount64(txn.index[blk])
+		if count <= n {
+			txn.index[blk] = 0
+			n -= count
+			continue
+		}
+
+		// Clear the lowest n bits of the block only
+		for ; n > 0; n-- {
+			txn.index[blk] &= txn.index[blk] - 1
+		}
+	}
+	return txn
+}
+
+// Limit keeps at most the first n objects of the result set, in ascending order of their
+// index or in the order of OrderBy if the query was ordered, so that the subsequent reads and
+// updates only visit them.
+func (txn *Txn) Limit(n int) *Txn {
+	txn.initialize()
+	if txn.order != nil {
+		txn.truncateOrdered(func(i int) bool { return i >= n })
+		return txn
+	}
+
+	for blk := range txn.index {
+		count := bits.OnesCount64(txn.index[blk])
+		switch {
+		case n <= 0:
+			txn.index[blk] = 0
+		case count <= n:
+			n -= count
+		default:
+			var kept uint64
+			for v := txn.index[blk]; n > 0; n-- {
+				lowest := v & -v
+				kept |= lowest
+				v &^= lowest
+			}
+			txn.index[blk] = kept
+		}
+	}
+	return txn
+}
+
+// truncateOrdered walks the ordered result set and removes the objects for which the
+// function returns true, given their position among the objects still matching the query.
+func (txn *Txn) truncateOrdered(drop func(i int) bool) {
+	i := 0
+	for _, idx := range txn.order {
+		if !txn.index.Contains(idx) {
+			continue
+		}
+
+		if drop(i) {
+			txn.index.Remove(idx)
+		}
+		i++
+	}
+}
+
+// Paginate reads a page of objects from the result set, starting right after the position
+// encoded in the cursor, or from the beginning if the cursor is empty. It returns the objects,
+// the cursor for the next page and whether there are more objects after this page. Since the
+// cursor encodes the last index seen rather than an offset, concurrent inserts and deletes do
+// not cause objects to be skipped or read twice. An invalid cursor yields an empty page.
+func (txn *Txn) Paginate(cursor string, pageSize int) (items []Object, nextCursor string, hasMore bool) {
+	start, ok := decodeCursor(cursor)
+	if !ok || pageSize <= 0 {
+		return nil, "", false
+	}
+
+	txn.initialize()
+	last := uint32(0)
+	items = make([]Object, 0, pageSize)
+	txn.rangeRead(func(offset uint32, index bitmap.Bitmap) {
+		if hasMore || offset+chunkSize <= start {
+			return
+		}
+
+		index.Range(func(x uint32) {
+			switch idx := offset + x; {
+			case hasMore || idx < start:
+				return
+			case len(items) == pageSize:
+				hasMore = true
+			default:
+				object := make(Object, txn.owner.cols.Count())
+				txn.owner.readObject(idx, object)
+				items = append(items, object)
+				last = idx
+			}
+		})
+	})
+
+	if hasMore {
+		nextCursor = encodeCursor(last + 1)
+	}
+	return
+}
+
+// Random picks an object from the result set uniformly at random and returns its index and
+// all of its values. The k-th object is selected by counting the bits of the result set, so
+// the matches do not need to be materialized. If there are no matches, ok will be false.
+func (txn *Txn) Random(rng *rand.Rand) (idx uint32, object Object, ok bool) {
+	txn.initialize()
+	count := txn.index.Count()
+	if count == 0 {
+		return 0, nil, false
+	}
+
+	// Find the word which contains the k-th bit, then the bit within that word
+	k := rng.Intn(count)
+	for i, word := range txn.index {
+		if n := bits.OnesCount64(word); k >= n {
+			k -= n
+			continue
+		}
+
+		for ; k > 0; k-- {
+			word &= word - 1
+		}
+
+		idx = uint32(i<<6 + bits.TrailingZeros64(word))
+		break
+	}
+
+	chunk := commit.ChunkAt(idx)
+	txn.owner.slock.RLock(uint(chunk))
+	object = make(Object, txn.owner.cols.Count())
+	txn.owner.readObject(idx, object)
+	txn.owner.slock.RUnlock(uint(chunk))
+	return idx, object, true
+}
+
+// encodeCursor encodes the index to resume from into an opaque cursor
+func encodeCursor(idx uint32) string {
+	var buffer [4]byte
+	binary.BigEndian.PutUint32(buffer[:], idx)
+	return base64.RawURLEncoding.EncodeToString(buffer[:])
+}
+
+// decodeCursor decodes the index to resume from, an empty cursor starts from zero
+func decodeCursor(cursor string) (uint32, bool) {
+	if cursor == "" {
+		return 0, true
+	}
+
+	buffer, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil || len(buffer) != 4 {
+		return 0, false
+	}
+
+	return binary.BigEndian.Uint32(buffer), true
+}
+
 // Rollback empties the pending update and delete queues and does not apply any of
-// the pending updates/deletes. This operation can be called several times for
-// a transaction in order to perform partial rollbacks.
+// the pending updates/deletes, releasing the indices allocated for the insertions.
+// This operation can be called several times for a transaction in order to perform
+// partial rollbacks.
 func (txn *Txn) rollback() {
-	txn.reset()
+	defer txn.reset()
+
+	// Release the indices which were allocated for the insertions, none of them committed
+	markers, ok := txn.findMarkers()
+	if !ok {
+		return
+	}
+
+	owner := txn.owner
+	owner.lock.Lock()
+	defer owner.lock.Unlock()
+	for txn.reader.Seek(markers); txn.reader.Next(); {
+		if idx := txn.reader.Index(); txn.reader.Type == commit.Insert {
+			owner.fill.Remove(idx)
+			if owner.alloc == FIFO {
+				owner.freed = append(owner.freed, idx)
+			}
+		}
+	}
+
+	atomic.StoreUint64(&owner.count, uint64(owner.fill.Count()))
 }
 
 // Commit commits the transaction by applying all pending updates and deletes to
@@ -411,9 +1155,30 @@ func (txn *Txn) commit() {
 		txn.commitCapacity(commit.Chunk(last))
 	}
 
+	// If the owner retains history, collect the inverse operations
+	var inverse *undo
+	if txn.owner.history != nil {
+		inverse = new(undo)
+	}
+
+	// If the commit is retained in the history or journal, serialize it with the others, so
+	// that the order of the versions matches the order in which the chunks were written.
+	if owner := txn.owner; !txn.locked && owner.isVersioned() {
+		owner.serial.Lock()
+		defer owner.serial.Unlock()
+	}
+
 	// Commit chunk by chunk to reduce lock contentions
+	changed := false
 	txn.rangeWrite(func(commitID uint64, chunk commit.Chunk, fill bitmap.Bitmap) {
+		if inverse != nil {
+			txn.captureUndo(chunk, markers, inverse)
+		}
+
+		// Read the objects deleted before their values are cleared, so observers receive them
+		var removed map[uint32]Object
 		if changedRows {
+			removed = txn.removedOf(chunk, markers)
 			txn.commitMarkers(chunk, fill, markers)
 		}
 
@@ -423,6 +1188,11 @@ func (txn *Txn) commit() {
 			return
 		}
 
+		changed = true
+		txn.trace(commitID, chunk)
+		txn.notify(chunk, markers, removed)
+		txn.commitKeys(chunk, markers)
+
 		// If there is a pending snapshot, append commit into a temp log
 		if dst, ok := txn.owner.isSnapshotting(); ok {
 			dst.Append(commit.Commit{
@@ -440,6 +1210,29 @@ func (txn *Txn) commit() {
 			})
 		}
 	})
+
+	// Write the traced records now that the latches are released
+	txn.flushTrace()
+
+	// If anything was changed, bump the version of the collection
+	if changed {
+		version := atomic.AddUint64(&txn.owner.version, 1)
+		if inverse != nil {
+			txn.owner.history.Append(version, inverse.updates)
+		}
+
+		// Additions were swapped with puts of their resulting values while being applied,
+		// so the journaled operations can be replayed as-is.
+		if journal := txn.owner.journal; journal != nil {
+			updates := make([]*commit.Buffer, 0, len(txn.updates))
+			for _, u := range txn.updates {
+				if !u.IsEmpty() {
+					updates = append(updates, u.Clone())
+				}
+			}
+			journal.Append(version, updates)
+		}
+	}
 }
 
 // commitUpdates applies the pending updates to the collection.
@@ -451,6 +1244,12 @@ func (txn *Txn) commitUpdates(chunk commit.Chunk) (updated bool) {
 
 		// Get the column to update
 		columns, exists := txn.owner.cols.LoadWithIndex(u.Column)
+		if !exists {
+			if nulls, ok := txn.owner.nullsNamed(u.Column); ok {
+				columns, exists = []*column{nulls}, true
+			}
+		}
+
 		if !exists || len(columns) == 0 {
 			continue
 		}
@@ -478,6 +1277,9 @@ func (txn *Txn) commitMarkers(chunk commit.Chunk, fill bitmap.Bitmap, buffer *co
 			case commit.Insert:
 				txn.owner.fill.Set(r.Index())
 			case commit.Delete:
+				if txn.owner.alloc == FIFO && txn.owner.fill.Contains(r.Index()) {
+					txn.owner.freed = append(txn.owner.freed, r.Index())
+				}
 				txn.owner.fill.Remove(r.Index())
 			}
 			txn.owner.lock.Unlock()
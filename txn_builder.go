@@ -0,0 +1,82 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package column
+
+import (
+	"github.com/kelindar/bitmap"
+)
+
+// QueryBuilder accumulates the conditions of a query one at a time, which is convenient
+// when some of the conditions are optional. Every condition added must be satisfied, and
+// AddOr groups alternative conditions together. Nothing is evaluated until the query
+// built is applied to a transaction.
+type QueryBuilder struct {
+	conditions []condition // The conditions to apply, in order
+}
+
+// condition represents a single condition which filters down the transaction
+type condition func(txn *Txn) *Txn
+
+// NewQueryBuilder creates a new query builder without any condition, which matches every
+// object of a collection.
+func NewQueryBuilder() *QueryBuilder {
+	return new(QueryBuilder)
+}
+
+// AddEq adds a condition which matches the objects whose value of the column is equal to
+// the specified value, same as WhereEq.
+func (b *QueryBuilder) AddEq(column string, value interface{}) *QueryBuilder {
+	b.conditions = append(b.conditions, func(txn *Txn) *Txn {
+		return txn.WhereEq(column, value)
+	})
+	return b
+}
+
+// AddRange adds a condition which matches the objects whose value of the column is between
+// the minimum and the maximum, both included. The values are compared the same way as in
+// WhereGt and WhereLt.
+func (b *QueryBuilder) AddRange(column string, min, max interface{}) *QueryBuilder {
+	b.conditions = append(b.conditions, func(txn *Txn) *Txn {
+		return txn.where(column, isAtLeast, min).where(column, isAtMost, max)
+	})
+	return b
+}
+
+// AddOr adds a condition which matches the objects matched by any of the branches, each of
+// them being a group of conditions. If there are no branches, no object is matched.
+func (b *QueryBuilder) AddOr(branches ...*QueryBuilder) *QueryBuilder {
+	b.conditions = append(b.conditions, func(txn *Txn) *Txn {
+		return txn.union(branches)
+	})
+	return b
+}
+
+// Build returns the query, which applies the conditions to a transaction and returns it
+// so that the matching objects can be read or updated.
+func (b *QueryBuilder) Build() func(txn *Txn) *Txn {
+	conditions := append([]condition(nil), b.conditions...)
+	return func(txn *Txn) *Txn {
+		txn.initialize()
+		for _, fn := range conditions {
+			fn(txn)
+		}
+		return txn
+	}
+}
+
+// union filters down the query to the objects matching any of the branches, by applying
+// each of them to a copy of the current index.
+func (txn *Txn) union(branches []*QueryBuilder) *Txn {
+	txn.initialize()
+	var source, result bitmap.Bitmap
+	txn.index.Clone(&source)
+	for _, branch := range branches {
+		source.Clone(&txn.index)
+		branch.Build()(txn)
+		result.Or(txn.index)
+	}
+
+	result.Clone(&txn.index)
+	return txn
+}
@@ -86,7 +86,12 @@ func (txn *Txn) rangeWrite(fn func(commitID uint64, chunk commit.Chunk, fill bit
 	txn.dirty.Range(func(x uint32) {
 		chunk := commit.Chunk(x)
 		commitID := commit.Next()
-		lock.Lock(uint(chunk))
+		if !txn.locked {
+			lock.Lock(uint(chunk))
+
+			// Release the latch even if the delegate panics, so the chunk stays usable
+			defer lock.Unlock(uint(chunk))
+		}
 
 		// Compute the fill and set the last commit ID
 		txn.owner.lock.RLock()
@@ -96,7 +101,6 @@ func (txn *Txn) rangeWrite(fn func(commitID uint64, chunk commit.Chunk, fill bit
 
 		// Call the delegate
 		fn(commitID, chunk, fill)
-		lock.Unlock(uint(chunk))
 	})
 }
 
@@ -116,3 +120,82 @@ func (c *Collection) readChunk(chunk commit.Chunk, fn func(uint64, commit.Chunk,
 	lock.RUnlock(uint(chunk))
 	return
 }
+
+// --------------------------- Locked Collection ---------------------------
+
+// WithLock acquires the write latches of every chunk and executes the callback, so that a
+// sequence of reads and writes is performed atomically. Each write is committed as soon as
+// it is made, hence it is visible to the reads which follow it, but not to any other reader
+// until the callback returns. This blocks every other query, so the callback should be short
+// and it must not query the collection itself, which would deadlock.
+func (c *Collection) WithLock(fn func(*LockedCollection)) {
+	if c.isVersioned() {
+		c.serial.Lock() // The commits of the callback are serialized too
+		defer c.serial.Unlock()
+	}
+
+	for shard := uint(0); shard < 128; shard++ {
+		c.slock.Lock(shard)
+		defer c.slock.Unlock(shard)
+	}
+
+	txn := c.txns.acquire(c)
+	txn.locked = true
+	defer c.txns.release(txn)
+
+	locked := &LockedCollection{txn: txn}
+	defer func() { locked.txn = nil }()
+	fn(locked)
+}
+
+// LockedCollection provides a low-level access to a collection whose latches are held. It
+// is only valid within the callback of WithLock, and it panics if used after that.
+type LockedCollection struct {
+	txn *Txn // The transaction used to commit the writes, nil once released
+}
+
+// Get reads the value of the column for the object at the index, and returns false if
+// there is no such value.
+func (l *LockedCollection) Get(idx uint32, columnName string) (interface{}, bool) {
+	txn := l.acquire()
+	column, ok := txn.owner.cols.Load(columnName)
+	if !ok || column.IsIndex() {
+		return nil, false
+	}
+
+	return column.Value(idx)
+}
+
+// Set writes the value of the column for the object at the index, and returns false if
+// either the object or the column does not exist.
+func (l *LockedCollection) Set(idx uint32, columnName string, value interface{}) bool {
+	txn := l.acquire()
+	column, ok := txn.owner.cols.Load(columnName)
+	if !ok || column.IsIndex() || !txn.owner.Contains(idx) {
+		return false
+	}
+
+	txn.bufferFor(columnName).PutAny(commit.Put, idx, value)
+	txn.commit()
+	return true
+}
+
+// Remove deletes the object at the index, and returns false if it does not exist.
+func (l *LockedCollection) Remove(idx uint32) bool {
+	txn := l.acquire()
+	if !txn.owner.Contains(idx) {
+		return false
+	}
+
+	txn.deleteAt(idx)
+	txn.commit()
+	return true
+}
+
+// acquire returns the transaction, or panics if the callback has already returned
+func (l *LockedCollection) acquire() *Txn {
+	if l.txn == nil {
+		panic("column: locked collection used outside of its callback")
+	}
+	return l.txn
+}
@@ -0,0 +1,67 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package column
+
+// Pipeline represents a lazily evaluated chain of stages over the values of a single
+// column, for the rows selected by the transaction. Nothing is evaluated until the
+// pipeline is reduced, and no intermediate results are materialized.
+type Pipeline struct {
+	txn    *Txn    // The owning transaction
+	column string  // The source column
+	stages []stage // The stages to apply, in order
+}
+
+// stage represents a single step of the pipeline. It returns the resulting value and
+// whether this value should continue down the pipeline.
+type stage func(v interface{}) (interface{}, bool)
+
+// Pipe creates a lazily evaluated pipeline which pulls the values of the specified
+// column for every row currently selected by the transaction, one at a time.
+func (txn *Txn) Pipe(columnName string) *Pipeline {
+	return &Pipeline{
+		txn:    txn,
+		column: columnName,
+	}
+}
+
+// Filter adds a stage which only lets through the values matching the predicate.
+func (p *Pipeline) Filter(predicate func(v interface{}) bool) *Pipeline {
+	p.stages = append(p.stages, func(v interface{}) (interface{}, bool) {
+		return v, predicate(v)
+	})
+	return p
+}
+
+// Map adds a stage which transforms every value using the specified function.
+func (p *Pipeline) Map(fn func(v interface{}) interface{}) *Pipeline {
+	p.stages = append(p.stages, func(v interface{}) (interface{}, bool) {
+		return fn(v), true
+	})
+	return p
+}
+
+// Reduce evaluates the pipeline and folds every resulting value into an accumulator,
+// starting with the initial value provided. Rows which do not have a value for the
+// source column are skipped. Each value is read while holding the read latch of its
+// chunk and the set of rows is the one selected by the transaction, so the result is
+// consistent with the rest of the transaction.
+func (p *Pipeline) Reduce(init interface{}, fn func(acc, v interface{}) interface{}) interface{} {
+	column, ok := p.txn.columnAt(p.column)
+	if !ok {
+		return init
+	}
+
+	acc := init
+	p.txn.Range(func(idx uint32) {
+		v, ok := column.Value(idx)
+		for i := 0; ok && i < len(p.stages); i++ {
+			v, ok = p.stages[i](v)
+		}
+
+		if ok {
+			acc = fn(acc, v)
+		}
+	})
+	return acc
+}
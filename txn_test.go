@@ -5,8 +5,13 @@ package column
 
 import (
 	"fmt"
+	"math"
+	"math/rand"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -114,6 +119,37 @@ func TestCount(t *testing.T) {
 	})
 }
 
+func TestForEachParallel(t *testing.T) {
+	players := loadPlayers(50000)
+	var seen sync.Map
+	var count int64
+	players.Query(func(txn *Txn) error {
+		txn.With("human").ForEachParallel(4, func(idx uint32, obj Object) {
+			_, loaded := seen.LoadOrStore(idx, true)
+			assert.False(t, loaded)
+			assert.Equal(t, "human", obj["race"])
+			atomic.AddInt64(&count, 1)
+		})
+		return nil
+	})
+
+	players.Query(func(txn *Txn) error {
+		assert.Equal(t, txn.With("human").Count(), int(count))
+		return nil
+	})
+
+	// The expiration of the rows is internal, hence not part of the objects
+	col := NewCollection()
+	col.CreateColumn("name", ForString())
+	col.InsertObjectWithTTL(Object{"name": "Roman"}, time.Hour)
+	col.Query(func(txn *Txn) error {
+		txn.ForEachParallel(2, func(idx uint32, obj Object) {
+			assert.Equal(t, Object{"name": "Roman"}, obj)
+		})
+		return nil
+	})
+}
+
 func TestIndexInvalid(t *testing.T) {
 	players := loadPlayers(500)
 	players.Query(func(txn *Txn) error {
@@ -592,3 +628,973 @@ func TestRow(t *testing.T) {
 
 	wg.Wait()
 }
+
+func TestPipe(t *testing.T) {
+	players := loadPlayers(500)
+	players.Query(func(txn *Txn) error {
+		expect := 0.0
+		balance := txn.Float64("balance")
+		txn.With("human").Range(func(idx uint32) {
+			if v, _ := balance.Get(); v > 2500 {
+				expect += v / 2
+			}
+		})
+
+		result := txn.Pipe("balance").Filter(func(v interface{}) bool {
+			return v.(float64) > 2500
+		}).Map(func(v interface{}) interface{} {
+			return v.(float64) / 2
+		}).Reduce(0.0, func(acc, v interface{}) interface{} {
+			return acc.(float64) + v.(float64)
+		})
+
+		assert.NotZero(t, expect)
+		assert.Equal(t, expect, result)
+		return nil
+	})
+}
+
+func TestPipeInvalidColumn(t *testing.T) {
+	players := loadPlayers(500)
+	players.Query(func(txn *Txn) error {
+		assert.Equal(t, 0, txn.Pipe("invalid").Reduce(0, func(acc, v interface{}) interface{} {
+			return acc.(int) + 1
+		}))
+		return nil
+	})
+}
+
+func TestSumTyped(t *testing.T) {
+	players := loadPlayers(500)
+	players.Query(func(txn *Txn) error {
+		expect := 0.0
+		balance := txn.Float64("balance")
+		txn.With("human").Range(func(idx uint32) {
+			v, _ := balance.Get()
+			expect += v
+		})
+
+		assert.NotZero(t, expect)
+		assert.Equal(t, expect, txn.SumFloat64("balance"))
+		assert.Panics(t, func() {
+			txn.SumInt64("balance")
+		})
+		return nil
+	})
+
+	col := NewCollection()
+	col.CreateColumn("age", ForInt64())
+	col.InsertObject(Object{"age": int64(10)})
+	col.InsertObject(Object{"age": int64(20)})
+	col.InsertObject(Object{})
+	col.Query(func(txn *Txn) error {
+		assert.Equal(t, int64(30), txn.SumInt64("age"))
+		assert.Equal(t, int64(20), txn.WithInt("age", func(v int64) bool {
+			return v > 10
+		}).SumInt64("age"))
+		return nil
+	})
+}
+
+func TestWhere(t *testing.T) {
+	players := newEmpty(500)
+	players.CreateColumn("level", ForUint16())
+	players.Query(func(txn *Txn) error {
+		for i, p := range loadFixture("players.json") {
+			idx, _ := txn.InsertObject(p)
+			txn.QueryAt(idx, func(r Row) error {
+				r.SetUint16("level", uint16(i%100))
+				return nil
+			})
+		}
+		return nil
+	})
+
+	// countOf counts the objects matching the filter
+	countOf := func(fn func(txn *Txn) *Txn) (count int) {
+		players.Query(func(txn *Txn) error {
+			count = fn(txn).Count()
+			return nil
+		})
+		return
+	}
+
+	tests := []struct {
+		where     func(txn *Txn) *Txn
+		predicate func(txn *Txn) *Txn
+	}{
+		{
+			where: func(txn *Txn) *Txn { return txn.WhereEq("age", 30) },
+			predicate: func(txn *Txn) *Txn {
+				return txn.WithFloat("age", func(v float64) bool { return v == 30 })
+			},
+		},
+		{
+			where: func(txn *Txn) *Txn { return txn.WhereGt("age", 30.5) },
+			predicate: func(txn *Txn) *Txn {
+				return txn.WithFloat("age", func(v float64) bool { return v > 30.5 })
+			},
+		},
+		{
+			where: func(txn *Txn) *Txn { return txn.WhereLt("level", 50) },
+			predicate: func(txn *Txn) *Txn {
+				return txn.WithUint("level", func(v uint64) bool { return v < 50 })
+			},
+		},
+		{
+			where: func(txn *Txn) *Txn { return txn.WhereGt("level", -1) },
+			predicate: func(txn *Txn) *Txn {
+				return txn.With("level")
+			},
+		},
+		{
+			where: func(txn *Txn) *Txn { return txn.WhereEq("class", "mage") },
+			predicate: func(txn *Txn) *Txn {
+				return txn.WithString("class", func(v string) bool { return v == "mage" })
+			},
+		},
+		{
+			where: func(txn *Txn) *Txn { return txn.WhereLt("race", "human") },
+			predicate: func(txn *Txn) *Txn {
+				return txn.WithString("race", func(v string) bool { return v < "human" })
+			},
+		},
+		{
+			where: func(txn *Txn) *Txn { return txn.WhereEq("active", true) },
+			predicate: func(txn *Txn) *Txn {
+				return txn.WithValue("active", func(v interface{}) bool { return v == true })
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		expect := countOf(tc.predicate)
+		assert.NotZero(t, expect)
+		assert.Equal(t, expect, countOf(tc.where))
+	}
+
+	// Primary key lookups and invalid comparisons
+	assert.Equal(t, 1, countOf(func(txn *Txn) *Txn {
+		return txn.WhereEq("serial", "18771f05-a3be-49b1-9567-f8f2b891d5ff")
+	}))
+	assert.Equal(t, 0, countOf(func(txn *Txn) *Txn {
+		return txn.WhereEq("serial", "invalid")
+	}))
+	assert.Equal(t, 0, countOf(func(txn *Txn) *Txn {
+		return txn.WhereGt("active", true)
+	}))
+	assert.Equal(t, 0, countOf(func(txn *Txn) *Txn {
+		return txn.WhereEq("invalid", 1)
+	}))
+}
+
+func TestWhereRegex(t *testing.T) {
+	players := loadPlayers(500)
+	countOf := func(fn func(txn *Txn) *Txn) (count int) {
+		players.Query(func(txn *Txn) error {
+			count = fn(txn).Count()
+			return nil
+		})
+		return
+	}
+
+	expect := countOf(func(txn *Txn) *Txn {
+		return txn.WithString("name", func(v string) bool {
+			return strings.HasPrefix(v, "M")
+		})
+	})
+	assert.NotZero(t, expect)
+
+	assert.Equal(t, expect, countOf(func(txn *Txn) *Txn {
+		out, err := txn.WhereRegex("name", "^M")
+		assert.NoError(t, err)
+		return out
+	}))
+
+	// Non-string columns and invalid patterns
+	assert.Equal(t, 0, countOf(func(txn *Txn) *Txn {
+		out, err := txn.WhereRegex("balance", ".*")
+		assert.NoError(t, err)
+		return out
+	}))
+	assert.Equal(t, players.Count(), countOf(func(txn *Txn) *Txn {
+		out, err := txn.WhereRegex("name", "[")
+		assert.Error(t, err)
+		return out
+	}))
+}
+
+func TestRandom(t *testing.T) {
+	players := loadPlayers(500)
+	rng := rand.New(rand.NewSource(1))
+
+	// Pick from a sparse set of matches, every match must be picked about as often
+	hits := make(map[uint32]int)
+	players.Query(func(txn *Txn) error {
+		matches := txn.With("human", "mage").Count()
+		assert.NotZero(t, matches)
+		for i := 0; i < 1000*matches; i++ {
+			idx, object, ok := txn.Random(rng)
+			assert.True(t, ok)
+			assert.Equal(t, "human", object["race"])
+			hits[idx]++
+		}
+
+		assert.Len(t, hits, matches)
+		for _, n := range hits {
+			assert.InDelta(t, 1000, n, 150)
+		}
+		return nil
+	})
+
+	// No matches
+	players.Query(func(txn *Txn) error {
+		_, _, ok := txn.WithValue("race", func(v interface{}) bool {
+			return false
+		}).Random(rng)
+		assert.False(t, ok)
+		return nil
+	})
+}
+
+func TestPaginate(t *testing.T) {
+	players := loadPlayers(500)
+	humans := 0
+	players.Query(func(txn *Txn) error {
+		humans = txn.With("human").Count()
+		return nil
+	})
+
+	pageOf := func(cursor string, size int) (items []Object, next string, more bool) {
+		players.Query(func(txn *Txn) error {
+			items, next, more = txn.With("human").Paginate(cursor, size)
+			return nil
+		})
+		return
+	}
+
+	// Page through all of the humans, inserting a new one half-way through
+	seen := make(map[string]bool)
+	cursor, pages := "", 0
+	for more := true; more; pages++ {
+		var items []Object
+		items, cursor, more = pageOf(cursor, 30)
+		assert.LessOrEqual(t, len(items), 30)
+		for _, item := range items {
+			serial := item["serial"].(string)
+			assert.False(t, seen[serial])
+			seen[serial] = true
+		}
+
+		if pages == 3 {
+			players.InsertObject(Object{
+				"serial": "inserted",
+				"race":   "human",
+			})
+		}
+	}
+
+	assert.True(t, seen["inserted"])
+	assert.Equal(t, humans+1, len(seen))
+
+	// Invalid cursors and page sizes
+	items, next, more := pageOf("invalid", 10)
+	assert.Empty(t, items)
+	assert.Empty(t, next)
+	assert.False(t, more)
+	items, _, _ = pageOf("", 0)
+	assert.Empty(t, items)
+}
+
+func TestSumGroupBy(t *testing.T) {
+	c := NewCollection()
+	c.CreateColumn("category", ForString())
+	c.CreateColumn("amount", ForFloat64())
+	c.InsertObject(Object{"category": "food", "amount": 10.0})
+	c.InsertObject(Object{"category": "food", "amount": 2.5})
+	c.InsertObject(Object{"category": "rent", "amount": 500.0})
+	c.InsertObject(Object{"category": "rent"})
+	c.InsertObject(Object{"amount": 7.0})
+
+	c.Query(func(txn *Txn) error {
+		assert.Equal(t, map[interface{}]float64{
+			"food": 12.5,
+			"rent": 500,
+			nil:    7,
+		}, txn.SumGroupBy("category", "amount"))
+
+		// Missing or non-numeric values contribute zero
+		assert.Equal(t, map[interface{}]float64{
+			"food": 0,
+			"rent": 0,
+			nil:    0,
+		}, txn.SumGroupBy("category", "category"))
+
+		// Missing group columns put everything under nil
+		assert.Equal(t, map[interface{}]float64{
+			nil: 519.5,
+		}, txn.SumGroupBy("missing", "amount"))
+		return nil
+	})
+
+	// Only the matching objects are aggregated
+	c.Query(func(txn *Txn) error {
+		assert.Equal(t, map[interface{}]float64{
+			"food": 10,
+		}, txn.WithFloat("amount", func(v float64) bool {
+			return v == 10
+		}).SumGroupBy("category", "amount"))
+		return nil
+	})
+}
+
+func TestIntersectSorted(t *testing.T) {
+	players := loadPlayers(500)
+	players.Query(func(txn *Txn) error {
+		var expect, sorted []uint32
+		txn.With("human").Range(func(idx uint32) {
+			if idx%3 == 0 {
+				expect = append(expect, idx)
+			}
+		})
+
+		// Every third index, including the ones which are not human or out of range
+		for i := uint32(0); i < 1000; i += 3 {
+			sorted = append(sorted, i)
+		}
+
+		var actual []uint32
+		_, err := txn.IntersectSorted(sorted)
+		assert.NoError(t, err)
+		txn.Range(func(idx uint32) {
+			actual = append(actual, idx)
+		})
+		assert.NotEmpty(t, actual)
+		assert.Equal(t, expect, actual)
+		return nil
+	})
+
+	players.Query(func(txn *Txn) error {
+		_, err := txn.IntersectSorted(nil)
+		assert.NoError(t, err)
+		assert.Equal(t, 0, txn.Count())
+		return nil
+	})
+
+	// Unsorted indices leave the query unchanged
+	for _, input := range [][]uint32{{100, 5}, {5, 10000, 9999}} {
+		players.Query(func(txn *Txn) error {
+			_, err := txn.IntersectSorted(input)
+			assert.Error(t, err)
+			assert.Equal(t, 500, txn.Count())
+			return nil
+		})
+	}
+}
+
+func TestOrderBy(t *testing.T) {
+	players := loadPlayers(500)
+	byBalance := func(a, b interface{}) bool {
+		return a.(float64) < b.(float64)
+	}
+
+	players.Query(func(txn *Txn) error {
+		var values []float64
+		txn.OrderBy("balance", byBalance).With("human").Range(func(idx uint32) {
+			balance, _ := Get[float64](players, idx, "balance")
+			race, _ := Get[string](players, idx, "race")
+			assert.Equal(t, "human", race)
+			values = append(values, balance)
+		})
+
+		assert.Equal(t, txn.Count(), len(values))
+		for i := 1; i < len(values); i++ {
+			assert.LessOrEqual(t, values[i-1], values[i])
+		}
+		return nil
+	})
+
+	// Equal values keep the order of their indices
+	players.Query(func(txn *Txn) error {
+		last := make(map[interface{}]uint32)
+		txn.OrderBy("race", func(a, b interface{}) bool {
+			return a.(string) < b.(string)
+		}).Range(func(idx uint32) {
+			race, _ := Get[string](players, idx, "race")
+			if prev, ok := last[race]; ok {
+				assert.Less(t, prev, idx)
+			}
+			last[race] = idx
+		})
+		return nil
+	})
+
+	// Objects without a value come last
+	col := NewCollection()
+	col.CreateColumn("balance", ForFloat64())
+	col.InsertObject(Object{})
+	col.InsertObject(Object{"balance": 2.0})
+	col.InsertObject(Object{"balance": 1.0})
+	col.Query(func(txn *Txn) error {
+		var order []uint32
+		txn.OrderBy("balance", byBalance).Range(func(idx uint32) {
+			order = append(order, idx)
+		})
+		assert.Equal(t, []uint32{2, 1, 0}, order)
+		return nil
+	})
+
+	// Limit keeps the first objects of the sorted result set
+	col.Query(func(txn *Txn) error {
+		var order []uint32
+		txn.OrderBy("balance", byBalance).Limit(1).Range(func(idx uint32) {
+			order = append(order, idx)
+		})
+		assert.Equal(t, []uint32{2}, order)
+		return nil
+	})
+}
+
+func TestSelect(t *testing.T) {
+	players := loadPlayers(500)
+	players.Query(func(txn *Txn) error {
+		objects := txn.With("human", "mage").Select()
+		assert.Equal(t, txn.Count(), len(objects))
+		for _, object := range objects {
+			assert.Equal(t, "human", object["race"])
+			assert.Equal(t, "mage", object["class"])
+		}
+
+		// Objects are read in the order of the query
+		ordered := txn.OrderBy("age", func(a, b interface{}) bool {
+			return a.(float64) < b.(float64)
+		}).Select()
+		for i := 1; i < len(ordered); i++ {
+			assert.LessOrEqual(t, ordered[i-1]["age"], ordered[i]["age"])
+		}
+		return nil
+	})
+
+	// The destination slice and its objects are reused
+	dst := []Object{{"stale": true}}
+	serial, _ := Get[string](players, 0, "serial")
+	players.Query(func(txn *Txn) error {
+		first := dst[0]
+		dst = txn.WithValue("serial", func(v interface{}) bool {
+			return v == serial
+		}).SelectTo(dst)
+		assert.Len(t, dst, 1)
+		assert.Equal(t, serial, dst[0]["serial"])
+		assert.NotContains(t, dst[0], "stale")
+
+		first["marker"] = true
+		assert.Contains(t, dst[0], "marker")
+		return nil
+	})
+
+	// Nothing matching yields an empty, non-nil slice
+	players.Query(func(txn *Txn) error {
+		objects := txn.With("missing").Select()
+		assert.NotNil(t, objects)
+		assert.Empty(t, objects)
+		return nil
+	})
+}
+
+func TestWhereAll(t *testing.T) {
+	players := loadPlayers(500)
+	isHuman := func(v interface{}) bool { return v == "human" }
+	isMage := func(v interface{}) bool { return v == "mage" }
+	isOld := func(v interface{}) bool { return v.(float64) >= 30 }
+
+	var expect, actual []uint32
+	players.Query(func(txn *Txn) error {
+		return txn.WithValue("race", isHuman).WithValue("class", isMage).WithValue("age", isOld).Range(func(idx uint32) {
+			expect = append(expect, idx)
+		})
+	})
+	players.Query(func(txn *Txn) error {
+		return txn.WhereAll(map[string]func(v interface{}) bool{
+			"race":  isHuman,
+			"class": isMage,
+			"age":   isOld,
+		}).Range(func(idx uint32) {
+			actual = append(actual, idx)
+		})
+	})
+	assert.NotEmpty(t, actual)
+	assert.Equal(t, expect, actual)
+
+	// Objects without a value do not satisfy the predicate, and missing columns match nothing
+	players.Clear(actual[0], "age")
+	players.Query(func(txn *Txn) error {
+		assert.Equal(t, len(expect)-1, txn.WhereAll(map[string]func(v interface{}) bool{
+			"race": isHuman,
+			"age": func(v interface{}) bool {
+				return true
+			},
+		}).WhereAll(map[string]func(v interface{}) bool{
+			"class": isMage,
+			"age":   isOld,
+		}).Count())
+		return nil
+	})
+	players.Query(func(txn *Txn) error {
+		assert.Equal(t, 0, txn.WhereAll(map[string]func(v interface{}) bool{
+			"race":    isHuman,
+			"missing": isHuman,
+		}).Count())
+		return nil
+	})
+	players.Query(func(txn *Txn) error {
+		assert.Equal(t, 500, txn.WhereAll(nil).Count())
+		return nil
+	})
+}
+
+func TestQueryBuilder(t *testing.T) {
+	players := loadPlayers(500)
+	query := NewQueryBuilder().
+		AddEq("race", "human").
+		AddRange("age", 30, 50).
+		AddOr(
+			NewQueryBuilder().AddEq("class", "mage"),
+			NewQueryBuilder().AddEq("class", "rogue").AddEq("active", true),
+		).Build()
+
+	// The equivalent fluent chains, one for each of the alternatives
+	expect := make(map[uint32]bool)
+	players.Query(func(txn *Txn) error {
+		return txn.WhereEq("race", "human").WhereLt("age", 51).WhereGt("age", 29).WhereEq("class", "mage").Range(func(idx uint32) {
+			expect[idx] = true
+		})
+	})
+	players.Query(func(txn *Txn) error {
+		return txn.WhereEq("race", "human").WhereLt("age", 51).WhereGt("age", 29).WhereEq("class", "rogue").With("active").Range(func(idx uint32) {
+			expect[idx] = true
+		})
+	})
+
+	actual := make(map[uint32]bool)
+	players.Query(func(txn *Txn) error {
+		return query(txn).Range(func(idx uint32) {
+			actual[idx] = true
+		})
+	})
+	assert.NotEmpty(t, actual)
+	assert.Equal(t, expect, actual)
+
+	// A builder without conditions matches every object, and an empty group matches nothing
+	players.Query(func(txn *Txn) error {
+		assert.Equal(t, 500, NewQueryBuilder().Build()(txn).Count())
+		return nil
+	})
+	players.Query(func(txn *Txn) error {
+		assert.Equal(t, 0, NewQueryBuilder().AddOr().Build()(txn).Count())
+		return nil
+	})
+}
+
+func TestWithLock(t *testing.T) {
+	players := loadPlayers(500)
+	var locked *LockedCollection
+	players.WithLock(func(c *LockedCollection) {
+		locked = c
+
+		// Move the balance of one player to another, reading the writes back
+		from, _ := c.Get(1, "balance")
+		to, _ := c.Get(2, "balance")
+		assert.True(t, c.Set(1, "balance", 0.0))
+		assert.True(t, c.Set(2, "balance", from.(float64)+to.(float64)))
+		moved, _ := c.Get(2, "balance")
+		assert.Equal(t, from.(float64)+to.(float64), moved)
+
+		// Objects and columns which do not exist can not be written
+		assert.True(t, c.Remove(3))
+		assert.False(t, c.Remove(3))
+		assert.False(t, c.Set(3, "balance", 1.0))
+		assert.False(t, c.Set(1, "xxx", 1.0))
+		_, ok := c.Get(3, "balance")
+		assert.False(t, ok)
+	})
+
+	// Writes were committed and queries can proceed once the lock is released
+	balance, _ := Get[float64](players, 1, "balance")
+	assert.Equal(t, 0.0, balance)
+	assert.Equal(t, 499, players.Count())
+
+	// The locked collection can not be used outside of the callback
+	assert.Panics(t, func() {
+		locked.Get(1, "balance")
+	})
+}
+
+func TestQueryRollback(t *testing.T) {
+	players := loadPlayers(500)
+	balance, _ := Get[float64](players, 1, "balance")
+	mutate := func(txn *Txn) {
+		txn.InsertObject(Object{"name": "Merlin", "balance": 10.0})
+		txn.Reserve(2)
+		txn.DeleteAt(0)
+		txn.QueryAt(1, func(r Row) error {
+			r.SetFloat64("balance", -1)
+			return nil
+		})
+	}
+
+	// Neither an error nor a panic leave any of the operations behind
+	assert.Error(t, players.Query(func(txn *Txn) error {
+		mutate(txn)
+		return fmt.Errorf("rollback")
+	}))
+	assert.Panics(t, func() {
+		players.Query(func(txn *Txn) error {
+			mutate(txn)
+			panic("rollback")
+		})
+	})
+
+	assert.Equal(t, 500, players.Count())
+	assert.Equal(t, 500, len(players.Keys()))
+	assert.True(t, players.Contains(0))
+	current, _ := Get[float64](players, 1, "balance")
+	assert.Equal(t, balance, current)
+
+	// The indices are released, so they are allocated again
+	assert.Equal(t, uint32(500), players.InsertObject(Object{"name": "Merlin"}))
+}
+
+func TestQueryPanicOnCommit(t *testing.T) {
+	players := loadPlayers(500)
+	assert.NoError(t, players.CreateIndex("broken", "balance", func(r Reader) bool {
+		if r.Float() < 0 {
+			panic("broken index")
+		}
+		return false
+	}))
+
+	// A panic while committing is not rolled back, but the latch of the chunk is released
+	assert.Panics(t, func() {
+		players.QueryAt(1, func(r Row) error {
+			r.SetFloat64("balance", -1)
+			return nil
+		})
+	})
+
+	assert.NoError(t, players.DropIndex("broken"))
+	done := make(chan struct{})
+	go func() {
+		players.QueryAt(1, func(r Row) error {
+			r.SetFloat64("balance", 5)
+			return nil
+		})
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		assert.Fail(t, "the latch of the chunk was not released")
+	}
+}
+
+func TestWhereRange(t *testing.T) {
+	players := loadPlayers(500)
+	players.CreateColumn("level", ForUint16())
+	players.Query(func(txn *Txn) error {
+		for i := uint32(0); i < 500; i++ {
+			txn.QueryAt(i, func(r Row) error {
+				r.SetUint16("level", uint16(i%100))
+				return nil
+			})
+		}
+		return nil
+	})
+
+	// Both of the bounds are included
+	players.Query(func(txn *Txn) error {
+		assert.Equal(t, 25, txn.WhereRange("level", 10, 14).Count())
+		return nil
+	})
+	players.Query(func(txn *Txn) error {
+		assert.Equal(t, 5, txn.WhereRange("level", 99, 99).Count())
+		return nil
+	})
+
+	var expect int
+	players.Query(func(txn *Txn) error {
+		expect = txn.WithValue("age", func(v interface{}) bool {
+			return v.(float64) >= 30 && v.(float64) <= 50
+		}).Count()
+		return nil
+	})
+	players.Query(func(txn *Txn) error {
+		assert.NotZero(t, expect)
+		assert.Equal(t, expect, txn.WhereRange("age", 30, 50).Count())
+		return nil
+	})
+
+	// Non-numeric and missing columns do not match any value
+	players.Query(func(txn *Txn) error {
+		assert.Zero(t, txn.WhereRange("name", 0, math.MaxFloat64).Count())
+		return nil
+	})
+	players.Query(func(txn *Txn) error {
+		assert.Zero(t, txn.WhereRange("xxx", 0, math.MaxFloat64).Count())
+		return nil
+	})
+}
+
+func TestIntersectDifference(t *testing.T) {
+	players := loadPlayers(500)
+	var expect int
+	players.Query(func(txn *Txn) error {
+		expect = txn.With("old", "mage").Count()
+		return nil
+	})
+
+	players.Query(func(txn *Txn) error {
+		return players.Query(func(other *Txn) error {
+			other.With("mage")
+			assert.NotZero(t, expect)
+			assert.Equal(t, expect, txn.With("old").Intersect(other).Count())
+			return nil
+		})
+	})
+
+	players.Query(func(txn *Txn) error {
+		return players.Query(func(other *Txn) error {
+			old := other.With("old").Count()
+			notOld := txn.Difference(other).Count()
+			assert.Equal(t, 500-old, notOld)
+			assert.Zero(t, txn.With("old").Count())
+			return nil
+		})
+	})
+
+	// Both queries must be on the same collection
+	assert.Panics(t, func() {
+		players.Query(func(txn *Txn) error {
+			return loadPlayers(10).Query(func(other *Txn) error {
+				txn.Intersect(other)
+				return nil
+			})
+		})
+	})
+}
+
+func TestAggregates(t *testing.T) {
+	c := NewCollection()
+	c.CreateColumn("name", ForString())
+	c.CreateColumn("level", ForInt16())
+	c.CreateColumn("score", ForFloat32())
+	c.InsertObject(Object{"name": "a", "level": int16(3), "score": float32(1.5)})
+	c.InsertObject(Object{"name": "b", "level": int16(-1), "score": float32(2.5)})
+	c.InsertObject(Object{"name": "c", "level": int16(10)})
+	c.InsertObject(Object{"name": "d"})
+
+	c.Query(func(txn *Txn) error {
+		assert.Equal(t, 12.0, txn.Sum("level"))
+		assert.Equal(t, 4.0, txn.Sum("score"))
+
+		avg, ok := txn.Avg("level")
+		assert.True(t, ok)
+		assert.Equal(t, 4.0, avg)
+
+		min, ok := txn.Min("level")
+		assert.True(t, ok)
+		assert.Equal(t, -1.0, min)
+
+		max, ok := txn.Max("score")
+		assert.True(t, ok)
+		assert.Equal(t, 2.5, max)
+		return nil
+	})
+
+	// Only the values of the matching objects are aggregated
+	c.Query(func(txn *Txn) error {
+		txn.WithString("name", func(v string) bool { return v >= "c" })
+		assert.Equal(t, 10.0, txn.Sum("level"))
+		_, ok := txn.Avg("score")
+		assert.False(t, ok)
+		return nil
+	})
+
+	// Non-numeric and missing columns have no values
+	c.Query(func(txn *Txn) error {
+		assert.Zero(t, txn.Sum("name"))
+		_, ok := txn.Min("missing")
+		assert.False(t, ok)
+		_, ok = txn.Max("name")
+		assert.False(t, ok)
+		return nil
+	})
+}
+
+func TestUnionValue(t *testing.T) {
+	players := loadPlayers(500)
+	var old, either int
+	players.Query(func(txn *Txn) error {
+		old = txn.With("old").Count()
+		return nil
+	})
+	players.Query(func(txn *Txn) error {
+		either = txn.With("mage").Union("old").Count()
+		return nil
+	})
+
+	// Mages or players of age 30 and above, same as the union of both indexes
+	players.Query(func(txn *Txn) error {
+		txn.With("mage").UnionValue("age", func(v interface{}) bool {
+			return v.(float64) >= 30
+		})
+		assert.Less(t, old, either)
+		assert.Equal(t, either, txn.Count())
+		return nil
+	})
+
+	// A union applied to a fresh query behaves like WithValue, so unions can be chained
+	players.Query(func(txn *Txn) error {
+		txn.UnionValue("class", func(v interface{}) bool {
+			return v == "mage"
+		}).UnionValue("age", func(v interface{}) bool {
+			return v.(float64) >= 30
+		})
+		assert.Equal(t, either, txn.Count())
+		return nil
+	})
+
+	// A union does not resurrect deleted objects
+	players.DeleteAt(0)
+	players.Query(func(txn *Txn) error {
+		assert.Equal(t, 499, txn.UnionValue("age", func(v interface{}) bool {
+			return true
+		}).Count())
+		return nil
+	})
+	players.Query(func(txn *Txn) error {
+		txn.WithValue("age", func(v interface{}) bool { return false })
+		assert.Equal(t, 499, txn.UnionValue("age", func(v interface{}) bool {
+			return true
+		}).Count())
+		return nil
+	})
+
+	// A missing column does not change the query
+	players.Query(func(txn *Txn) error {
+		assert.Equal(t, old, txn.With("old").UnionValue("xxx", func(v interface{}) bool {
+			return true
+		}).Count())
+		return nil
+	})
+}
+
+func TestOffsetLimit(t *testing.T) {
+	players := loadPlayers(500)
+	var all []uint32
+	players.Query(func(txn *Txn) error {
+		return txn.With("mage").Range(func(idx uint32) {
+			all = append(all, idx)
+		})
+	})
+
+	read := func(fn func(txn *Txn) *Txn) (out []uint32) {
+		players.Query(func(txn *Txn) error {
+			return fn(txn.With("mage")).Range(func(idx uint32) {
+				out = append(out, idx)
+			})
+		})
+		return
+	}
+
+	assert.Equal(t, all[20:30], read(func(txn *Txn) *Txn {
+		return txn.Offset(20).Limit(10)
+	}))
+	assert.Equal(t, all[:5], read(func(txn *Txn) *Txn {
+		return txn.Limit(5)
+	}))
+	assert.Equal(t, all[len(all)-3:], read(func(txn *Txn) *Txn {
+		return txn.Offset(len(all) - 3).Limit(100)
+	}))
+	assert.Equal(t, all, read(func(txn *Txn) *Txn {
+		return txn.Offset(0)
+	}))
+	assert.Empty(t, read(func(txn *Txn) *Txn {
+		return txn.Offset(len(all))
+	}))
+	assert.Empty(t, read(func(txn *Txn) *Txn {
+		return txn.Limit(0)
+	}))
+}
+
+func TestOffsetLimitOrdered(t *testing.T) {
+	col := NewCollection()
+	col.CreateColumn("v", ForInt())
+	for _, v := range []int{50, 40, 30, 20, 10} {
+		col.InsertObject(Object{"v": v})
+	}
+
+	read := func(fn func(txn *Txn) *Txn) (out []int) {
+		col.Query(func(txn *Txn) error {
+			return fn(txn.OrderBy("v", func(a, b interface{}) bool {
+				return a.(int) < b.(int)
+			})).Range(func(idx uint32) {
+				v, _ := Get[int](col, idx, "v")
+				out = append(out, v)
+			})
+		})
+		return
+	}
+
+	// The result set is truncated in the order of the sort rather than of the indices
+	assert.Equal(t, []int{10, 20}, read(func(txn *Txn) *Txn {
+		return txn.Limit(2)
+	}))
+	assert.Equal(t, []int{20, 30}, read(func(txn *Txn) *Txn {
+		return txn.Offset(1).Limit(2)
+	}))
+	assert.Equal(t, []int{40, 50}, read(func(txn *Txn) *Txn {
+		return txn.Offset(3).Limit(10)
+	}))
+	assert.Equal(t, []int{30, 50}, read(func(txn *Txn) *Txn {
+		return txn.WithInt("v", func(v int64) bool {
+			return v != 10 && v != 40
+		}).Offset(1)
+	}))
+}
+
+func TestCountGroupBy(t *testing.T) {
+	players := loadPlayers(500)
+	players.CreateColumn("tag", ForString())
+	players.QueryAt(10, func(r Row) error {
+		r.SetString("tag", "x")
+		return nil
+	})
+
+	// The counts must match the ones computed naively, for enums and other columns
+	for _, name := range []string{"class", "age", "tag", "missing"} {
+		expect := make(map[interface{}]int)
+		players.Query(func(txn *Txn) error {
+			txn.WithValue("age", func(v interface{}) bool {
+				return v.(float64) >= 30
+			}).Range(func(idx uint32) {
+				var key interface{}
+				if column, ok := players.cols.Load(name); ok {
+					key, _ = column.Value(idx)
+				}
+				expect[key]++
+			})
+
+			assert.Equal(t, expect, txn.CountGroupBy(name), name)
+			return nil
+		})
+	}
+
+	players.Query(func(txn *Txn) error {
+		assert.Equal(t, map[interface{}]int{nil: 499, "x": 1}, txn.CountGroupBy("tag"))
+		assert.Empty(t, txn.WithValue("age", func(interface{}) bool {
+			return false
+		}).CountGroupBy("missing"))
+		return nil
+	})
+}
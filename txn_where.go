@@ -0,0 +1,282 @@
+// Copyright (c) Roman Atachiants and contributors. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for details.
+
+package column
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+
+	"github.com/kelindar/bitmap"
+)
+
+// comparison represents a comparison operator for the where filters
+type comparison uint8
+
+const (
+	isEqual comparison = iota
+	isGreater
+	isLess
+	isAtLeast
+	isAtMost
+)
+
+// WhereEq filters down the values of a column which are equal to the specified value. Numbers
+// and strings are compared using a typed comparison, without boxing the values. If the column
+// is the primary key of the collection, the key lookup table is used instead of a scan, and
+// if the column has an equality index, the objects of the value are selected from it.
+func (txn *Txn) WhereEq(column string, value interface{}) *Txn {
+	return txn.where(column, isEqual, value)
+}
+
+// WhereGt filters down the values of a column which are greater than the specified value. Only
+// numeric and string columns can be compared, other columns do not match any value.
+func (txn *Txn) WhereGt(column string, value interface{}) *Txn {
+	return txn.where(column, isGreater, value)
+}
+
+// WhereLt filters down the values of a column which are less than the specified value. Only
+// numeric and string columns can be compared, other columns do not match any value.
+func (txn *Txn) WhereLt(column string, value interface{}) *Txn {
+	return txn.where(column, isLess, value)
+}
+
+// WhereRange filters down the values of a numeric column which are between the minimum and
+// the maximum, both included. Values of any numeric type are converted to float64 in order
+// to be compared, while non-numeric columns do not match any value.
+func (txn *Txn) WhereRange(column string, min, max float64) *Txn {
+	return txn.WithFloat(column, func(v float64) bool {
+		return v >= min && v <= max
+	})
+}
+
+// WhereRegex filters down the values of a string column which match the regular expression.
+// The expression is compiled once for the entire query and non-string columns do not match
+// any value. If the expression is invalid, an error is returned and the query is unchanged.
+func (txn *Txn) WhereRegex(column, pattern string) (*Txn, error) {
+	expr, err := regexp.Compile(pattern)
+	if err != nil {
+		return txn, fmt.Errorf("column: unable to filter, invalid pattern (%v)", err)
+	}
+
+	return txn.WithString(column, expr.MatchString), nil
+}
+
+// WhereAll filters down the objects whose values satisfy every one of the predicates, keyed by
+// the column they apply to, evaluating all of them in a single pass. An object without a value
+// for one of the columns does not satisfy its predicate, so it is filtered out. This matches
+// chaining a WithValue for each of the predicates.
+func (txn *Txn) WhereAll(predicates map[string]func(v interface{}) bool) *Txn {
+	type filter struct {
+		column    *column
+		predicate func(v interface{}) bool
+	}
+
+	txn.initialize()
+	filters := make([]filter, 0, len(predicates))
+	for name, predicate := range predicates {
+		c, ok := txn.columnAt(name)
+		if !ok {
+			txn.index.Clear()
+			return txn
+		}
+
+		filters = append(filters, filter{column: c, predicate: predicate})
+	}
+
+	txn.rangeRead(func(offset uint32, index bitmap.Bitmap) {
+		index.Filter(func(x uint32) bool {
+			for _, f := range filters {
+				if v, ok := f.column.Value(offset + x); !ok || !f.predicate(v) {
+					return false
+				}
+			}
+			return true
+		})
+	})
+	return txn
+}
+
+// where filters down the values of a column by comparing them with the specified value
+func (txn *Txn) where(columnName string, op comparison, value interface{}) *Txn {
+	txn.initialize()
+	c, ok := txn.columnAt(columnName)
+	if !ok {
+		txn.index.Clear()
+		return txn
+	}
+
+	// Strings are compared lexicographically, or seeked for the primary key
+	if v, ok := value.(string); ok {
+		if pk := txn.owner.pk; op == isEqual && pk != nil && pk.name == columnName {
+			return txn.whereKey(v)
+		}
+
+		if values, ok := txn.owner.valuesOf(columnName); ok && op == isEqual {
+			values.AndValue(v, &txn.index)
+			return txn
+		}
+
+		return txn.WithString(columnName, func(x string) bool {
+			return op.compareString(x, v)
+		})
+	}
+
+	// Numbers are compared with the type of the column, converting where required
+	if v := reflect.ValueOf(value); c.IsNumeric() && isNumberKind(v.Kind()) {
+		floating, unsigned := kindOf(c.Column)
+		switch kind := v.Kind(); {
+		case floating || kind == reflect.Float32 || kind == reflect.Float64:
+			n := toFloat64(v)
+			return txn.WithFloat(columnName, func(x float64) bool {
+				return op.compareFloat(x, n)
+			})
+		case isSigned(kind) && (!unsigned || v.Int() < 0):
+			n := v.Int()
+			return txn.WithInt(columnName, func(x int64) bool {
+				return op.compareInt(x, n)
+			})
+		case isSigned(kind):
+			n := uint64(v.Int())
+			return txn.WithUint(columnName, func(x uint64) bool {
+				return op.compareUint(x, n)
+			})
+		case unsigned:
+			n := v.Uint()
+			return txn.WithUint(columnName, func(x uint64) bool {
+				return op.compareUint(x, n)
+			})
+		case v.Uint() <= math.MaxInt64:
+			n := int64(v.Uint())
+			return txn.WithInt(columnName, func(x int64) bool {
+				return op.compareInt(x, n)
+			})
+		default:
+			n := toFloat64(v)
+			return txn.WithFloat(columnName, func(x float64) bool {
+				return op.compareFloat(x, n)
+			})
+		}
+	}
+
+	// Other values can only be checked for equality
+	if op != isEqual {
+		txn.index.Clear()
+		return txn
+	}
+
+	return txn.WithValue(columnName, func(v interface{}) bool {
+		return v == value
+	})
+}
+
+// whereKey filters down the query to the object with the specified primary key
+func (txn *Txn) whereKey(key string) *Txn {
+	idx, ok := txn.owner.pk.OffsetOf(key)
+	found := ok && txn.index.Contains(idx)
+	txn.index.Clear()
+	if found {
+		txn.index.Set(idx)
+	}
+	return txn
+}
+
+// compareFloat compares two float64 values
+func (op comparison) compareFloat(a, b float64) bool {
+	switch op {
+	case isGreater:
+		return a > b
+	case isLess:
+		return a < b
+	case isAtLeast:
+		return a >= b
+	case isAtMost:
+		return a <= b
+	default:
+		return a == b
+	}
+}
+
+// compareInt compares two int64 values
+func (op comparison) compareInt(a, b int64) bool {
+	switch op {
+	case isGreater:
+		return a > b
+	case isLess:
+		return a < b
+	case isAtLeast:
+		return a >= b
+	case isAtMost:
+		return a <= b
+	default:
+		return a == b
+	}
+}
+
+// compareUint compares two uint64 values
+func (op comparison) compareUint(a, b uint64) bool {
+	switch op {
+	case isGreater:
+		return a > b
+	case isLess:
+		return a < b
+	case isAtLeast:
+		return a >= b
+	case isAtMost:
+		return a <= b
+	default:
+		return a == b
+	}
+}
+
+// compareString compares two string values
+func (op comparison) compareString(a, b string) bool {
+	switch op {
+	case isGreater:
+		return a > b
+	case isLess:
+		return a < b
+	case isAtLeast:
+		return a >= b
+	case isAtMost:
+		return a <= b
+	default:
+		return a == b
+	}
+}
+
+// kindOf returns whether the values of a numeric column are floating-point or unsigned
+func kindOf(column Column) (floating, unsigned bool) {
+	switch column.(type) {
+	case *float32Column, *float64Column:
+		return true, false
+	case *uintColumn, *uint16Column, *uint32Column, *uint64Column:
+		return false, true
+	default:
+		return false, false
+	}
+}
+
+// isSigned returns whether the kind is a signed integer
+func isSigned(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return true
+	default:
+		return false
+	}
+}
+
+// toFloat64 converts a numeric value to float64
+func toFloat64(v reflect.Value) float64 {
+	switch {
+	case isSigned(v.Kind()):
+		return float64(v.Int())
+	case v.Kind() == reflect.Float32 || v.Kind() == reflect.Float64:
+		return v.Float()
+	default:
+		return float64(v.Uint())
+	}
+}